@@ -0,0 +1,435 @@
+// Command ipinfo looks up IP addresses and ASNs with the IPinfo API.
+//
+// Usage:
+//
+//	ipinfo [flags] <command> [flags] [args...]
+//
+// The commands are:
+//
+//	lookup     details of IPs from the legacy API
+//	lite       details of IPs from the Lite API
+//	core       details of IPs from the Core API
+//	plus       details of IPs from the Plus API
+//	batch      details of IPs from the legacy API, in batches
+//	asn        details of ASNs, e.g. AS15169
+//	resproxy   residential proxy details of IPs
+//	summarize  summary of a list of IPs
+//	map        map report URL of a list of IPs
+//	myip       details of the IP of this machine
+//	bogon      whether IPs are bogons and their special-purpose block, without
+//	           calling the API
+//
+// IPs and ASNs are taken from the arguments or, if there are none, read from
+// standard input, separated by whitespace. The API token is read from the
+// `-token` flag or the IPINFO_TOKEN environment variable; the other variables
+// read by `ipinfo.FromEnv` apply as well.
+//
+// Results are written to standard output in the format chosen with `-f`:
+// json (the default), ndjson, csv, yaml or table. `-f` also accepts a list of
+// fields such as "ip,geo.city" or a template such as "{{.IP}} {{.City}}"; see
+// package `format`. Fields are checked against the results of the command
+// before any lookup, so a misspelled format is an error. Lookups which fail
+// are reported on standard error, and the exit status is then 1.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, args []string) error
+
+	// A value of the type of the results, against which field lists and
+	// templates given with `-f` are validated.
+	result interface{}
+}
+
+var commands = []*command{
+	{"lookup", "details of IPs from the legacy API", runLookup, (*ipinfo.Core)(nil)},
+	{"lite", "details of IPs from the Lite API", runLite, (*ipinfo.Lite)(nil)},
+	{"core", "details of IPs from the Core API", runCore, (*ipinfo.CoreResponse)(nil)},
+	{"plus", "details of IPs from the Plus API", runPlus, (*ipinfo.Plus)(nil)},
+	{"batch", "details of IPs from the legacy API, in batches", runBatch, (*ipinfo.Core)(nil)},
+	{"asn", "details of ASNs, e.g. AS15169", runASN, (*ipinfo.ASNDetails)(nil)},
+	{"resproxy", "residential proxy details of IPs", runResproxy, (*ipinfo.ResproxyDetails)(nil)},
+	{"summarize", "summary of a list of IPs", runSummarize, (*ipinfo.IPSummary)(nil)},
+	{"map", "map report URL of a list of IPs", runMap, (*ipinfo.IPMap)(nil)},
+	{"myip", "details of the IP of this machine", runMyIP, (*ipinfo.Core)(nil)},
+	{"bogon", "whether IPs are bogons, without calling the API", runBogon, (*bogonResult)(nil)},
+}
+
+// cli holds the state of an invocation.
+type cli struct {
+	token   string
+	format  string
+	timeout time.Duration
+	result  interface{}
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	failed bool
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.main(os.Args[1:]))
+}
+
+// `main` runs the command line `args` and returns the exit status.
+func (c *cli) main(args []string) int {
+	fs := c.flagSet("ipinfo")
+	fs.Usage = func() { c.usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		c.usage(fs)
+		return 2
+	}
+
+	name := fs.Arg(0)
+	var cmd *command
+	for _, cc := range commands {
+		if cc.name == name {
+			cmd = cc
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(c.stderr, "ipinfo: unknown command %q\n", name)
+		c.usage(fs)
+		return 2
+	}
+
+	// flags are also accepted after the command name.
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return 2
+	}
+	c.result = cmd.result
+	if _, err := newWriter(c.format, c.result, io.Discard); err != nil {
+		fmt.Fprintf(c.stderr, "ipinfo: %v\n", err)
+		return 2
+	}
+
+	if err := cmd.run(c, fs.Args()); err != nil {
+		fmt.Fprintf(c.stderr, "ipinfo: %s: %v\n", cmd.name, err)
+		return 1
+	}
+	if c.failed {
+		return 1
+	}
+	return 0
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.token, "token", c.token, "API `token`; defaults to $IPINFO_TOKEN")
+	fs.StringVar(&c.format, "f", "json", "output `format`: json, ndjson, csv, yaml, table, a field list or a template")
+	fs.DurationVar(&c.timeout, "timeout", 0, "timeout of each API call; 0 means none")
+	return fs
+}
+
+func (c *cli) usage(fs *flag.FlagSet) {
+	fmt.Fprintf(c.stderr, "Usage: ipinfo [flags] <command> [flags] [args...]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(c.stderr, "\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+// `options` returns the client options selected by the environment and
+// flags.
+func (c *cli) options() []ipinfo.Option {
+	opts := []ipinfo.Option{ipinfo.FromEnv()}
+	if c.token != "" {
+		opts = append(opts, ipinfo.WithToken(c.token))
+	}
+	if c.timeout > 0 {
+		opts = append(opts, ipinfo.WithTimeout(c.timeout))
+	}
+	return opts
+}
+
+// `fail` reports the failure of the lookup of `input` and continues.
+func (c *cli) fail(input string, err error) {
+	fmt.Fprintf(c.stderr, "ipinfo: %s: %v\n", input, err)
+	c.failed = true
+}
+
+// `inputs` returns `args`, or the whitespace-separated words of standard
+// input if there are none.
+func (c *cli) inputs(args []string) ([]string, error) {
+	if len(args) != 0 {
+		return args, nil
+	}
+
+	var words []string
+	sc := bufio.NewScanner(c.stdin)
+	sc.Split(bufio.ScanWords)
+	for sc.Scan() {
+		words = append(words, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("no input; pass arguments or write to standard input")
+	}
+	return words, nil
+}
+
+// `ips` returns the IPs among the inputs given by `args`, reporting those
+// which are not IPs.
+func (c *cli) ips(args []string) ([]net.IP, error) {
+	inputs, err := c.inputs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(inputs))
+	for _, s := range inputs {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			c.fail(s, errors.New("invalid IP"))
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// `write` writes `records` to standard output in the selected format.
+func (c *cli) write(records ...interface{}) error {
+	w, err := newWriter(c.format, c.result, c.stdout)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// `lookupEach` looks up each IP given by `args` with `lookup` and writes the
+// results.
+func lookupEach(
+	c *cli,
+	args []string,
+	lookup func(ip net.IP) (interface{}, error),
+) error {
+	ips, err := c.ips(args)
+	if err != nil {
+		return err
+	}
+
+	records := make([]interface{}, 0, len(ips))
+	for _, ip := range ips {
+		v, err := lookup(ip)
+		if err != nil {
+			c.fail(ip.String(), err)
+			continue
+		}
+		records = append(records, v)
+	}
+	return c.write(records...)
+}
+
+/* COMMANDS */
+
+func runLookup(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	return lookupEach(c, args, func(ip net.IP) (interface{}, error) {
+		return client.GetIPInfo(ip)
+	})
+}
+
+func runLite(c *cli, args []string) error {
+	client, err := ipinfo.NewLiteClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	return lookupEach(c, args, func(ip net.IP) (interface{}, error) {
+		return client.GetIPInfo(ip)
+	})
+}
+
+func runCore(c *cli, args []string) error {
+	client, err := ipinfo.NewCoreClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	return lookupEach(c, args, func(ip net.IP) (interface{}, error) {
+		return client.GetIPInfo(ip)
+	})
+}
+
+func runPlus(c *cli, args []string) error {
+	client, err := ipinfo.NewPlusClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	return lookupEach(c, args, func(ip net.IP) (interface{}, error) {
+		return client.GetIPInfo(ip)
+	})
+}
+
+func runResproxy(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	return lookupEach(c, args, func(ip net.IP) (interface{}, error) {
+		return client.GetResproxy(ip.String())
+	})
+}
+
+func runBatch(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	ips, err := c.ips(args)
+	if err != nil {
+		return err
+	}
+	if len(ips) == 0 {
+		return c.write()
+	}
+
+	// a failed batch may still have results for some of the IPs, which are
+	// written before the error is reported.
+	res, batchErr := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{})
+	if batchErr != nil && len(res) == 0 {
+		return batchErr
+	}
+
+	// keep the order of the input.
+	records := make([]interface{}, 0, len(ips))
+	for _, ip := range ips {
+		v, ok := res[ip.String()]
+		if !ok || v == nil {
+			c.fail(ip.String(), errors.New("no result"))
+			continue
+		}
+		records = append(records, v)
+	}
+	if err := c.write(records...); err != nil {
+		return err
+	}
+	return batchErr
+}
+
+func runASN(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	inputs, err := c.inputs(args)
+	if err != nil {
+		return err
+	}
+
+	records := make([]interface{}, 0, len(inputs))
+	for _, s := range inputs {
+		asn := strings.ToUpper(s)
+		if !strings.HasPrefix(asn, "AS") {
+			asn = "AS" + asn
+		}
+		v, err := client.GetASNDetails(asn)
+		if err != nil {
+			c.fail(s, err)
+			continue
+		}
+		records = append(records, v)
+	}
+	return c.write(records...)
+}
+
+func runSummarize(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	ips, err := c.ips(args)
+	if err != nil {
+		return err
+	}
+	v, err := client.GetIPSummary(ips)
+	if err != nil {
+		return err
+	}
+	return c.write(v)
+}
+
+func runMap(c *cli, args []string) error {
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	ips, err := c.ips(args)
+	if err != nil {
+		return err
+	}
+	v, err := client.GetIPMap(ips)
+	if err != nil {
+		return err
+	}
+	return c.write(v)
+}
+
+func runMyIP(c *cli, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments expected")
+	}
+	client, err := ipinfo.NewClientWithOptions(c.options()...)
+	if err != nil {
+		return err
+	}
+	v, err := client.GetIPInfo(nil)
+	if err != nil {
+		return err
+	}
+	return c.write(v)
+}
+
+// bogonResult is the result of the bogon command for an IP.
+type bogonResult struct {
+	IP        string            `json:"ip" csv:"ip"`
+	Bogon     bool              `json:"bogon" csv:"bogon"`
+	BogonInfo *ipinfo.BogonInfo `json:"bogon_info,omitempty" csv:"bogon_,inline"`
+}
+
+func runBogon(c *cli, args []string) error {
+	ips, err := c.ips(args)
+	if err != nil {
+		return err
+	}
+
+	records := make([]interface{}, len(ips))
+	for i, ip := range ips {
+		addr, _ := netip.AddrFromSlice(ip)
+		info := ipinfo.ClassifyBogon(addr.Unmap())
+		records[i] = &bogonResult{IP: ip.String(), Bogon: info != nil, BogonInfo: info}
+	}
+	return c.write(records...)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `newTestServer` returns a fake API server which the commands are pointed
+// at through the environment.
+func newTestServer(t *testing.T) *ipinfotest.Server {
+	t.Helper()
+	srv := ipinfotest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.RequireToken("secret")
+
+	t.Setenv("IPINFO_TOKEN", "secret")
+	t.Setenv("IPINFO_BASE_URL", srv.URL)
+	t.Setenv("IPINFO_LITE_BASE_URL", srv.URL+"/lite")
+	t.Setenv("IPINFO_CORE_BASE_URL", srv.URL+"/lookup")
+	t.Setenv("IPINFO_PLUS_BASE_URL", srv.URL+"/lookup")
+	return srv
+}
+
+// `run` runs the command line `args` with `stdin`, returning the exit status
+// and outputs.
+func run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := c.main(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := run("")
+	if code != 2 || !strings.Contains(stderr, "Usage: ipinfo") {
+		t.Errorf("exit status %d, stderr %q, want usage", code, stderr)
+	}
+
+	code, _, stderr = run("", "whois", "8.8.8.8")
+	if code != 2 || !strings.Contains(stderr, `unknown command "whois"`) {
+		t.Errorf("exit status %d, stderr %q, want an unknown command", code, stderr)
+	}
+
+	code, _, _ = run("", "-nope", "lookup")
+	if code != 2 {
+		t.Errorf("exit status %d for an unknown flag, want 2", code)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+
+	code, stdout, stderr := run("", "lookup", "8.8.8.8")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var v ipinfo.Core
+	if err := json.Unmarshal([]byte(stdout), &v); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if v.City != "Mountain View" {
+		t.Errorf("City = %q", v.City)
+	}
+}
+
+func TestProducts(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{CountryCode: "US"})
+
+	tests := []struct{ command, path string }{
+		{"lite", "/lite/8.8.8.8"},
+		{"core", "/lookup/8.8.8.8"},
+		{"plus", "/lookup/8.8.8.8"},
+		{"resproxy", "/resproxy/8.8.8.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			code, stdout, stderr := run("", tt.command, "8.8.8.8")
+			if code != 0 {
+				t.Fatalf("exit status %d: %s", code, stderr)
+			}
+			if !strings.Contains(stdout, `"8.8.8.8"`) {
+				t.Errorf("output = %q, want the IP", stdout)
+			}
+			reqs := srv.Requests()
+			if last := reqs[len(reqs)-1]; last.Path != tt.path {
+				t.Errorf("path = %q, want %q", last.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestStdinAndFailures(t *testing.T) {
+	newTestServer(t)
+
+	code, stdout, stderr := run("8.8.8.8\nnot-an-ip  1.1.1.1\n", "lookup")
+	if code != 1 {
+		t.Errorf("exit status %d, want 1 for the invalid IP", code)
+	}
+	if !strings.Contains(stderr, "not-an-ip: invalid IP") {
+		t.Errorf("stderr = %q, want the invalid IP reported", stderr)
+	}
+	var results []ipinfo.Core
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if len(results) != 2 || results[0].IP.String() != "8.8.8.8" || results[1].IP.String() != "1.1.1.1" {
+		t.Errorf("results = %+v, want the valid IPs in order", results)
+	}
+
+	code, _, stderr = run("", "lookup")
+	if code != 1 || !strings.Contains(stderr, "no input") {
+		t.Errorf("exit status %d, stderr %q, want no input reported", code, stderr)
+	}
+}
+
+func TestTokenFlag(t *testing.T) {
+	srv := newTestServer(t)
+
+	// flags are accepted after the command name, and override the
+	// environment.
+	code, _, stderr := run("", "lookup", "-token", "wrong", "8.8.8.8")
+	if code != 1 || !strings.Contains(stderr, "403") {
+		t.Errorf("exit status %d, stderr %q, want a 403", code, stderr)
+	}
+	if auth := srv.Requests()[0].Header.Get("Authorization"); auth != "Bearer wrong" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetIP("1.1.1.1", &ipinfo.Core{City: "Brisbane"})
+
+	code, stdout, stderr := run("", "-f", "ndjson", "batch", "8.8.8.8", "1.1.1.1")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "8.8.8.8") || !strings.Contains(lines[1], "Brisbane") {
+		t.Errorf("output = %q, want a line per IP in input order", stdout)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want one batch", n)
+	}
+}
+
+func TestASN(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetASN("AS15169", &ipinfo.ASNDetails{Name: "Google LLC"})
+
+	code, stdout, stderr := run("", "asn", "15169")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Google LLC") || srv.Requests()[0].Path != "/AS15169" {
+		t.Errorf("output = %q, want the details of AS15169", stdout)
+	}
+}
+
+func TestSummarizeAndMap(t *testing.T) {
+	newTestServer(t)
+
+	code, stdout, stderr := run("", "summarize", "8.8.8.8", "8.8.8.8")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var summary ipinfo.IPSummary
+	if err := json.Unmarshal([]byte(stdout), &summary); err != nil || summary.Total != 2 || summary.Unique != 1 {
+		t.Errorf("summary = %+v, %v", summary, err)
+	}
+
+	code, stdout, stderr = run("", "map", "8.8.8.8")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "reportUrl") {
+		t.Errorf("output = %q, want a report URL", stdout)
+	}
+}
+
+func TestMyIP(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetCallerIP("192.0.2.7")
+
+	code, stdout, stderr := run("", "myip")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "192.0.2.7") {
+		t.Errorf("output = %q, want the caller IP", stdout)
+	}
+
+	if code, _, _ := run("", "myip", "8.8.8.8"); code != 1 {
+		t.Errorf("exit status %d for arguments, want 1", code)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View", ASN: &ipinfo.CoreASN{ASN: "AS15169"}})
+
+	tests := []struct {
+		format string
+		check  func(out string) bool
+	}{
+		{"json", func(out string) bool { return strings.HasPrefix(out, "[") }},
+		{"ndjson", func(out string) bool { return strings.Count(out, "\n") == 2 }},
+		{"csv", func(out string) bool {
+			return strings.HasPrefix(out, "ip,") && strings.Contains(out, "asn_id") && strings.Count(out, "\n") == 3
+		}},
+		{"yaml", func(out string) bool {
+			return strings.Contains(out, "city: Mountain View") && strings.Contains(out, "---")
+		}},
+		{"table", func(out string) bool {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			return len(lines) == 3 && strings.Contains(lines[0], "ASN_ASN") && strings.Contains(lines[1], "Mountain View")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			code, stdout, stderr := run("", "-f", tt.format, "lookup", "8.8.8.8", "1.1.1.1")
+			if code != 0 {
+				t.Fatalf("exit status %d: %s", code, stderr)
+			}
+			if !tt.check(stdout) {
+				t.Errorf("output:\n%s", stdout)
+			}
+		})
+	}
+}
+
+func TestJSONSingleResult(t *testing.T) {
+	newTestServer(t)
+
+	// a single result is written as an object, none as an empty array.
+	_, stdout, _ := run("", "lookup", "8.8.8.8")
+	if !strings.HasPrefix(stdout, "{") {
+		t.Errorf("output = %q, want an object", stdout)
+	}
+	_, stdout, _ = run("", "lookup", "not-an-ip")
+	if strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("output = %q, want an empty array", stdout)
+	}
+}
+
+// Check that commands don't resolve hostnames.
+func TestHostnameInput(t *testing.T) {
+	newTestServer(t)
+	if code, _, stderr := run("", "lookup", "localhost"); code != 1 || !strings.Contains(stderr, "invalid IP") {
+		t.Errorf("exit status %d, stderr %q, want the hostname rejected", code, stderr)
+	}
+}
+
+func TestFieldFormats(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+
+	code, stdout, stderr := run("", "-f", "ip,city", "lookup", "8.8.8.8")
+	if code != 0 || stdout != "8.8.8.8\tMountain View\n" {
+		t.Errorf("exit status %d, output %q: %s", code, stdout, stderr)
+	}
+	code, stdout, stderr = run("", "-f", "{{.IP}} in {{.City}}", "lookup", "8.8.8.8")
+	if code != 0 || stdout != "8.8.8.8 in Mountain View\n" {
+		t.Errorf("exit status %d, output %q: %s", code, stdout, stderr)
+	}
+	code, stdout, stderr = run("", "-f", "ip,bogon", "bogon", "10.0.0.1")
+	if code != 0 || stdout != "10.0.0.1\ttrue\n" {
+		t.Errorf("exit status %d, output %q: %s", code, stdout, stderr)
+	}
+}
+
+func TestInvalidFormats(t *testing.T) {
+	srv := newTestServer(t)
+
+	// formats are checked against the results of the command before any
+	// lookup.
+	tests := []struct{ command, spec string }{
+		{"lookup", "jsn"},
+		{"lookup", "ip,geo.city"},
+		{"core", "ip,city"},
+		{"asn", "{{.ASN}} {{.Nme}}"},
+		{"bogon", "ip,country"},
+		{"lookup", "{{.IP"},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := run("", "-f", tt.spec, tt.command, "8.8.8.8")
+		if code != 2 || stdout != "" || stderr == "" {
+			t.Errorf("%s -f %q: exit status %d, output %q, stderr %q, want a usage error",
+				tt.command, tt.spec, code, stdout, stderr)
+		}
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests for invalid formats", n)
+	}
+}
+
+func TestBogon(t *testing.T) {
+	srv := newTestServer(t)
+
+	code, stdout, stderr := run("", "-f", "ndjson", "bogon", "10.0.0.1", "8.8.8.8", "2001:0:a01:203::1", "nope")
+	if code != 1 || !strings.Contains(stderr, "nope: invalid IP") {
+		t.Errorf("exit status %d, stderr %q, want the invalid IP reported", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want a line per IP", stdout)
+	}
+
+	var results []bogonResult
+	for _, line := range lines {
+		var r bogonResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, r)
+	}
+	if r := results[0]; !r.Bogon || r.BogonInfo == nil || r.BogonInfo.Category != ipinfo.BogonPrivate {
+		t.Errorf("10.0.0.1 = %+v, want a private bogon", r)
+	}
+	if r := results[1]; r.IP != "8.8.8.8" || r.Bogon || r.BogonInfo != nil {
+		t.Errorf("8.8.8.8 = %+v, want no bogon", r)
+	}
+	if r := results[2]; r.BogonInfo == nil || r.BogonInfo.Category != ipinfo.BogonTeredo ||
+		r.BogonInfo.Embedded == nil || r.BogonInfo.Embedded.Category != ipinfo.BogonPrivate {
+		t.Errorf("Teredo = %+v, want an embedded private bogon", r)
+	}
+
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests, want none", n)
+	}
+}
+
+func TestBogonCSV(t *testing.T) {
+	newTestServer(t)
+
+	// IPv4 addresses are classified as IPv4 rather than IPv4-mapped.
+	code, stdout, stderr := run("", "-f", "csv", "bogon", "127.0.0.1", "1.1.1.1")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	want := "ip,bogon,bogon_category,bogon_prefix,bogon_rfc\n" +
+		"127.0.0.1,true,loopback,127.0.0.0/8,RFC 1122\n" +
+		"1.1.1.1,false,,,\n"
+	if stdout != want {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+}
+
+func TestCSVResults(t *testing.T) {
+	newTestServer(t)
+
+	code, stdout, stderr := run("", "-f", "csv", "summarize", "8.8.8.8", "8.8.8.8")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "total,unique,mobile,privacy_vpn,") || !strings.Contains(stdout, "\n2,1,") {
+		t.Errorf("output = %q, want a summary row", stdout)
+	}
+
+	code, stdout, stderr = run("", "-f", "csv", "resproxy", "8.8.8.8")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "ip,last_seen,percent_days_seen,service\n8.8.8.8,") {
+		t.Errorf("output = %q, want a resproxy row", stdout)
+	}
+
+	// results without columns are rejected before any lookup.
+	type untagged struct{ Name string }
+	if _, err := newWriter("csv", (*untagged)(nil), io.Discard); err == nil {
+		t.Error("csv writer for results without csv tags, want an error")
+	}
+}
+
+func TestBatchPartial(t *testing.T) {
+	srv := newTestServer(t)
+
+	// of the two batches, the first to arrive fails after the other has
+	// answered.
+	srv.Fault("/batch", ipinfotest.Fault{
+		Status: http.StatusBadRequest,
+		Delay:  100 * time.Millisecond,
+		Times:  1,
+	})
+	args := []string{"-f", "ndjson", "batch"}
+	for i := 0; i < 1001; i++ {
+		args = append(args, fmt.Sprintf("10.%d.%d.1", i/256, i%256))
+	}
+	code, stdout, stderr := run("", args...)
+	if code != 1 || stderr == "" {
+		t.Errorf("exit status %d, stderr %q, want the error of the failed batch", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 1 && len(lines) != 1000 {
+		t.Errorf("output has %d lines, want the results of the other batch", len(lines))
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/format"
+)
+
+// writer writes results in an output format.
+type writer interface {
+	// Write writes or buffers the result `v`.
+	Write(v interface{}) error
+
+	// Flush writes any buffered results.
+	Flush() error
+}
+
+// `newWriter` returns a writer of the output format `spec` to `w`, for
+// results of the type of `result`.
+func newWriter(spec string, result interface{}, w io.Writer) (writer, error) {
+	switch spec {
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "ndjson":
+		return streamWriter{ipinfo.NewNDJSONWriter(w)}, nil
+	case "csv":
+		// check that the results have columns before any lookup.
+		if result != nil {
+			zero := reflect.New(reflect.TypeOf(result).Elem()).Interface()
+			if err := ipinfo.NewCSVWriter(io.Discard).Write(zero); err != nil {
+				return nil, err
+			}
+		}
+		return &csvWriter{w: ipinfo.NewCSVWriter(w)}, nil
+	case "yaml":
+		return streamWriter{ipinfo.NewYAMLWriter(w)}, nil
+	case "table":
+		return &tableWriter{w: w}, nil
+	}
+
+	// anything else is a field list or template, which is validated
+	// against the type of the results, so that a misspelled format such as
+	// "jsn" is rejected as an unknown field before any lookup.
+	f, err := format.New(spec, result)
+	if err != nil {
+		return nil, err
+	}
+	return &formatWriter{f: f, w: w}, nil
+}
+
+// jsonWriter writes a single result as an object, and several as an array.
+type jsonWriter struct {
+	w       io.Writer
+	results []interface{}
+}
+
+func (w *jsonWriter) Write(v interface{}) error {
+	w.results = append(w.results, v)
+	return nil
+}
+
+func (w *jsonWriter) Flush() error {
+	enc := json.NewEncoder(w.w)
+	enc.SetIndent("", "  ")
+	if len(w.results) == 1 {
+		return enc.Encode(w.results[0])
+	}
+	if w.results == nil {
+		w.results = []interface{}{}
+	}
+	return enc.Encode(w.results)
+}
+
+// csvWriter writes results as CSV rows with the columns of their `csv`
+// tags.
+type csvWriter struct {
+	w *ipinfo.CSVWriter
+}
+
+func (w *csvWriter) Write(v interface{}) error {
+	return w.w.Write(v)
+}
+
+func (w *csvWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// streamWriter is a writer which writes each result as soon as it's
+// written, leaving nothing to flush.
+type streamWriter struct {
+	w interface{ Write(v interface{}) error }
+}
+
+func (w streamWriter) Write(v interface{}) error {
+	return w.w.Write(v)
+}
+
+func (w streamWriter) Flush() error {
+	return nil
+}
+
+// formatWriter writes results with a field list or template of the
+// `format` package.
+type formatWriter struct {
+	f format.Formatter
+	w io.Writer
+}
+
+func (w *formatWriter) Write(v interface{}) error {
+	return w.f.Format(w.w, v)
+}
+
+func (w *formatWriter) Flush() error {
+	return nil
+}
+
+// tableWriter writes results as aligned columns of their flattened fields,
+// leaving out fields which are empty in all results.
+type tableWriter struct {
+	w    io.Writer
+	rows rows
+}
+
+func (w *tableWriter) Write(v interface{}) error {
+	return w.rows.add(v)
+}
+
+func (w *tableWriter) Flush() error {
+	values := w.rows.values()
+	var used []int
+	for i := range w.rows.columns {
+		for _, row := range values {
+			if row[i] != "" {
+				used = append(used, i)
+				break
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w.w, 0, 4, 2, ' ', 0)
+	line := make([]string, len(used))
+	for j, i := range used {
+		line[j] = strings.ToUpper(w.rows.columns[i])
+	}
+	fmt.Fprintln(tw, strings.Join(line, "\t"))
+	for _, row := range values {
+		for j, i := range used {
+			line[j] = row[i]
+		}
+		fmt.Fprintln(tw, strings.Join(line, "\t"))
+	}
+	return tw.Flush()
+}
+
+/* FLATTENING */
+
+// object is a JSON object which retains the order of its keys.
+type object struct {
+	keys   []string
+	values []interface{}
+}
+
+// `jsonTree` returns the JSON encoding of `v` decoded into a tree of
+// `*object`, `[]interface{}` and scalar values, with numbers as
+// `json.Number`.
+func jsonTree(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeTree(dec)
+}
+
+func decodeTree(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.values = append(obj.values, val)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// rows accumulates flattened results. Nested fields are named by joining
+// their keys with "_", as in "asn_domain".
+type rows struct {
+	columns []string
+	index   map[string]int
+	records []map[int]string
+}
+
+func (r *rows) add(v interface{}) error {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return err
+	}
+	if r.index == nil {
+		r.index = make(map[string]int)
+	}
+	record := make(map[int]string)
+	r.flatten(record, "", tree)
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *rows) flatten(record map[int]string, prefix string, v interface{}) {
+	if obj, ok := v.(*object); ok {
+		for i, key := range obj.keys {
+			r.flatten(record, prefix+key+"_", obj.values[i])
+		}
+		return
+	}
+
+	name := strings.TrimSuffix(prefix, "_")
+	i, ok := r.index[name]
+	if !ok {
+		i = len(r.columns)
+		r.index[name] = i
+		r.columns = append(r.columns, name)
+	}
+	record[i] = scalarString(v)
+}
+
+// `values` returns the rows of all records, with a value for each column.
+func (r *rows) values() [][]string {
+	res := make([][]string, len(r.records))
+	for i, record := range r.records {
+		row := make([]string, len(r.columns))
+		for j, v := range record {
+			row[j] = v
+		}
+		res[i] = row
+	}
+	return res
+}
+
+// `scalarString` formats a leaf of a JSON tree for a single cell. Arrays of
+// scalars are joined with commas; other arrays are written as JSON.
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			switch e.(type) {
+			case *object, []interface{}:
+				data, _ := json.Marshal(plain(v))
+				return string(data)
+			}
+			parts[i] = scalarString(e)
+		}
+		return strings.Join(parts, ",")
+	}
+	data, _ := json.Marshal(plain(v))
+	return string(data)
+}
+
+// `plain` converts a JSON tree back into values encoding/json can marshal,
+// losing the order of keys.
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *object:
+		m := make(map[string]interface{}, len(v.keys))
+		for i, key := range v.keys {
+			m[key] = plain(v.values[i])
+		}
+		return m
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, e := range v {
+			res[i] = plain(e)
+		}
+		return res
+	}
+	return v
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	client := ipinfo.NewClient(nil, nil, os.Getenv("IPINFO_TOKEN"))
+
+	batch, err := client.GetIPInfoBatch(
+		[]net.IP{
+			net.ParseIP("1.1.1.1"),
+			net.ParseIP("8.8.8.8"),
+		},
+		ipinfo.BatchReqOpts{},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := ipinfo.NewCSVWriter(os.Stdout)
+	if err := w.WriteBatch(batch); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
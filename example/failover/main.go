@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	// prefer an internal caching proxy, falling back to the public API.
+	pool, err := ipinfo.NewEndpointPool(
+		[]string{"http://ipinfo-proxy.internal/", "https://ipinfo.io/"},
+		ipinfo.EndpointPoolOpts{
+			Hedge: &ipinfo.HedgePolicy{
+				Percentile: 0.9,
+				MinDelay:   50 * time.Millisecond,
+			},
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithToken(os.Getenv("IPINFO_TOKEN")),
+		ipinfo.WithEndpointPool(pool),
+		ipinfo.WithCircuitBreaker(ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+			OnStateChange: func(from, to ipinfo.CircuitState) {
+				log.Printf("circuit breaker: %v -> %v", from, to)
+			},
+		})),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %s\n", info.IP, info.Org)
+
+	for _, h := range pool.Health() {
+		fmt.Printf("%s: requests=%d failures=%d\n", h.URL, h.Requests, h.Failures)
+	}
+}
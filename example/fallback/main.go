@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	client := ipinfo.NewFallbackClient(nil, nil, os.Getenv("IPINFO_TOKEN"))
+
+	// try Core before Lite, skipping Plus.
+	client.Order = []ipinfo.Tier{ipinfo.TierCore, ipinfo.TierLite}
+
+	details, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(details)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/format"
+)
+
+func main() {
+	client := ipinfo.NewCoreClient(nil, nil, os.Getenv("IPINFO_TOKEN"))
+
+	// fields are validated against the result type before any lookup.
+	f, err := format.New("ip,geo.city,geo.country_code,as.asn", (*ipinfo.CoreResponse)(nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, ip := range []string{"1.1.1.1", "8.8.8.8"} {
+		info, err := client.GetIPInfo(net.ParseIP(ip))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Format(os.Stdout, info); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
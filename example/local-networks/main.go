@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	// usually loaded from a file with `ipinfo.LoadLocalNetworks`.
+	networks, err := ipinfo.NewLocalNetworks(
+		ipinfo.LocalNetwork{
+			Network: netip.MustParsePrefix("10.0.0.0/8"),
+			City:    "Berlin",
+			Country: "DE",
+			Org:     "Example Corp",
+			ASN:     "AS64512",
+			Tags:    []string{"corporate"},
+		},
+		ipinfo.LocalNetwork{
+			Network: netip.MustParsePrefix("10.20.0.0/16"),
+			City:    "Munich",
+			Country: "DE",
+			Org:     "Example Corp",
+			Tags:    []string{"datacenter"},
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := ipinfo.NewLiteClientWithOptions(
+		ipinfo.WithToken(os.Getenv("IPINFO_TOKEN")),
+		ipinfo.WithLocalNetworks(networks),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, ip := range []string{"10.1.2.3", "10.20.1.1", "8.8.8.8"} {
+		info, err := client.GetIPInfo(net.ParseIP(ip))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if info.Local != nil {
+			fmt.Printf("%s: local %s, %s %v\n", ip, info.Local.Network, info.Country, info.Local.Tags)
+		} else {
+			fmt.Printf("%s: %s, %s\n", ip, info.ASName, info.Country)
+		}
+	}
+}
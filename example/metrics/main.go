@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+)
+
+func main() {
+	metrics := ipinfo.NewMetrics()
+	metrics.Publish("ipinfo") // served on /debug/vars
+
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithToken(os.Getenv("IPINFO_TOKEN")),
+		ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())),
+		ipinfo.WithObserver(metrics),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	http.Handle("/metrics", metrics)
+	http.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		info, err := client.GetIPInfo(net.ParseIP(r.URL.Query().Get("ip")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(info.City + "\n"))
+	})
+	log.Fatal(http.ListenAndServe("localhost:8080", nil))
+}
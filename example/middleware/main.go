@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	client := ipinfo.NewLiteClient(nil, nil, os.Getenv("IPINFO_TOKEN"))
+
+	// log every request the client sends along with its latency.
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			log.Printf("%s %s took %v", req.Method, req.URL.Path, time.Since(start))
+			return resp, err
+		})
+	})
+
+	// tag every request with a custom header.
+	client.OnRequest(func(req *http.Request) error {
+		req.Header.Set("X-Request-Source", "middleware-example")
+		return nil
+	})
+
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s is in %s\n", info.IP, info.Country)
+}
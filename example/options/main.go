@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+)
+
+func main() {
+	// reads IPINFO_TOKEN, IPINFO_CORE_BASE_URL etc. from the environment.
+	client, err := ipinfo.NewCoreClientWithOptions(
+		ipinfo.FromEnv(),
+		ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())),
+		ipinfo.WithTimeout(5*time.Second),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 3}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %+v\n", info.IP, info.Geo)
+}
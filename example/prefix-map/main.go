@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	// a deny list of internal networks.
+	var b ipinfo.PrefixMapBuilder[string]
+	for _, n := range []struct{ prefix, name string }{
+		{"10.0.0.0/8", "corporate"},
+		{"10.20.0.0/16", "datacenter"},
+		{"192.168.0.0/16", "office"},
+		{"fd00::/8", "corporate"},
+	} {
+		if err := b.Add(netip.MustParsePrefix(n.prefix), n.name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	deny := b.Build()
+
+	for _, ip := range []string{"10.20.1.1", "10.1.1.1", "8.8.8.8", "fd00::1"} {
+		prefix, name, ok := deny.LookupPrefix(netip.MustParseAddr(ip))
+		if !ok {
+			fmt.Printf("%s: allowed\n", ip)
+			continue
+		}
+		fmt.Printf("%s: denied by %s (%s)\n", ip, prefix, name)
+	}
+
+	// lookups take time proportional to the prefix length rather than the
+	// number of prefixes; compare against a linear scan of 10000 random
+	// prefixes.
+	r := rand.New(rand.NewSource(1))
+	prefixes := make([]netip.Prefix, 10000)
+	var lb ipinfo.PrefixMapBuilder[struct{}]
+	for i := range prefixes {
+		var a [4]byte
+		r.Read(a[:])
+		prefixes[i] = netip.PrefixFrom(netip.AddrFrom4(a), 16+r.Intn(17)).Masked()
+		if err := lb.Add(prefixes[i], struct{}{}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	m := lb.Build()
+
+	addrs := make([]netip.Addr, 1024)
+	for i := range addrs {
+		var a [4]byte
+		r.Read(a[:])
+		addrs[i] = netip.AddrFrom4(a)
+	}
+
+	linear := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			addr := addrs[i%len(addrs)]
+			for _, p := range prefixes {
+				if p.Contains(addr) {
+					break
+				}
+			}
+		}
+	})
+	trie := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.Contains(addrs[i%len(addrs)])
+		}
+	})
+	bogon := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ipinfo.ClassifyBogon(addrs[i%len(addrs)])
+		}
+	})
+	fmt.Printf("linear scan:  %s\n", linear)
+	fmt.Printf("prefix map:   %s\n", trie)
+	fmt.Printf("bogon lookup: %s\n", bogon)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithToken(os.Getenv("IPINFO_TOKEN")),
+		ipinfo.WithKeepRawBody(),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, meta, err := client.GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("IP: %s (%s)\n", info.IP, info.City)
+	fmt.Printf("Status: %d\n", meta.StatusCode)
+	fmt.Printf("Duration: %v\n", meta.Duration)
+	fmt.Printf("From cache: %v\n", meta.FromCache)
+	if meta.RateLimit != nil {
+		fmt.Printf("Remaining: %d/%d\n", meta.RateLimit.Remaining, meta.RateLimit.Limit)
+	}
+	fmt.Printf("Raw body: %s\n", meta.Body)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func main() {
+	// e.g. IPINFO_TOKENS="token1,token2,token3"
+	pool := ipinfo.NewTokenPool(
+		strings.Split(os.Getenv("IPINFO_TOKENS"), ","),
+		ipinfo.TokenPoolOpts{Strategy: ipinfo.TokenMostRemaining},
+	)
+
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithTokenProvider(pool),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 3}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"} {
+		info, err := client.GetIPInfo(net.ParseIP(ip))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", info.IP, info.Org)
+	}
+
+	for _, u := range pool.Usage() {
+		fmt.Printf("%.4s...: requests=%d errors=%d remaining=%d\n",
+			u.Token, u.Requests, u.Errors, u.Remaining)
+	}
+}
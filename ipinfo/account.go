@@ -0,0 +1,66 @@
+package ipinfo
+
+import (
+	"encoding/json"
+)
+
+// TokenDetails is the account information of an API token, as reported by
+// the `/me` endpoint.
+type TokenDetails struct {
+	Token    string                     `json:"token"`
+	Plan     string                     `json:"plan,omitempty"`
+	Requests TokenRequests              `json:"requests"`
+	Features map[string]json.RawMessage `json:"features,omitempty"`
+}
+
+// TokenRequests represents the request counters and limits of a token.
+type TokenRequests struct {
+	Day       uint64 `json:"day"`
+	Month     uint64 `json:"month"`
+	Limit     uint64 `json:"limit"`
+	Remaining uint64 `json:"remaining"`
+}
+
+// HasFeature reports whether the token has access to the named feature, e.g.
+// "core" or "privacy".
+func (d *TokenDetails) HasFeature(name string) bool {
+	v, ok := d.Features[name]
+	return ok && string(v) != "false" && string(v) != "null"
+}
+
+// Tier returns the highest API tier that the token is entitled to, according
+// to the "plus", "core" and "lite" entitlements reported in `Features`, or
+// `TierUnknown` if none of them are reported.
+func (d *TokenDetails) Tier() Tier {
+	switch {
+	case d.HasFeature("plus"):
+		return TierPlus
+	case d.HasFeature("core"):
+		return TierCore
+	case d.HasFeature("lite"):
+		return TierLite
+	default:
+		return TierUnknown
+	}
+}
+
+// GetTokenDetails returns the account information of the package-level
+// client's token.
+func GetTokenDetails() (*TokenDetails, error) {
+	return DefaultClient.GetTokenDetails()
+}
+
+// GetTokenDetails returns the account information of the client's token,
+// including its plan, limits and remaining requests.
+func (c *Client) GetTokenDetails() (*TokenDetails, error) {
+	req, err := c.transport().newRequest(nil, "GET", "me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(TokenDetails)
+	if _, err := c.transport().do(req, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,126 @@
+package ipinfo
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+)
+
+// InvalidIPError is reported when an invalid IP was specified.
+type InvalidIPError struct {
+	IP string
+}
+
+func (err *InvalidIPError) Error() string {
+	return "invalid IP: " + strconv.Quote(err.IP)
+}
+
+// `validIP` checks `ip`, which is nil to look up the IP of the caller, and
+// returns it as a `netip.Addr`, which is invalid if `ip` is nil. IPv4 addresses
+// in their 16-byte form are returned as IPv4.
+func validIP(ip net.IP) (netip.Addr, error) {
+	if ip == nil {
+		return netip.Addr{}, nil
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		if len(ip) == 0 {
+			return netip.Addr{}, &InvalidIPError{}
+		}
+		return netip.Addr{}, &InvalidIPError{IP: ip.String()}
+	}
+	return addr.Unmap(), nil
+}
+
+// `addrIP` returns `addr` as a `net.IP`. Addresses with a zone can't be looked
+// up and are invalid.
+func addrIP(addr netip.Addr) (net.IP, error) {
+	if !addr.IsValid() {
+		return nil, &InvalidIPError{}
+	}
+	if addr.Zone() != "" {
+		return nil, &InvalidIPError{IP: addr.String()}
+	}
+	return net.IP(addr.Unmap().AsSlice()), nil
+}
+
+// `parseIP` parses `s` as an IP to be looked up.
+func parseIP(s string) (net.IP, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return nil, &InvalidIPError{IP: s}
+	}
+	return net.IP(addr.Unmap().AsSlice()), nil
+}
+
+// `addrIPs` returns `addrs` as `net.IP`s, failing on the first which is
+// invalid.
+func addrIPs(addrs []netip.Addr) ([]net.IP, error) {
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ip, err := addrIP(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips[i] = ip
+	}
+	return ips, nil
+}
+
+// `parseIPs` parses `ips` as IPs to be looked up, failing on the first which
+// is invalid.
+func parseIPs(ips []string) ([]net.IP, error) {
+	parsed := make([]net.IP, len(ips))
+	for i, s := range ips {
+		ip, err := parseIP(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = ip
+	}
+	return parsed, nil
+}
+
+// `ipAddr` returns `ip` as a `netip.Addr`, or the zero `netip.Addr` if `ip` is
+// not set or invalid.
+func ipAddr(ip net.IP) netip.Addr {
+	addr, _ := netip.AddrFromSlice(ip)
+	return addr.Unmap()
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set.
+func (v *Core) Addr() netip.Addr {
+	return ipAddr(v.IP)
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set.
+func (v *Lite) Addr() netip.Addr {
+	return ipAddr(v.IP)
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set.
+func (v *CoreResponse) Addr() netip.Addr {
+	return ipAddr(v.IP)
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set.
+func (v *Plus) Addr() netip.Addr {
+	return ipAddr(v.IP)
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set.
+func (v *IPDetails) Addr() netip.Addr {
+	return ipAddr(v.IP)
+}
+
+// Addr returns the IP of `v` as a `netip.Addr`, or the zero `netip.Addr` if it
+// isn't set or invalid.
+func (v *ResproxyDetails) Addr() netip.Addr {
+	addr, _ := netip.ParseAddr(v.IP)
+	return addr
+}
@@ -0,0 +1,355 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `requireInvalidIP` fails `t` unless `err` is an `*ipinfo.InvalidIPError`.
+func requireInvalidIP(t *testing.T, err error) {
+	t.Helper()
+	var ipErr *ipinfo.InvalidIPError
+	if !errors.As(err, &ipErr) {
+		t.Fatalf("err = %v, want *InvalidIPError", err)
+	}
+}
+
+func TestGetIPInfoV6Variants(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("2001:4860:4860::8888", &ipinfo.Core{City: "Mountain View"})
+	client := srv.Client()
+
+	info, err := client.GetIPAddrInfoV6(netip.MustParseAddr("2001:4860:4860::8888"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q", info.City)
+	}
+	info, _, err = client.GetIPStrInfoV6WithMeta("2001:4860:4860::8888")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q", info.City)
+	}
+
+	_, err = client.GetIPStrInfoV6("not an ip")
+	requireInvalidIP(t, err)
+	_, _, err = client.GetIPAddrInfoV6WithMeta(netip.Addr{})
+	requireInvalidIP(t, err)
+}
+
+func TestGetIPMapVariants(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	if _, err := client.GetIPAddrMap([]netip.Addr{
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("1.1.1.1"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPStrMap([]string{"8.8.8.8", "1.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range srv.Requests() {
+		var ips []string
+		if err := json.Unmarshal(req.Body, &ips); err != nil {
+			t.Fatal(err)
+		}
+		if len(ips) != 2 || ips[0] != "8.8.8.8" || ips[1] != "1.1.1.1" {
+			t.Errorf("body = %s", req.Body)
+		}
+	}
+
+	n := len(srv.Requests())
+	_, err := client.GetIPStrMap([]string{"8.8.8.8", "nope"})
+	requireInvalidIP(t, err)
+	_, err = client.GetIPAddrMap([]netip.Addr{{}})
+	requireInvalidIP(t, err)
+	if len(srv.Requests()) != n {
+		t.Error("invalid IPs were sent")
+	}
+}
+
+func TestGetIPSummaryVariants(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	sum, err := client.GetIPAddrSummary([]netip.Addr{
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("8.8.8.8"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != 2 || sum.Unique != 1 {
+		t.Errorf("summary = %+v", sum)
+	}
+	sum, err = client.GetIPStrSummary([]string{"8.8.8.8", "1.1.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != 2 || sum.Unique != 2 {
+		t.Errorf("summary = %+v", sum)
+	}
+
+	_, err = client.GetIPStrSummary([]string{"8.8.8.8.8"})
+	requireInvalidIP(t, err)
+	_, err = client.GetIPAddrSummary([]netip.Addr{netip.MustParseAddr("fe80::1%eth0")})
+	requireInvalidIP(t, err)
+}
+
+func TestPackageLevelVariants(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{CountryCode: "US"})
+	srv.SetCore("8.8.8.8", &ipinfo.CoreResponse{Geo: &ipinfo.CoreGeo{City: "Mountain View"}})
+	srv.SetPlus("8.8.8.8", &ipinfo.Plus{Geo: &ipinfo.PlusGeo{City: "Mountain View"}})
+
+	lite, core, plus := ipinfo.DefaultLiteClient, ipinfo.DefaultCoreClient, ipinfo.DefaultPlusClient
+	defer func() {
+		ipinfo.DefaultLiteClient, ipinfo.DefaultCoreClient, ipinfo.DefaultPlusClient = lite, core, plus
+	}()
+	ipinfo.DefaultLiteClient = srv.LiteClient()
+	ipinfo.DefaultCoreClient = srv.CoreClient()
+	ipinfo.DefaultPlusClient = srv.PlusClient()
+
+	addr := netip.MustParseAddr("8.8.8.8")
+
+	l, err := ipinfo.GetIPAddrInfoLite(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.CountryCode != "US" {
+		t.Errorf("CountryCode = %q", l.CountryCode)
+	}
+	l, _, err = ipinfo.GetIPStrInfoLiteWithMeta("8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.CountryCode != "US" {
+		t.Errorf("CountryCode = %q", l.CountryCode)
+	}
+
+	c, err := ipinfo.GetIPStrInfoCore("8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Geo == nil || c.Geo.City != "Mountain View" {
+		t.Errorf("Geo = %+v", c.Geo)
+	}
+	c, _, err = ipinfo.GetIPAddrInfoCoreWithMeta(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Geo == nil || c.Geo.City != "Mountain View" {
+		t.Errorf("Geo = %+v", c.Geo)
+	}
+
+	p, err := ipinfo.GetIPAddrInfoPlus(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Geo == nil || p.Geo.City != "Mountain View" {
+		t.Errorf("Geo = %+v", p.Geo)
+	}
+	p, _, err = ipinfo.GetIPStrInfoPlusWithMeta("8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Geo == nil || p.Geo.City != "Mountain View" {
+		t.Errorf("Geo = %+v", p.Geo)
+	}
+
+	n := len(srv.Requests())
+	_, err = ipinfo.GetIPStrInfoLite("")
+	requireInvalidIP(t, err)
+	_, err = ipinfo.GetIPAddrInfoCore(netip.Addr{})
+	requireInvalidIP(t, err)
+	_, _, err = ipinfo.GetIPStrInfoPlusWithMeta("8.8")
+	requireInvalidIP(t, err)
+	if len(srv.Requests()) != n {
+		t.Error("invalid IPs were sent")
+	}
+}
+
+func TestFallbackAndAutoVariants(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Core", "core"))
+	srv.SetPlus("8.8.8.8", &ipinfo.Plus{Geo: &ipinfo.PlusGeo{City: "Mountain View"}})
+
+	fc := fallbackClient(srv)
+	d, err := fc.GetIPAddrInfo(netip.MustParseAddr("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Tier != ipinfo.TierPlus || d.Geo == nil || d.Geo.City != "Mountain View" {
+		t.Errorf("details = %+v", d)
+	}
+	if _, err := fc.GetIPStrInfo("8.8.8.8"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = fc.GetIPStrInfo("8.8.8.256")
+	requireInvalidIP(t, err)
+
+	ac := autoClient(srv)
+	res, err := ac.GetIPStrInfo("8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierCore {
+		t.Errorf("Tier = %v, want %v", res.Tier, ipinfo.TierCore)
+	}
+	if _, err := ac.GetIPAddrInfo(netip.MustParseAddr("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ac.GetIPAddrInfo(netip.Addr{})
+	requireInvalidIP(t, err)
+}
+
+func TestAddrLookuper(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Core", "core"))
+
+	tests := []struct {
+		name     string
+		lookuper ipinfo.AddrLookuper
+	}{
+		{"Client", srv.Client()},
+		{"LiteClient", srv.LiteClient()},
+		{"CoreClient", srv.CoreClient()},
+		{"PlusClient", srv.PlusClient()},
+		{"FallbackClient", fallbackClient(srv)},
+		{"AutoClient", autoClient(srv)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.lookuper.LookupAddr(netip.MustParseAddr("8.8.8.8"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !d.IP.Equal(net.ParseIP("8.8.8.8")) {
+				t.Errorf("IP = %v", d.IP)
+			}
+			d, err = tt.lookuper.LookupStr("2001:4860:4860::8888")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !d.IP.Equal(net.ParseIP("2001:4860:4860::8888")) {
+				t.Errorf("IP = %v", d.IP)
+			}
+
+			_, err = tt.lookuper.LookupStr("example.com")
+			requireInvalidIP(t, err)
+			_, err = tt.lookuper.LookupAddr(netip.Addr{})
+			requireInvalidIP(t, err)
+		})
+	}
+}
+
+func TestGetIPStrInfoBatchInvalid(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+	client := srv.Client()
+
+	res, err := client.GetIPStrInfoBatch(
+		[]string{"8.8.8.8", "not an ip", "AS15169", "1.1.1.1"},
+		ipinfo.BatchReqOpts{},
+	)
+	var be *ipinfo.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("err = %v, want a *BatchError", err)
+	}
+	if len(be.Errs) != 2 {
+		t.Fatalf("Errs = %v, want the two invalid entries", be.Errs)
+	}
+	for i, want := range []string{"not an ip", "AS15169"} {
+		var ipErr *ipinfo.InvalidIPError
+		if !errors.As(be.Errs[i], &ipErr) || ipErr.IP != want {
+			t.Errorf("Errs[%d] = %v, want an *InvalidIPError for %q", i, be.Errs[i], want)
+		}
+	}
+	requireInvalidIP(t, err)
+
+	if len(res) != 2 {
+		t.Fatalf("len(res) = %d, want 2: %v", len(res), res)
+	}
+	if res["8.8.8.8"] == nil || res["8.8.8.8"].City != "Mountain View" {
+		t.Errorf("res[8.8.8.8] = %+v", res["8.8.8.8"])
+	}
+	if res["1.1.1.1"] == nil {
+		t.Error("res[1.1.1.1] is missing")
+	}
+
+	var urls []string
+	reqs := srv.Requests()
+	if err := json.Unmarshal(reqs[len(reqs)-1].Body, &urls); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("batch body = %s", reqs[len(reqs)-1].Body)
+	}
+
+	// valid input is not an error.
+	if _, err := client.GetIPStrInfoBatch([]string{"8.8.8.8"}, ipinfo.BatchReqOpts{}); err != nil {
+		t.Errorf("err = %v for valid input", err)
+	}
+}
+
+func TestGetIPInfoBatchNil(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	res, err := srv.Client().GetIPInfoBatch(
+		[]net.IP{net.ParseIP("8.8.8.8"), nil},
+		ipinfo.BatchReqOpts{},
+	)
+	var be *ipinfo.BatchError
+	if !errors.As(err, &be) || len(be.Errs) != 1 {
+		t.Fatalf("err = %v, want a *BatchError for the nil IP", err)
+	}
+	requireInvalidIP(t, err)
+	if len(res) != 1 || res["8.8.8.8"] == nil {
+		t.Errorf("res = %v, want the result for 8.8.8.8", res)
+	}
+}
+
+func TestGetASNDetailsBatchInvalid(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.SetASN("AS15169", &ipinfo.ASNDetails{Name: "Google LLC"})
+
+	client := srv.Client(ipinfo.WithLocalNetworks(localNetworks(t)))
+	res, err := client.GetASNDetailsBatch(
+		[]string{"AS15169", "10.1.2.3", "8.8.8.8/city"},
+		ipinfo.BatchReqOpts{},
+	)
+	var be *ipinfo.BatchError
+	if !errors.As(err, &be) || len(be.Errs) != 2 {
+		t.Fatalf("err = %v, want a *BatchError for the two non-ASN entries", err)
+	}
+	var asnErr *ipinfo.InvalidASNError
+	if !errors.As(be.Errs[0], &asnErr) || asnErr.ASN != "10.1.2.3" {
+		t.Errorf("Errs[0] = %v, want an *InvalidASNError for 10.1.2.3", be.Errs[0])
+	}
+	if len(res) != 1 || res["AS15169"] == nil || res["AS15169"].Name != "Google LLC" {
+		t.Errorf("res = %v, want the result for AS15169", res)
+	}
+}
@@ -2,35 +2,36 @@ package ipinfo
 
 import (
 	"strings"
+	"time"
 )
 
 // ASNDetails represents details for an ASN.
 type ASNDetails struct {
-	ASN         string             `json:"asn"`
-	Name        string             `json:"name"`
-	Country     string             `json:"country"`
-	CountryName string             `json:"-"`
-	Allocated   string             `json:"allocated"`
-	Registry    string             `json:"registry"`
-	Domain      string             `json:"domain"`
-	NumIPs      uint64             `json:"num_ips"`
-	Type        string             `json:"type"`
-	Prefixes    []ASNDetailsPrefix `json:"prefixes"`
-	Prefixes6   []ASNDetailsPrefix `json:"prefixes6"`
-	Peers       []string           `json:"peers"`
-	Upstreams   []string           `json:"upstreams"`
-	Downstreams []string           `json:"downstreams"`
+	ASN         string             `json:"asn" csv:"asn" yaml:"asn,omitempty" schema:"required"`
+	Name        string             `json:"name" csv:"name" yaml:"name,omitempty" schema:"required"`
+	Country     string             `json:"country" csv:"country" yaml:"country,omitempty"`
+	CountryName string             `json:"-" csv:"country_name" yaml:"countryName,omitempty"`
+	Allocated   string             `json:"allocated" csv:"allocated" yaml:"allocated,omitempty"`
+	Registry    string             `json:"registry" csv:"registry" yaml:"registry,omitempty"`
+	Domain      string             `json:"domain" csv:"domain" yaml:"domain,omitempty"`
+	NumIPs      uint64             `json:"num_ips" csv:"num_ips" yaml:"numIPs,omitempty"`
+	Type        string             `json:"type" csv:"type" yaml:"type,omitempty"`
+	Prefixes    []ASNDetailsPrefix `json:"prefixes" csv:"-" yaml:"prefixes,omitempty"`
+	Prefixes6   []ASNDetailsPrefix `json:"prefixes6" csv:"-" yaml:"prefixes6,omitempty"`
+	Peers       []string           `json:"peers" csv:"-" yaml:"peers,omitempty"`
+	Upstreams   []string           `json:"upstreams" csv:"-" yaml:"upstreams,omitempty"`
+	Downstreams []string           `json:"downstreams" csv:"-" yaml:"downstreams,omitempty"`
 }
 
 // ASNDetailsPrefix represents data for prefixes managed by an ASN.
 type ASNDetailsPrefix struct {
-	Netblock string `json:"netblock"`
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Country  string `json:"country"`
-	Size     string `json:"size"`
-	Status   string `json:"status"`
-	Domain   string `json:"domain"`
+	Netblock string `json:"netblock" yaml:"netblock,omitempty"`
+	ID       string `json:"id" yaml:"id,omitempty"`
+	Name     string `json:"name" yaml:"name,omitempty"`
+	Country  string `json:"country" yaml:"country,omitempty"`
+	Size     string `json:"size" yaml:"size,omitempty"`
+	Status   string `json:"status" yaml:"status,omitempty"`
+	Domain   string `json:"domain" yaml:"domain,omitempty"`
 }
 
 // InvalidASNError is reported when the invalid ASN was specified.
@@ -53,40 +54,58 @@ func GetASNDetails(asn string) (*ASNDetails, error) {
 	return DefaultClient.GetASNDetails(asn)
 }
 
+// GetASNDetailsWithMeta returns the details for the specified ASN along with
+// metadata about the API response.
+func GetASNDetailsWithMeta(asn string) (*ASNDetails, *ResponseMeta, error) {
+	return DefaultClient.GetASNDetailsWithMeta(asn)
+}
+
 // GetASNDetails returns the details for the specified ASN.
 func (c *Client) GetASNDetails(asn string) (*ASNDetails, error) {
+	v, _, err := c.GetASNDetailsWithMeta(asn)
+	return v, err
+}
+
+// GetASNDetailsWithMeta returns the details for the specified ASN along with
+// metadata about the API response.
+func (c *Client) GetASNDetailsWithMeta(
+	asn string,
+) (*ASNDetails, *ResponseMeta, error) {
+	start := time.Now()
+	cache := c.transport().config().observedCache
 	if !strings.HasPrefix(asn, "AS") {
-		return nil, &InvalidASNError{ASN: asn}
+		return nil, nil, &InvalidASNError{ASN: asn}
 	}
 
 	// perform cache lookup.
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey(asn)); err == nil {
-			return res.(*ASNDetails), nil
+	if cache != nil {
+		if res, err := cache.Get(cacheKey(asn)); err == nil {
+			return res.(*ASNDetails), cachedMeta(start), nil
 		}
 	}
 
 	// prepare req
-	req, err := c.newRequest(nil, "GET", asn, nil)
+	req, err := c.transport().newRequest(nil, "GET", asn, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// do req
 	v := new(ASNDetails)
-	if _, err := c.do(req, v); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, v)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	// format
 	v.setCountryName()
 
 	// cache req result
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey(asn), v); err != nil {
-			return v, err
+	if cache != nil {
+		if err := cache.Set(cacheKey(asn), v); err != nil {
+			return v, meta, err
 		}
 	}
 
-	return v, nil
+	return v, meta, nil
 }
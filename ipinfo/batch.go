@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -40,6 +41,55 @@ type BatchCore map[string]*Core
 // `ASNDetails` data.
 type BatchASNDetails map[string]*ASNDetails
 
+// BatchError is reported by batch requests which left out some of their
+// inputs, e.g. because they are invalid, along with the results for the
+// remaining inputs.
+type BatchError struct {
+	// Errors for the inputs left out, in the order of the inputs, e.g.
+	// `*InvalidIPError`, followed by the error of the request, if any.
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "batch: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of `e.Errs` matches `target`.
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of `e.Errs` which matches `target`.
+func (e *BatchError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// `batchError` returns the error of a batch request which failed with `err`
+// and left out inputs for `invalid`, or nil if there are neither.
+func batchError(invalid []error, err error) error {
+	if len(invalid) == 0 {
+		return err
+	}
+	if err != nil {
+		invalid = append(invalid, err)
+	}
+	return &BatchError{Errs: invalid}
+}
+
 // BatchReqOpts are options input into batch request functions.
 type BatchReqOpts struct {
 	// BatchSize is the internal batch size used per API request; the IPinfo
@@ -100,12 +150,28 @@ func (c *Client) GetBatch(
 	var result Batch
 	var mu sync.Mutex
 
-	// if the cache is available, filter out URLs already cached.
+	cfg := c.transport().config()
+	cache := cfg.observedCache
+
+	// answer IPs in local networks without the API.
 	result = make(Batch, len(urls))
-	if c.Cache != nil {
+	if cfg.local != nil {
+		remaining := make([]string, 0, len(urls))
+		for _, url := range urls {
+			if v := cfg.local.lookupURL(url); v != nil {
+				result[url] = v
+			} else {
+				remaining = append(remaining, url)
+			}
+		}
+		urls = remaining
+	}
+
+	// if the cache is available, filter out URLs already cached.
+	if cache != nil {
 		lookupUrls = make([]string, 0, len(urls)/2)
 		for _, url := range urls {
-			if res, err := c.Cache.Get(cacheKey(url)); err == nil {
+			if res, err := cache.Get(cacheKey(url)); err == nil {
 				result[url] = res
 			} else {
 				lookupUrls = append(lookupUrls, url)
@@ -190,7 +256,7 @@ func (c *Client) GetBatch(
 			}
 			jsonBuf := bytes.NewBuffer(jsonArrStr)
 
-			req, err := c.newRequest(timeoutPerBatchCtx, "POST", postURL, jsonBuf)
+			req, err := c.transport().newRequest(timeoutPerBatchCtx, "POST", postURL, jsonBuf)
 			if err != nil {
 				return err
 			}
@@ -200,7 +266,16 @@ func (c *Client) GetBatch(
 			// network data into it; once we have it local we'll merge it with
 			// `result` in a concurrency-safe way.
 			localResult := new(batch)
-			if _, err := c.do(req, localResult); err != nil {
+			chunkStart := time.Now()
+			_, err = c.transport().do(req, localResult)
+			if len(cfg.observers) != 0 {
+				cfg.observers.batchChunk(timeoutPerBatchCtx, &BatchChunkEvent{
+					Size:     len(urlsChunk),
+					Duration: time.Since(chunkStart),
+					Err:      err,
+				})
+			}
+			if err != nil {
 				return err
 			}
 
@@ -213,6 +288,11 @@ func (c *Client) GetBatch(
 					if err := json.Unmarshal(v, decodedV); err != nil {
 						return err
 					}
+					if cfg.strictSchema {
+						if err := CheckSchema(v, decodedV); err != nil {
+							return err
+						}
+					}
 
 					decodedV.setCountryName()
 					result[k] = decodedV
@@ -221,6 +301,11 @@ func (c *Client) GetBatch(
 					if err := json.Unmarshal(v, decodedV); err != nil {
 						return err
 					}
+					if cfg.strictSchema {
+						if err := CheckSchema(v, decodedV); err != nil {
+							return err
+						}
+					}
 
 					decodedV.setCountryName()
 					result[k] = decodedV
@@ -246,10 +331,10 @@ func (c *Client) GetBatch(
 	// 2. doing it while updating `result` inside the request workers would be
 	//    problematic if the cache is external since we take a mutex lock for
 	//    that entire period.
-	if c.Cache != nil {
+	if cache != nil {
 		for _, url := range lookupUrls {
 			if v, exists := result[url]; exists {
-				if err := c.Cache.Set(cacheKey(url), v); err != nil {
+				if err := cache.Set(cacheKey(url), v); err != nil {
 					// NOTE: still return the result even if the cache fails.
 					return result, err
 				}
@@ -270,20 +355,21 @@ func GetIPInfoBatch(
 	return DefaultClient.GetIPInfoBatch(ips, opts)
 }
 
-// GetIPInfoBatch does a batch request for all `ips` at once.
+// GetIPInfoBatch does a batch request for all `ips` at once. Entries of `ips`
+// which are nil or invalid are left out of the request and reported in a
+// `*BatchError`, which is returned along with the results of the others.
 func (c *Client) GetIPInfoBatch(
 	ips []net.IP,
 	opts BatchReqOpts,
 ) (BatchCore, error) {
-	ipstrs := make([]string, 0, len(ips))
-	if c.Token == "" {
+	ipstrs := make([]string, len(ips))
+	if !c.transport().config().hasToken() {
 		return nil, fmt.Errorf("invalid token")
 	}
-	for _, ip := range ips {
-		if ip == nil {
-			continue
+	for i, ip := range ips {
+		if ip != nil {
+			ipstrs[i] = ip.String()
 		}
-		ipstrs = append(ipstrs, ip.String())
 	}
 
 	return c.GetIPStrInfoBatch(ipstrs, opts)
@@ -299,25 +385,39 @@ func GetIPStrInfoBatch(
 	return DefaultClient.GetIPStrInfoBatch(ips, opts)
 }
 
-// GetIPStrInfoBatch does a batch request for all `ips` at once.
+// GetIPStrInfoBatch does a batch request for all `ips` at once. Entries of
+// `ips` which are not valid IPs are left out of the request and reported in
+// a `*BatchError`, which is returned along with the results of the others.
 func (c *Client) GetIPStrInfoBatch(
 	ips []string,
 	opts BatchReqOpts,
 ) (BatchCore, error) {
-	intermediateRes, err := c.GetBatch(ips, opts)
+	var invalid []error
+	valid := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if _, err := parseIP(ip); err != nil {
+			invalid = append(invalid, err)
+			continue
+		}
+		valid = append(valid, ip)
+	}
+
+	intermediateRes, err := c.GetBatch(valid, opts)
 
 	// if we have items in the result, don't throw them away; we'll convert
 	// below and return the error together if it existed.
 	if err != nil && len(intermediateRes) == 0 {
-		return nil, err
+		return nil, batchError(invalid, err)
 	}
 
 	res := make(BatchCore, len(intermediateRes))
 	for k, v := range intermediateRes {
-		res[k] = v.(*Core)
+		if core, ok := v.(*Core); ok {
+			res[k] = core
+		}
 	}
 
-	return res, err
+	return res, batchError(invalid, err)
 }
 
 /* ASN */
@@ -330,22 +430,42 @@ func GetASNDetailsBatch(
 	return DefaultClient.GetASNDetailsBatch(asns, opts)
 }
 
-// GetASNDetailsBatch does a batch request for all `asns` at once.
+// GetASNDetailsBatch does a batch request for all `asns` at once. Entries of
+// `asns` which are not ASNs are left out of the request and reported in a
+// `*BatchError`, which is returned along with the results of the others.
 func (c *Client) GetASNDetailsBatch(
 	asns []string,
 	opts BatchReqOpts,
 ) (BatchASNDetails, error) {
-	intermediateRes, err := c.GetBatch(asns, opts)
+	var invalid []error
+	valid := make([]string, 0, len(asns))
+	for _, asn := range asns {
+		if !strings.HasPrefix(asn, "AS") {
+			invalid = append(invalid, &InvalidASNError{ASN: asn})
+			continue
+		}
+		valid = append(valid, asn)
+	}
+
+	intermediateRes, err := c.GetBatch(valid, opts)
 
 	// if we have items in the result, don't throw them away; we'll convert
 	// below and return the error together if it existed.
 	if err != nil && len(intermediateRes) == 0 {
-		return nil, err
+		return nil, batchError(invalid, err)
 	}
 
 	res := make(BatchASNDetails, len(intermediateRes))
-	for k, v := range intermediateRes {
-		res[k] = v.(*ASNDetails)
+	for _, asn := range valid {
+		v, ok := intermediateRes[asn]
+		if !ok {
+			continue
+		}
+		if details, ok := v.(*ASNDetails); ok {
+			res[asn] = details
+		} else {
+			invalid = append(invalid, &InvalidASNError{ASN: asn})
+		}
 	}
-	return res, err
+	return res, batchError(invalid, err)
 }
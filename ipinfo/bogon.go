@@ -1,71 +1,215 @@
 package ipinfo
 
 import (
+	"net"
 	"net/netip"
 )
 
+// BogonCategory is the kind of special-purpose address block a bogon belongs
+// to, as registered by IANA.
+type BogonCategory string
+
+const (
+	// BogonThisNetwork is "this network", 0.0.0.0/8.
+	BogonThisNetwork BogonCategory = "this-network"
+
+	// BogonPrivate is private-use address space, e.g. 10.0.0.0/8.
+	BogonPrivate BogonCategory = "private"
+
+	// BogonSharedAddress is the shared address space of carrier-grade NAT,
+	// 100.64.0.0/10.
+	BogonSharedAddress BogonCategory = "cgnat"
+
+	// BogonLoopback is loopback addresses, e.g. 127.0.0.0/8 and ::1.
+	BogonLoopback BogonCategory = "loopback"
+
+	// BogonLinkLocal is link-local addresses, e.g. 169.254.0.0/16.
+	BogonLinkLocal BogonCategory = "link-local"
+
+	// BogonIETFProtocol is IETF protocol assignments, 192.0.0.0/24.
+	BogonIETFProtocol BogonCategory = "ietf-protocol"
+
+	// BogonDocumentation is address space reserved for documentation, e.g.
+	// 192.0.2.0/24 and 2001:db8::/32.
+	BogonDocumentation BogonCategory = "documentation"
+
+	// BogonBenchmarking is address space for benchmarking, 198.18.0.0/15.
+	BogonBenchmarking BogonCategory = "benchmarking"
+
+	// BogonMulticast is multicast addresses, e.g. 224.0.0.0/4.
+	BogonMulticast BogonCategory = "multicast"
+
+	// BogonReserved is address space reserved for future use, 240.0.0.0/4.
+	BogonReserved BogonCategory = "reserved"
+
+	// BogonBroadcast is the limited broadcast address, 255.255.255.255.
+	BogonBroadcast BogonCategory = "broadcast"
+
+	// BogonUnspecified is the unspecified IPv6 address, ::.
+	BogonUnspecified BogonCategory = "unspecified"
+
+	// BogonIPv4Mapped is IPv4-mapped IPv6 addresses, ::ffff:0:0/96.
+	BogonIPv4Mapped BogonCategory = "ipv4-mapped"
+
+	// BogonIPv4Compatible is deprecated IPv4-compatible IPv6 addresses,
+	// ::/96.
+	BogonIPv4Compatible BogonCategory = "ipv4-compatible"
+
+	// BogonDiscard is the IPv6 discard prefix, 100::/64.
+	BogonDiscard BogonCategory = "discard"
+
+	// BogonORCHID is deprecated ORCHID addresses, 2001:10::/28.
+	BogonORCHID BogonCategory = "orchid"
+
+	// BogonUniqueLocal is IPv6 unique local addresses, fc00::/7.
+	BogonUniqueLocal BogonCategory = "unique-local"
+
+	// BogonSiteLocal is deprecated IPv6 site-local addresses, fec0::/10.
+	BogonSiteLocal BogonCategory = "site-local"
+
+	// Bogon6to4 is 6to4 addresses embedding an IPv4 bogon, e.g.
+	// 2002:a00::/24 for 10.0.0.0/8.
+	Bogon6to4 BogonCategory = "6to4-embedded"
+
+	// BogonTeredo is Teredo addresses whose server embeds an IPv4 bogon,
+	// e.g. 2001:0:a00::/40 for 10.0.0.0/8.
+	BogonTeredo BogonCategory = "teredo-embedded"
+)
+
+// BogonInfo describes the special-purpose address block a bogon belongs to.
+type BogonInfo struct {
+	// Category of the block.
+	Category BogonCategory `json:"category" csv:"category" yaml:"category,omitempty"`
+
+	// The block itself.
+	Prefix netip.Prefix `json:"prefix" csv:"prefix" yaml:"prefix,omitempty"`
+
+	// The RFC defining the block, e.g. "RFC 1918".
+	RFC string `json:"rfc" csv:"rfc" yaml:"rfc,omitempty"`
+
+	// For 6to4 and Teredo addresses, the classification of the embedded
+	// IPv4 bogon.
+	Embedded *BogonInfo `json:"embedded,omitempty" csv:"-" yaml:"embedded,omitempty"`
+}
+
+// IsBogon reports whether `ip` is a bogon, i.e. an address which is reserved
+// or otherwise not routable on the public internet. Lookups of bogons are
+// answered without calling the API.
+func IsBogon(ip net.IP) bool {
+	return isBogon(ipAddr(ip))
+}
+
+// ClassifyBogon returns the special-purpose block which `ip` belongs to, or
+// nil if `ip` is not a bogon. The most specific block is reported.
+//
+// Note that an IPv4 address converted from a 16-byte `net.IP` is an
+// IPv4-mapped IPv6 address; use `netip.Addr.Unmap` to classify it as IPv4.
+func ClassifyBogon(ip netip.Addr) *BogonInfo {
+	b := lookupBogon(ip)
+	if b == nil {
+		return nil
+	}
+
+	info := &BogonInfo{Category: b.category, Prefix: b.prefix, RFC: b.rfc}
+	switch b.category {
+	case Bogon6to4:
+		// the IPv4 address follows the 2002::/16 prefix.
+		a := ip.As16()
+		info.Embedded = ClassifyBogon(netip.AddrFrom4([4]byte{a[2], a[3], a[4], a[5]}))
+	case BogonTeredo:
+		// the IPv4 address of the Teredo server follows the 2001::/32
+		// prefix.
+		a := ip.As16()
+		info.Embedded = ClassifyBogon(netip.AddrFrom4([4]byte{a[4], a[5], a[6], a[7]}))
+	}
+	return info
+}
+
 func isBogon(ip netip.Addr) bool {
-	for _, network := range bogonNetworks {
-		if network.Contains(ip) {
-			return true
+	return lookupBogon(ip) != nil
+}
+
+// `lookupBogon` returns the most specific bogon network containing `ip`, or
+// nil.
+func lookupBogon(ip netip.Addr) *bogonNetwork {
+	b, _ := bogons.Lookup(ip)
+	return b
+}
+
+type bogonNetwork struct {
+	prefix   netip.Prefix
+	category BogonCategory
+	rfc      string
+}
+
+func bogon(prefix string, category BogonCategory, rfc string) bogonNetwork {
+	return bogonNetwork{netip.MustParsePrefix(prefix), category, rfc}
+}
+
+// bogons indexes `bogonNetworks` for lookups.
+var bogons = func() *PrefixMap[*bogonNetwork] {
+	var b PrefixMapBuilder[*bogonNetwork]
+	for i := range bogonNetworks {
+		if err := b.Add(bogonNetworks[i].prefix, &bogonNetworks[i]); err != nil {
+			panic(err)
 		}
 	}
-	return false
-}
+	return b.Build()
+}()
 
-var bogonNetworks = []netip.Prefix{
-	netip.MustParsePrefix("0.0.0.0/8"),
-	netip.MustParsePrefix("10.0.0.0/8"),
-	netip.MustParsePrefix("100.64.0.0/10"),
-	netip.MustParsePrefix("127.0.0.0/8"),
-	netip.MustParsePrefix("169.254.0.0/16"),
-	netip.MustParsePrefix("172.16.0.0/12"),
-	netip.MustParsePrefix("192.0.0.0/24"),
-	netip.MustParsePrefix("192.0.2.0/24"),
-	netip.MustParsePrefix("192.168.0.0/16"),
-	netip.MustParsePrefix("198.18.0.0/15"),
-	netip.MustParsePrefix("198.51.100.0/24"),
-	netip.MustParsePrefix("203.0.113.0/24"),
-	netip.MustParsePrefix("224.0.0.0/4"),
-	netip.MustParsePrefix("240.0.0.0/4"),
-	netip.MustParsePrefix("255.255.255.255/32"),
-	netip.MustParsePrefix("::/128"),
-	netip.MustParsePrefix("::1/128"),
-	netip.MustParsePrefix("::ffff:0:0/96"),
-	netip.MustParsePrefix("::/96"),
-	netip.MustParsePrefix("100::/64"),
-	netip.MustParsePrefix("2001:10::/28"),
-	netip.MustParsePrefix("2001:db8::/32"),
-	netip.MustParsePrefix("fc00::/7"),
-	netip.MustParsePrefix("fe80::/10"),
-	netip.MustParsePrefix("fec0::/10"),
-	netip.MustParsePrefix("ff00::/8"),
-	netip.MustParsePrefix("2002::/24"),
-	netip.MustParsePrefix("2002:a00::/24"),
-	netip.MustParsePrefix("2002:7f00::/24"),
-	netip.MustParsePrefix("2002:a9fe::/32"),
-	netip.MustParsePrefix("2002:ac10::/28"),
-	netip.MustParsePrefix("2002:c000::/40"),
-	netip.MustParsePrefix("2002:c000:200::/40"),
-	netip.MustParsePrefix("2002:c0a8::/32"),
-	netip.MustParsePrefix("2002:c612::/31"),
-	netip.MustParsePrefix("2002:c633:6400::/40"),
-	netip.MustParsePrefix("2002:cb00:7100::/40"),
-	netip.MustParsePrefix("2002:e000::/20"),
-	netip.MustParsePrefix("2002:f000::/20"),
-	netip.MustParsePrefix("2002:ffff:ffff::/48"),
-	netip.MustParsePrefix("2001::/40"),
-	netip.MustParsePrefix("2001:0:a00::/40"),
-	netip.MustParsePrefix("2001:0:7f00::/40"),
-	netip.MustParsePrefix("2001:0:a9fe::/48"),
-	netip.MustParsePrefix("2001:0:ac10::/44"),
-	netip.MustParsePrefix("2001:0:c000::/56"),
-	netip.MustParsePrefix("2001:0:c000:200::/56"),
-	netip.MustParsePrefix("2001:0:c0a8::/48"),
-	netip.MustParsePrefix("2001:0:c612::/47"),
-	netip.MustParsePrefix("2001:0:c633:6400::/56"),
-	netip.MustParsePrefix("2001:0:cb00:7100::/56"),
-	netip.MustParsePrefix("2001:0:e000::/36"),
-	netip.MustParsePrefix("2001:0:f000::/36"),
-	netip.MustParsePrefix("2001:0:ffff:ffff::/64"),
+var bogonNetworks = []bogonNetwork{
+	bogon("0.0.0.0/8", BogonThisNetwork, "RFC 1122"),
+	bogon("10.0.0.0/8", BogonPrivate, "RFC 1918"),
+	bogon("100.64.0.0/10", BogonSharedAddress, "RFC 6598"),
+	bogon("127.0.0.0/8", BogonLoopback, "RFC 1122"),
+	bogon("169.254.0.0/16", BogonLinkLocal, "RFC 3927"),
+	bogon("172.16.0.0/12", BogonPrivate, "RFC 1918"),
+	bogon("192.0.0.0/24", BogonIETFProtocol, "RFC 6890"),
+	bogon("192.0.2.0/24", BogonDocumentation, "RFC 5737"),
+	bogon("192.168.0.0/16", BogonPrivate, "RFC 1918"),
+	bogon("198.18.0.0/15", BogonBenchmarking, "RFC 2544"),
+	bogon("198.51.100.0/24", BogonDocumentation, "RFC 5737"),
+	bogon("203.0.113.0/24", BogonDocumentation, "RFC 5737"),
+	bogon("224.0.0.0/4", BogonMulticast, "RFC 5771"),
+	bogon("240.0.0.0/4", BogonReserved, "RFC 1112"),
+	bogon("255.255.255.255/32", BogonBroadcast, "RFC 919"),
+	bogon("::/128", BogonUnspecified, "RFC 4291"),
+	bogon("::1/128", BogonLoopback, "RFC 4291"),
+	bogon("::ffff:0:0/96", BogonIPv4Mapped, "RFC 4291"),
+	bogon("::/96", BogonIPv4Compatible, "RFC 4291"),
+	bogon("100::/64", BogonDiscard, "RFC 6666"),
+	bogon("2001:10::/28", BogonORCHID, "RFC 4843"),
+	bogon("2001:db8::/32", BogonDocumentation, "RFC 3849"),
+	bogon("fc00::/7", BogonUniqueLocal, "RFC 4193"),
+	bogon("fe80::/10", BogonLinkLocal, "RFC 4291"),
+	bogon("fec0::/10", BogonSiteLocal, "RFC 3879"),
+	bogon("ff00::/8", BogonMulticast, "RFC 4291"),
+	bogon("2002::/24", Bogon6to4, "RFC 3056"),
+	bogon("2002:a00::/24", Bogon6to4, "RFC 3056"),
+	bogon("2002:7f00::/24", Bogon6to4, "RFC 3056"),
+	bogon("2002:a9fe::/32", Bogon6to4, "RFC 3056"),
+	bogon("2002:ac10::/28", Bogon6to4, "RFC 3056"),
+	bogon("2002:c000::/40", Bogon6to4, "RFC 3056"),
+	bogon("2002:c000:200::/40", Bogon6to4, "RFC 3056"),
+	bogon("2002:c0a8::/32", Bogon6to4, "RFC 3056"),
+	bogon("2002:c612::/31", Bogon6to4, "RFC 3056"),
+	bogon("2002:c633:6400::/40", Bogon6to4, "RFC 3056"),
+	bogon("2002:cb00:7100::/40", Bogon6to4, "RFC 3056"),
+	bogon("2002:e000::/20", Bogon6to4, "RFC 3056"),
+	bogon("2002:f000::/20", Bogon6to4, "RFC 3056"),
+	bogon("2002:ffff:ffff::/48", Bogon6to4, "RFC 3056"),
+	bogon("2001::/40", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:a00::/40", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:7f00::/40", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:a9fe::/48", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:ac10::/44", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:c000::/56", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:c000:200::/56", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:c0a8::/48", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:c612::/47", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:c633:6400::/56", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:cb00:7100::/56", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:e000::/36", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:f000::/36", BogonTeredo, "RFC 4380"),
+	bogon("2001:0:ffff:ffff::/64", BogonTeredo, "RFC 4380"),
 }
@@ -0,0 +1,186 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestClassifyBogon(t *testing.T) {
+	tests := []struct {
+		ip       string
+		category ipinfo.BogonCategory
+		prefix   string
+		rfc      string
+	}{
+		{"0.1.2.3", ipinfo.BogonThisNetwork, "0.0.0.0/8", "RFC 1122"},
+		{"10.0.0.1", ipinfo.BogonPrivate, "10.0.0.0/8", "RFC 1918"},
+		{"100.64.0.1", ipinfo.BogonSharedAddress, "100.64.0.0/10", "RFC 6598"},
+		{"127.0.0.1", ipinfo.BogonLoopback, "127.0.0.0/8", "RFC 1122"},
+		{"169.254.1.1", ipinfo.BogonLinkLocal, "169.254.0.0/16", "RFC 3927"},
+		{"172.31.255.255", ipinfo.BogonPrivate, "172.16.0.0/12", "RFC 1918"},
+		{"192.0.0.8", ipinfo.BogonIETFProtocol, "192.0.0.0/24", "RFC 6890"},
+		{"192.0.2.1", ipinfo.BogonDocumentation, "192.0.2.0/24", "RFC 5737"},
+		{"192.168.1.1", ipinfo.BogonPrivate, "192.168.0.0/16", "RFC 1918"},
+		{"198.19.0.1", ipinfo.BogonBenchmarking, "198.18.0.0/15", "RFC 2544"},
+		{"203.0.113.7", ipinfo.BogonDocumentation, "203.0.113.0/24", "RFC 5737"},
+		{"239.255.255.250", ipinfo.BogonMulticast, "224.0.0.0/4", "RFC 5771"},
+		{"240.0.0.1", ipinfo.BogonReserved, "240.0.0.0/4", "RFC 1112"},
+		// the most specific block is reported.
+		{"255.255.255.255", ipinfo.BogonBroadcast, "255.255.255.255/32", "RFC 919"},
+		{"::", ipinfo.BogonUnspecified, "::/128", "RFC 4291"},
+		{"::1", ipinfo.BogonLoopback, "::1/128", "RFC 4291"},
+		{"::ffff:8.8.8.8", ipinfo.BogonIPv4Mapped, "::ffff:0:0/96", "RFC 4291"},
+		{"::8.8.8.8", ipinfo.BogonIPv4Compatible, "::/96", "RFC 4291"},
+		{"100::1", ipinfo.BogonDiscard, "100::/64", "RFC 6666"},
+		{"2001:10::1", ipinfo.BogonORCHID, "2001:10::/28", "RFC 4843"},
+		{"2001:db8::1", ipinfo.BogonDocumentation, "2001:db8::/32", "RFC 3849"},
+		{"fd00::1", ipinfo.BogonUniqueLocal, "fc00::/7", "RFC 4193"},
+		{"fe80::1", ipinfo.BogonLinkLocal, "fe80::/10", "RFC 4291"},
+		{"fec0::1", ipinfo.BogonSiteLocal, "fec0::/10", "RFC 3879"},
+		{"ff02::1", ipinfo.BogonMulticast, "ff00::/8", "RFC 4291"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			info := ipinfo.ClassifyBogon(netip.MustParseAddr(tt.ip))
+			if info == nil {
+				t.Fatal("not a bogon")
+			}
+			if info.Category != tt.category || info.Prefix != netip.MustParsePrefix(tt.prefix) || info.RFC != tt.rfc {
+				t.Errorf("ClassifyBogon = %+v, want %s %s %s", info, tt.category, tt.prefix, tt.rfc)
+			}
+			if info.Embedded != nil {
+				t.Errorf("Embedded = %+v", info.Embedded)
+			}
+			// a `net.IP` of an IPv4-mapped address is IPv4.
+			if tt.category != ipinfo.BogonIPv4Mapped && !ipinfo.IsBogon(net.ParseIP(tt.ip)) {
+				t.Error("IsBogon = false")
+			}
+		})
+	}
+}
+
+func TestClassifyBogonEmbedded(t *testing.T) {
+	tests := []struct {
+		ip       string
+		category ipinfo.BogonCategory
+		embedded ipinfo.BogonCategory
+		prefix   string
+	}{
+		// 6to4 addresses of 10.0.0.1, 192.168.1.1 and 127.0.0.1.
+		{"2002:a00:1::1", ipinfo.Bogon6to4, ipinfo.BogonPrivate, "10.0.0.0/8"},
+		{"2002:c0a8:101::1", ipinfo.Bogon6to4, ipinfo.BogonPrivate, "192.168.0.0/16"},
+		{"2002:7f00:1::", ipinfo.Bogon6to4, ipinfo.BogonLoopback, "127.0.0.0/8"},
+		{"2002:ffff:ffff::1", ipinfo.Bogon6to4, ipinfo.BogonBroadcast, "255.255.255.255/32"},
+		// Teredo addresses with servers 10.1.2.3 and 172.16.0.1.
+		{"2001:0:a01:203::1", ipinfo.BogonTeredo, ipinfo.BogonPrivate, "10.0.0.0/8"},
+		{"2001:0:ac10:1::1", ipinfo.BogonTeredo, ipinfo.BogonPrivate, "172.16.0.0/12"},
+		{"2001:0:e000:1::1", ipinfo.BogonTeredo, ipinfo.BogonMulticast, "224.0.0.0/4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			info := ipinfo.ClassifyBogon(netip.MustParseAddr(tt.ip))
+			if info == nil || info.Category != tt.category {
+				t.Fatalf("ClassifyBogon = %+v, want %s", info, tt.category)
+			}
+			e := info.Embedded
+			if e == nil || e.Category != tt.embedded || e.Prefix != netip.MustParsePrefix(tt.prefix) {
+				t.Errorf("Embedded = %+v, want %s %s", e, tt.embedded, tt.prefix)
+			}
+		})
+	}
+
+	// 6to4 and Teredo addresses of public IPv4 addresses aren't bogons.
+	for _, ip := range []string{"2002:808:808::1", "2001:0:808:808::1", "2002:101:101::"} {
+		if info := ipinfo.ClassifyBogon(netip.MustParseAddr(ip)); info != nil {
+			t.Errorf("ClassifyBogon(%s) = %+v, want nil", ip, info)
+		}
+	}
+}
+
+func TestIsBogon(t *testing.T) {
+	tests := []struct {
+		ip   net.IP
+		want bool
+	}{
+		{net.ParseIP("8.8.8.8"), false},
+		{net.ParseIP("1.1.1.1"), false},
+		{net.ParseIP("172.32.0.1"), false},
+		{net.ParseIP("100.128.0.1"), false},
+		{net.ParseIP("2606:4700:4700::1111"), false},
+		{net.ParseIP("2001:4860:4860::8888"), false},
+		// 16-byte IPv4 addresses are classified as IPv4.
+		{net.ParseIP("10.1.1.1"), true},
+		{net.ParseIP("10.1.1.1").To4(), true},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := ipinfo.IsBogon(tt.ip); got != tt.want {
+			t.Errorf("IsBogon(%v) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if info := ipinfo.ClassifyBogon(netip.Addr{}); info != nil {
+		t.Errorf("ClassifyBogon of the zero Addr = %+v", info)
+	}
+	if info := ipinfo.ClassifyBogon(netip.MustParseAddr("8.8.8.8")); info != nil {
+		t.Errorf("ClassifyBogon(8.8.8.8) = %+v", info)
+	}
+}
+
+func TestBogonInfoJSON(t *testing.T) {
+	info := ipinfo.ClassifyBogon(netip.MustParseAddr("2002:a00::1"))
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"category":"6to4-embedded","prefix":"2002:a00::/24","rfc":"RFC 3056",` +
+		`"embedded":{"category":"private","prefix":"10.0.0.0/8","rfc":"RFC 1918"}}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
+
+func TestBogonLookups(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	// bogons are answered without calling the API, by every client.
+	ip := net.ParseIP("192.168.1.1")
+	core, err := srv.Client().GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !core.Bogon || core.BogonInfo == nil || core.BogonInfo.Category != ipinfo.BogonPrivate {
+		t.Errorf("Core = %+v, want a private bogon", core)
+	}
+	lite, err := srv.LiteClient().GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lite.Bogon || lite.BogonInfo == nil {
+		t.Errorf("Lite = %+v, want a bogon", lite)
+	}
+	resp, err := srv.CoreClient().GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Bogon || resp.BogonInfo == nil {
+		t.Errorf("CoreResponse = %+v, want a bogon", resp)
+	}
+	plus, err := srv.PlusClient().GetIPInfo(net.ParseIP("2002:c0a8:101::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plus.Bogon || plus.BogonInfo == nil || plus.BogonInfo.Embedded == nil {
+		t.Errorf("Plus = %+v, want a 6to4 bogon", plus)
+	}
+
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests for bogons", n)
+	}
+}
@@ -0,0 +1,334 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	circuitFailureRateDefault    = 0.5
+	circuitMinRequestsDefault    = 10
+	circuitWindowDefault         = 10 * time.Second
+	circuitOpenDurationDefault   = 30 * time.Second
+	circuitHalfOpenProbesDefault = 1
+)
+
+// ErrCircuitOpen is matched by the errors returned for requests rejected by an
+// open `CircuitBreaker`, i.e. `errors.Is(err, ErrCircuitOpen)` holds for them.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned for requests rejected by a `CircuitBreaker`
+// without being sent.
+type CircuitOpenError struct {
+	// State of the breaker when the request was rejected; either
+	// `CircuitOpen` or `CircuitHalfOpen` when all probes are in flight.
+	State CircuitState
+
+	// Time at which the breaker will let a probe through, if open.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.State == CircuitHalfOpen {
+		return "circuit breaker half-open: waiting for probe"
+	}
+	return fmt.Sprintf(
+		"circuit breaker open until %s",
+		e.RetryAt.Format(time.RFC3339),
+	)
+}
+
+// Is reports whether `target` is `ErrCircuitOpen`.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a `CircuitBreaker`.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through while tracking their error
+	// rate.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects all requests until the open duration has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a limited number of probe requests through to
+	// decide whether to close or re-open the circuit.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOpts are options for a `CircuitBreaker`.
+type CircuitBreakerOpts struct {
+	// FailureRate is the ratio of failed requests, between 0 and 1, within a
+	// window at which the circuit opens.
+	//
+	// 0 means to use a default of 0.5.
+	FailureRate float64
+
+	// MinRequests is the minimum number of requests within a window before
+	// the circuit may open, so that a few failures during low traffic don't
+	// open it.
+	//
+	// 0 means to use a default of 10.
+	MinRequests int
+
+	// Window is the interval over which the failure rate is measured; the
+	// counts are reset at the end of every window.
+	//
+	// 0 means to use a default of 10 seconds.
+	Window time.Duration
+
+	// OpenDuration is how long the circuit stays open before probing.
+	//
+	// 0 means to use a default of 30 seconds.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of requests let through while half-open;
+	// the circuit closes once all of them succeed and re-opens as soon as one
+	// fails.
+	//
+	// 0 means to use a default of 1.
+	HalfOpenProbes int
+
+	// IsFailure reports whether a request which ended with `resp` or `err`
+	// counts as failed. Requests canceled by the caller count as neither
+	// failed nor successful, and aren't passed to it.
+	//
+	// nil means to count network errors, timeouts and 5xx responses.
+	IsFailure func(resp *http.Response, err error) bool
+
+	// Fallback, if set, serves requests while the circuit is open instead of
+	// failing them with a `*CircuitOpenError`, e.g. by forwarding them to a
+	// caching proxy.
+	Fallback http.RoundTripper
+
+	// OnStateChange, if set, is called on every state change of the breaker.
+	// It is called synchronously, but without holding any locks.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops sending requests to the API while it fails, so that
+// calls fail fast instead of each waiting for a timeout.
+//
+// A breaker is set on a client with `WithCircuitBreaker` and applies to every
+// request of the client, including each chunk of a batch request and each
+// retry. A breaker may be shared by several clients to trip them together.
+//
+// Calls rejected by an open breaker fail with a `*CircuitOpenError`; a
+// `FallbackClient` whose tiers have separate breakers falls back to the next
+// tier for them.
+type CircuitBreaker struct {
+	opts CircuitBreakerOpts
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// NewCircuitBreaker returns a closed circuit breaker configured by `opts`.
+func NewCircuitBreaker(opts CircuitBreakerOpts) *CircuitBreaker {
+	if opts.FailureRate <= 0 {
+		opts.FailureRate = circuitFailureRateDefault
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = circuitMinRequestsDefault
+	}
+	if opts.Window <= 0 {
+		opts.Window = circuitWindowDefault
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = circuitOpenDurationDefault
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = circuitHalfOpenProbesDefault
+	}
+	return &CircuitBreaker{opts: opts, windowStart: time.Now()}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && !time.Now().Before(b.retryAt()) {
+		// the next request will be a probe.
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Reset closes the breaker and clears its counts.
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	from := b.state
+	b.setState(CircuitClosed, time.Now())
+	b.mu.Unlock()
+	b.notify(from, CircuitClosed)
+}
+
+// `allow` reports whether a request may be sent now, returning the
+// generation to pass to `done` with its outcome if so.
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	now := time.Now()
+	from := b.state
+
+	switch b.state {
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.opts.Window {
+			// requests still in flight count for the old window only.
+			b.generation++
+			b.resetCounts(now)
+		}
+	case CircuitOpen:
+		if now.Before(b.retryAt()) {
+			err := &CircuitOpenError{State: CircuitOpen, RetryAt: b.retryAt()}
+			b.mu.Unlock()
+			return 0, err
+		}
+		b.setState(CircuitHalfOpen, now)
+	}
+
+	if b.state == CircuitHalfOpen {
+		if b.probes >= b.opts.HalfOpenProbes {
+			b.mu.Unlock()
+			b.notify(from, CircuitHalfOpen)
+			return 0, &CircuitOpenError{State: CircuitHalfOpen}
+		}
+		b.probes++
+	}
+
+	b.requests++
+	gen, to := b.generation, b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return gen, nil
+}
+
+// `done` records the outcome of a request let through by `allow` in
+// generation `gen`, which ended with `resp` or `err`. Outcomes of requests
+// from earlier generations are ignored, as they predate the last state change
+// or window.
+func (b *CircuitBreaker) done(gen uint64, resp *http.Response, err error) {
+	canceled := errors.Is(err, context.Canceled)
+	failed := !canceled && b.isFailure(resp, err)
+
+	b.mu.Lock()
+	from := b.state
+	if gen != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case canceled:
+		// a canceled probe frees its slot for another.
+		b.requests--
+		if b.state == CircuitHalfOpen {
+			b.probes--
+		}
+	case b.state == CircuitClosed:
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.opts.MinRequests &&
+			float64(b.failures) >= b.opts.FailureRate*float64(b.requests) {
+			b.setState(CircuitOpen, now)
+		}
+	case b.state == CircuitHalfOpen:
+		if failed {
+			b.setState(CircuitOpen, now)
+		} else {
+			b.successes++
+			if b.successes >= b.opts.HalfOpenProbes {
+				b.setState(CircuitClosed, now)
+			}
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// `isFailure` reports whether a request which ended with `resp` or `err`
+// counts as failed.
+func (b *CircuitBreaker) isFailure(resp *http.Response, err error) bool {
+	if b.opts.IsFailure != nil {
+		return b.opts.IsFailure(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// `reject` handles a request rejected with `err` by `allow`, serving it from
+// the fallback if there is one.
+func (b *CircuitBreaker) reject(
+	req *http.Request,
+	err error,
+) (*http.Response, error) {
+	if b.opts.Fallback != nil {
+		return b.opts.Fallback.RoundTrip(req)
+	}
+	return nil, err
+}
+
+// `setState` moves to `state`, starting a new generation. Must be called
+// with `b.mu` held.
+func (b *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	b.state = state
+	b.generation++
+	b.resetCounts(now)
+	if state == CircuitOpen {
+		b.openedAt = now
+	}
+}
+
+// `resetCounts` starts a new window. Must be called with `b.mu` held.
+func (b *CircuitBreaker) resetCounts(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+}
+
+// `retryAt` returns when an open breaker lets a probe through. Must be
+// called with `b.mu` held.
+func (b *CircuitBreaker) retryAt() time.Time {
+	return b.openedAt.Add(b.opts.OpenDuration)
+}
+
+// `notify` calls the state change callback if `from` and `to` differ.
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,362 @@
+package ipinfo_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// stateRecorder records the state changes of a breaker.
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *stateRecorder) record(from, to ipinfo.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, from.String()+" -> "+to.String())
+}
+
+func (r *stateRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.changes...)
+}
+
+// `lookupN` looks up `n` IPs with `client`, ignoring errors.
+func lookupN(client *ipinfo.Client, n int) {
+	for i := 0; i < n; i++ {
+		client.GetIPInfo(net.IPv4(8, 8, 8, byte(i)))
+	}
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	rec := &stateRecorder{}
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:   4,
+		OpenDuration:  time.Hour,
+		OnStateChange: rec.record,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 4)
+	if state := breaker.State(); state != ipinfo.CircuitOpen {
+		t.Fatalf("State = %v after 4 failures, want %v", state, ipinfo.CircuitOpen)
+	}
+
+	start := time.Now()
+	_, err := client.GetIPInfo(net.ParseIP("1.1.1.1"))
+	var openErr *ipinfo.CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ipinfo.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want a CircuitOpenError", err)
+	}
+	if openErr.State != ipinfo.CircuitOpen || openErr.RetryAt.Before(start.Add(59*time.Minute)) {
+		t.Errorf("err = %+v, want open for an hour", openErr)
+	}
+	if n := len(srv.Requests()); n != 4 {
+		t.Errorf("server received %d requests, want the rejected one not sent", n)
+	}
+	if changes := rec.get(); !reflect.DeepEqual(changes, []string{"closed -> open"}) {
+		t.Errorf("state changes = %q", changes)
+	}
+}
+
+func TestCircuitBreakerMinRequests(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 3})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests: 10,
+		FailureRate: 0.5,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+
+	// 3 failures out of 10 requests stay below the rate.
+	lookupN(client, 10)
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v, want %v", state, ipinfo.CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 4})
+
+	rec := &stateRecorder{}
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:    2,
+		OpenDuration:   20 * time.Millisecond,
+		HalfOpenProbes: 2,
+		OnStateChange:  rec.record,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 2)
+
+	// a failed probe re-opens the circuit.
+	time.Sleep(30 * time.Millisecond)
+	if state := breaker.State(); state != ipinfo.CircuitHalfOpen {
+		t.Errorf("State = %v after the open duration, want %v", state, ipinfo.CircuitHalfOpen)
+	}
+	lookupN(client, 1)
+	if state := breaker.State(); state != ipinfo.CircuitOpen {
+		t.Errorf("State = %v after a failed probe, want %v", state, ipinfo.CircuitOpen)
+	}
+
+	// the remaining fault fails the first probe of the next round, so
+	// sleep twice.
+	time.Sleep(30 * time.Millisecond)
+	lookupN(client, 1)
+	time.Sleep(30 * time.Millisecond)
+	lookupN(client, 1)
+	if state := breaker.State(); state != ipinfo.CircuitHalfOpen {
+		t.Errorf("State = %v after one successful probe, want %v", state, ipinfo.CircuitHalfOpen)
+	}
+	lookupN(client, 1)
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v after all probes succeeded, want %v", state, ipinfo.CircuitClosed)
+	}
+
+	want := []string{
+		"closed -> open",
+		"open -> half-open", "half-open -> open",
+		"open -> half-open", "half-open -> open",
+		"open -> half-open", "half-open -> closed",
+	}
+	if changes := rec.get(); !reflect.DeepEqual(changes, want) {
+		t.Errorf("state changes = %q, want %q", changes, want)
+	}
+}
+
+func TestCircuitBreakerProbesInFlight(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:  1,
+		OpenDuration: 10 * time.Millisecond,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	// while the probe is in flight, other requests are rejected.
+	srv.Slow("/", 100*time.Millisecond)
+	done := make(chan error)
+	go func() {
+		_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+		done <- err
+	}()
+	time.Sleep(30 * time.Millisecond)
+	_, err := client.GetIPInfo(net.ParseIP("1.1.1.1"))
+	var openErr *ipinfo.CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.State != ipinfo.CircuitHalfOpen {
+		t.Errorf("err = %v, want a half-open CircuitOpenError", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("probe: %v", err)
+	}
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v, want %v", state, ipinfo.CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:  1,
+		OpenDuration: 10 * time.Millisecond,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	// a canceled probe decides nothing...
+	canceled := srv.Client(
+		ipinfo.WithCircuitBreaker(breaker),
+		ipinfo.WithHTTPClient(&http.Client{
+			Transport: ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return nil, context.Canceled
+			}),
+		}),
+	)
+	lookupN(canceled, 1)
+	if state := breaker.State(); state != ipinfo.CircuitHalfOpen {
+		t.Errorf("State = %v after a canceled probe, want %v", state, ipinfo.CircuitHalfOpen)
+	}
+
+	// ...and leaves its slot to the next one.
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v after a successful probe, want %v", state, ipinfo.CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{
+		Status: http.StatusBadGateway,
+		Delay:  50 * time.Millisecond,
+		Times:  1,
+	})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests: 1,
+		Window:      20 * time.Millisecond,
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+
+	// a failure from the last window doesn't count for the next.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lookupN(client, 1)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v after a failure from the last window, want %v", state, ipinfo.CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerIsFailure(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusNotFound})
+
+	// 4xx responses don't count by default...
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{MinRequests: 2})
+	lookupN(srv.Client(ipinfo.WithCircuitBreaker(breaker)), 4)
+	if state := breaker.State(); state != ipinfo.CircuitClosed {
+		t.Errorf("State = %v after 404s, want %v", state, ipinfo.CircuitClosed)
+	}
+
+	// ...but may be made to.
+	breaker = ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests: 2,
+		IsFailure: func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 400
+		},
+	})
+	lookupN(srv.Client(ipinfo.WithCircuitBreaker(breaker)), 2)
+	if state := breaker.State(); state != ipinfo.CircuitOpen {
+		t.Errorf("State = %v after 404s counted as failures, want %v", state, ipinfo.CircuitOpen)
+	}
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	proxy := ipinfotest.NewServer()
+	defer proxy.Close()
+	proxy.SetIP("8.8.8.8", &ipinfo.Core{City: "From Proxy"})
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:  1,
+		OpenDuration: time.Hour,
+		Fallback: ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			u := *req.URL
+			u.Host = proxy.Listener.Addr().String()
+			req = req.Clone(req.Context())
+			req.URL = &u
+			req.Host = ""
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 1)
+
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "From Proxy" {
+		t.Errorf("City = %q, want the fallback to answer", info.City)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests while open", n-1)
+	}
+}
+
+func TestCircuitBreakerBatch(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.Fault("/batch", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{MinRequests: 2})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	ips := []net.IP{net.ParseIP("8.8.8.8")}
+
+	// every chunk is guarded.
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{}); err == nil {
+			t.Fatal("err = nil for a failed chunk")
+		}
+	}
+	if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{}); !errors.Is(err, ipinfo.ErrCircuitOpen) {
+		t.Errorf("err = %v, want %v", err, ipinfo.ErrCircuitOpen)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server received %d chunks, want the one after the circuit opened rejected", n)
+	}
+}
+
+func TestCircuitBreakerReset(t *testing.T) {
+	rec := &stateRecorder{}
+	breaker := ipinfo.NewCircuitBreaker(ipinfo.CircuitBreakerOpts{
+		MinRequests:   1,
+		OnStateChange: rec.record,
+	})
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+	client := srv.Client(ipinfo.WithCircuitBreaker(breaker))
+	lookupN(client, 1)
+
+	breaker.Reset()
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v after Reset", err)
+	}
+	want := []string{"closed -> open", "open -> closed"}
+	if changes := rec.get(); !reflect.DeepEqual(changes, want) {
+		t.Errorf("state changes = %q, want %q", changes, want)
+	}
+}
+
+func TestCircuitStateString(t *testing.T) {
+	for state, want := range map[ipinfo.CircuitState]string{
+		ipinfo.CircuitClosed:   "closed",
+		ipinfo.CircuitOpen:     "open",
+		ipinfo.CircuitHalfOpen: "half-open",
+		ipinfo.CircuitState(9): "unknown",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("String of %d = %q, want %q", int(state), got, want)
+		}
+	}
+}
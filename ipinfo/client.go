@@ -1,13 +1,12 @@
 package ipinfo
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
 )
 
 const (
@@ -17,10 +16,11 @@ const (
 )
 
 // A Client is the main handler to communicate with the IPinfo API.
+//
+// The exported fields reflect the configuration of the client. Assigning
+// them takes effect with the next request, but unlike `Reconfigure` is not
+// safe while requests are in flight.
 type Client struct {
-	// HTTP client used to communicate with the API.
-	client *http.Client
-
 	// Base URL for API requests. BaseURL should always be specified with a
 	// trailing slash.
 	BaseURL *url.URL
@@ -34,6 +34,11 @@ type Client struct {
 
 	// The API token used for authorization for more data and higher limits.
 	Token string
+
+	// Transport of the client, created on first use if the client wasn't
+	// created by a constructor.
+	t    *transport
+	once sync.Once
 }
 
 // NewClient returns a new IPinfo API client.
@@ -50,125 +55,38 @@ func NewClient(
 	cache *Cache,
 	token string,
 ) *Client {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-
-	baseURL, _ := url.Parse(defaultBaseURL)
-	return &Client{
-		client:    httpClient,
-		BaseURL:   baseURL,
-		UserAgent: defaultUserAgent,
-		Cache:     cache,
-		Token:     token,
-	}
+	return newClient(newClientTransport(httpClient, cache, token))
 }
 
-// newRequest for IPV4
-func (c *Client) newRequest(
-	ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-) (*http.Request, error) {
-	return c.newRequestBase(ctx, method, urlStr, body, false)
-}
-
-// newRequest for IPV6
-func (c *Client) newRequestV6(
-	ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-) (*http.Request, error) {
-	return c.newRequestBase(ctx, method, urlStr, body, true)
-}
-
-// `newRequest` creates an API request. A relative URL can be provided in
-// urlStr, in which case it is resolved relative to the BaseURL of the Client.
-// Relative URLs should always be specified without a preceding slash.
-func (c *Client) newRequestBase(
-	ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-	useIPv6 bool,
-) (*http.Request, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	u := new(url.URL)
-
-	baseURL := c.BaseURL
-	if useIPv6 {
-		baseURL, _ = url.Parse(defaultBaseURLIPv6)
-	}
-
-	// get final URL path.
-	if rel, err := url.Parse(urlStr); err == nil {
-		u = baseURL.ResolveReference(rel)
-	} else if strings.ContainsRune(urlStr, ':') {
-		// IPv6 strings fail to parse as URLs, so let's add it as a URL Path.
-		*u = *baseURL
-		u.Path += urlStr
-	} else {
-		return nil, err
-	}
-
-	// get `http` package request object.
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
-	if err != nil {
-		return nil, err
-	}
-
-	// set common headers.
-	req.Header.Set("Accept", "application/json")
-	if c.UserAgent != "" {
-		req.Header.Set("User-Agent", c.UserAgent)
-	}
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
-	}
-
-	return req, nil
-}
-
-// `do` sends an API request and returns the API response. The API response is
-// JSON decoded and stored in the value pointed to by v, or returned as an
-// error if an API error has occurred. If v implements the io.Writer interface,
-// the raw response body will be written to v, without attempting to first
-// decode it.
-func (c *Client) do(
-	req *http.Request,
-	v interface{},
-) (*http.Response, error) {
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	err = checkResponse(resp)
-	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
-	}
+// `newClientTransport` returns a transport for the legacy API.
+func newClientTransport(
+	httpClient *http.Client,
+	cache *Cache,
+	token string,
+) *transport {
+	t := newTransport(httpClient, defaultBaseURL, "", cache, token)
+	t.cfg.baseURLIPv6, _ = url.Parse(defaultBaseURLIPv6)
+	return t
+}
 
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-			if err == io.EOF {
-				// ignore EOF errors caused by empty response body
-				err = nil
-			}
+// `transport` returns the transport of `c`, creating one with the defaults
+// of `NewClient` if `c` wasn't created by a constructor.
+func (c *Client) transport() *transport {
+	c.once.Do(func() {
+		if c.t == nil {
+			c.t = newClientTransport(nil, nil, "")
 		}
-	}
+		c.t.bind(&clientFields{&c.BaseURL, &c.UserAgent, &c.Cache, &c.Token})
+	})
+	return c.t
+}
 
-	return resp, err
+// `newClient` returns a Client using `t`, with its exported fields set
+// from the configuration of `t`.
+func newClient(t *transport) *Client {
+	c := &Client{t: t}
+	c.transport()
+	return c
 }
 
 // An ErrorResponse reports an error caused by an API request.
@@ -217,9 +135,26 @@ func SetCache(cache *Cache) {
 	DefaultClient.SetCache(cache)
 }
 
-// SetCache assigns a cache to the client `c`.
+// SetCache assigns a cache to the client.
+//
+// SetCache is safe to call concurrently with requests.
 func (c *Client) SetCache(cache *Cache) {
-	c.Cache = cache
+	c.transport().update(WithCache(cache))
+}
+
+// SetCache assigns a cache to the client. See `Client.SetCache`.
+func (c *LiteClient) SetCache(cache *Cache) {
+	c.transport().update(WithCache(cache))
+}
+
+// SetCache assigns a cache to the client. See `Client.SetCache`.
+func (c *CoreClient) SetCache(cache *Cache) {
+	c.transport().update(WithCache(cache))
+}
+
+// SetCache assigns a cache to the client. See `Client.SetCache`.
+func (c *PlusClient) SetCache(cache *Cache) {
+	c.transport().update(WithCache(cache))
 }
 
 /* SetToken */
@@ -229,7 +164,24 @@ func SetToken(token string) {
 	DefaultClient.SetToken(token)
 }
 
-// SetToken assigns a token to the client `c`.
+// SetToken assigns a token to the client.
+//
+// SetToken is safe to call concurrently with requests.
 func (c *Client) SetToken(token string) {
-	c.Token = token
+	c.transport().update(WithToken(token))
+}
+
+// SetToken assigns a token to the client. See `Client.SetToken`.
+func (c *LiteClient) SetToken(token string) {
+	c.transport().update(WithToken(token))
+}
+
+// SetToken assigns a token to the client. See `Client.SetToken`.
+func (c *CoreClient) SetToken(token string) {
+	c.transport().update(WithToken(token))
+}
+
+// SetToken assigns a token to the client. See `Client.SetToken`.
+func (c *PlusClient) SetToken(token string) {
+	c.transport().update(WithToken(token))
 }
@@ -0,0 +1,233 @@
+package ipinfo
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// clientConfig is an immutable snapshot of the configuration of a client.
+//
+// A snapshot must never be modified once it is stored in a transport; to
+// change the configuration, modify a copy and store that instead.
+type clientConfig struct {
+	// HTTP client used to communicate with the API.
+	client *http.Client
+
+	// HTTP client with `middleware` applied to its transport.
+	wrapped *http.Client
+
+	// Base URL for API requests, with a trailing slash.
+	baseURL *url.URL
+
+	// Base URL for requests which must be sent over IPv6, or nil to use
+	// `baseURL`.
+	baseURLIPv6 *url.URL
+
+	// User agent used when communicating with the IPinfo API.
+	userAgent string
+
+	// Cache implementation, or nil to not cache.
+	cache *Cache
+
+	// `cache` wrapped to report accesses to `observers`, or `cache` itself
+	// if there are none. Used for all cache accesses.
+	observedCache *Cache
+
+	// The API token used for authorization.
+	token string
+
+	// Provider of API tokens, used instead of `token` if set.
+	tokens TokenProvider
+
+	// Whether to retain the raw response body in `ResponseMeta`.
+	keepRawBody bool
+
+	// Whether to fail decoding of responses which don't match their type.
+	strictSchema bool
+
+	// Timeout for each API call, including retries; 0 means no timeout.
+	timeout time.Duration
+
+	// Retry policy for failed requests, or nil to not retry.
+	retry *RetryPolicy
+
+	// Limiter throttling outgoing requests, or nil for no throttling.
+	limiter Limiter
+
+	// Circuit breaker guarding outgoing requests, or nil for none.
+	breaker *CircuitBreaker
+
+	// Pool of endpoints which replaces `baseURL`, or nil to use `baseURL`.
+	endpoints *EndpointPool
+
+	// Networks whose lookups are answered locally, or nil for none.
+	local *LocalNetworks
+
+	// Name of the API product the client is for, e.g. "LITE"; empty for the
+	// legacy API. Used to look up product-specific environment variables.
+	product string
+
+	middleware    []Middleware
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	observers     observers
+}
+
+func newConfig(
+	httpClient *http.Client,
+	baseURL string,
+	product string,
+	cache *Cache,
+	token string,
+) *clientConfig {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	u, _ := url.Parse(baseURL)
+	cfg := &clientConfig{
+		client:    httpClient,
+		baseURL:   u,
+		userAgent: defaultUserAgent,
+		cache:     cache,
+		token:     token,
+		product:   product,
+	}
+	cfg.wrap()
+	return cfg
+}
+
+// `clone` returns a shallow copy of `c` which can be modified and stored as a
+// new snapshot. Slices are clipped so that appending to them in the copy
+// never writes to the backing arrays of `c`.
+func (c *clientConfig) clone() *clientConfig {
+	cc := *c
+	cc.middleware = c.middleware[:len(c.middleware):len(c.middleware)]
+	cc.requestHooks = c.requestHooks[:len(c.requestHooks):len(c.requestHooks)]
+	cc.responseHooks = c.responseHooks[:len(c.responseHooks):len(c.responseHooks)]
+	cc.observers = c.observers[:len(c.observers):len(c.observers)]
+	return &cc
+}
+
+// `apply` applies `opts` to `c` and then derives dependent fields.
+func (c *clientConfig) apply(opts []Option) error {
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+	c.wrap()
+	return nil
+}
+
+// `wrap` applies the middleware of `c` around the transport of its HTTP
+// client, and the observers of `c` around its cache.
+func (c *clientConfig) wrap() {
+	c.observedCache = c.cache
+	if c.cache != nil && len(c.observers) != 0 {
+		c.observedCache = &Cache{Interface: &observedCache{
+			Interface: c.cache.Interface,
+			observers: c.observers,
+		}}
+	}
+
+	if len(c.middleware) == 0 {
+		c.wrapped = c.client
+		return
+	}
+
+	rt := c.client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
+	}
+	wrapped := *c.client
+	wrapped.Transport = rt
+	c.wrapped = &wrapped
+}
+
+// config returns the current configuration snapshot of the client.
+//
+// If exported fields of the client were assigned since the snapshot was
+// taken, a new snapshot with their values is taken first, so that code
+// assigning them directly keeps working.
+func (t *transport) config() *clientConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.configLocked()
+}
+
+// `configLocked` is like `config` but must be called with `t.mu` held.
+func (t *transport) configLocked() *clientConfig {
+	f := t.fields
+	if f == nil ||
+		*f.baseURL == t.cfg.baseURL &&
+			*f.userAgent == t.cfg.userAgent &&
+			*f.cache == t.cfg.cache &&
+			*f.token == t.cfg.token {
+		return t.cfg
+	}
+
+	cfg := t.cfg.clone()
+	cfg.baseURL = *f.baseURL
+	cfg.userAgent = *f.userAgent
+	cfg.cache = *f.cache
+	cfg.token = *f.token
+	cfg.wrap()
+	t.cfg = cfg
+	return cfg
+}
+
+// `update` replaces the configuration of the client with a copy modified by
+// `opts`, and updates the exported fields of the client to match.
+func (t *transport) update(opts ...Option) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cfg := t.configLocked().clone()
+	if err := cfg.apply(opts); err != nil {
+		return err
+	}
+	t.cfg = cfg
+	t.setFields(cfg)
+	return nil
+}
+
+// `derive` returns a new transport whose configuration is a copy of that of
+// `t` modified by `opts`. The HTTP client, cache and hooks are shared.
+func (t *transport) derive(opts []Option) (*transport, error) {
+	cfg := t.config().clone()
+	if err := cfg.apply(opts); err != nil {
+		return nil, err
+	}
+	return newTransportFromConfig(cfg), nil
+}
+
+// Reconfigure atomically applies `opts` to the configuration of the client.
+// Calls already in flight complete with the configuration they started with.
+//
+// Reconfigure is safe to call concurrently with requests.
+func (c *Client) Reconfigure(opts ...Option) error {
+	return c.transport().update(opts...)
+}
+
+// Reconfigure atomically applies `opts` to the configuration of the client.
+// See `Client.Reconfigure`.
+func (c *LiteClient) Reconfigure(opts ...Option) error {
+	return c.transport().update(opts...)
+}
+
+// Reconfigure atomically applies `opts` to the configuration of the client.
+// See `Client.Reconfigure`.
+func (c *CoreClient) Reconfigure(opts ...Option) error {
+	return c.transport().update(opts...)
+}
+
+// Reconfigure atomically applies `opts` to the configuration of the client.
+// See `Client.Reconfigure`.
+func (c *PlusClient) Reconfigure(opts ...Option) error {
+	return c.transport().update(opts...)
+}
@@ -0,0 +1,234 @@
+package ipinfo_test
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestReconfigureConcurrent(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				client.SetToken("token" + string(rune('a'+i)))
+				client.SetCache(nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, req := range srv.Requests() {
+		if auth := req.Header.Get("Authorization"); auth != "" && !strings.HasPrefix(auth, "Bearer token") {
+			t.Errorf("Authorization = %q", auth)
+		}
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("new")
+
+	client := srv.LiteClient(ipinfo.WithToken("old"))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Fatal("err = nil for the wrong token")
+	}
+	if err := client.Reconfigure(ipinfo.WithToken("new")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failing option leaves the configuration unchanged.
+	if err := client.Reconfigure(ipinfo.WithToken("old"), ipinfo.WithBaseURL("relative")); err == nil {
+		t.Fatal("err = nil for an invalid base URL")
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); err != nil {
+		t.Errorf("configuration changed by a failed Reconfigure: %v", err)
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client(
+		ipinfo.WithToken("shared"),
+		ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())),
+	)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	override, err := client.WithOverrides(ipinfo.WithToken("single"), ipinfo.WithoutCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := override.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("server received %d requests, want the override to bypass the cache only", len(reqs))
+	}
+	if auth := reqs[1].Header.Get("Authorization"); auth != "Bearer single" {
+		t.Errorf("override: Authorization = %q, want %q", auth, "Bearer single")
+	}
+
+	// later changes of the client don't affect the override.
+	client.SetToken("changed")
+	if _, err := override.GetIPInfo(net.ParseIP("1.1.1.1")); err != nil {
+		t.Fatal(err)
+	}
+	reqs = srv.Requests()
+	if auth := reqs[len(reqs)-1].Header.Get("Authorization"); auth != "Bearer single" {
+		t.Errorf("override after SetToken: Authorization = %q, want %q", auth, "Bearer single")
+	}
+}
+
+func TestWithOverridesError(t *testing.T) {
+	client := ipinfo.NewCoreClient(nil, nil, "")
+	if _, err := client.WithOverrides(ipinfo.WithTimeout(-1)); err == nil {
+		t.Error("err = nil for an invalid option")
+	}
+}
+
+func TestExportedFields(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	// `lastAuth` returns the Authorization header of the last request.
+	lastAuth := func() string {
+		reqs := srv.Requests()
+		return reqs[len(reqs)-1].Header.Get("Authorization")
+	}
+
+	client := ipinfo.NewClient(srv.Server.Client(), nil, "first")
+	if client.Token != "first" || client.UserAgent == "" || client.BaseURL == nil {
+		t.Fatalf("fields = %q, %q, %v, want the configuration", client.Token, client.UserAgent, client.BaseURL)
+	}
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if auth := lastAuth(); auth != "Bearer first" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer first")
+	}
+
+	// assignments after the first use are honored too.
+	client.Token = "second"
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if auth := lastAuth(); auth != "Bearer second" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer second")
+	}
+
+	// reconfiguration writes the fields back.
+	client.SetToken("third")
+	if client.Token != "third" {
+		t.Errorf("Token = %q after SetToken, want %q", client.Token, "third")
+	}
+	if err := client.Reconfigure(ipinfo.WithUserAgent("agent/1.0")); err != nil {
+		t.Fatal(err)
+	}
+	if client.UserAgent != "agent/1.0" {
+		t.Errorf("UserAgent = %q after Reconfigure, want %q", client.UserAgent, "agent/1.0")
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	if req := reqs[len(reqs)-1]; req.Header.Get("Authorization") != "Bearer third" ||
+		req.Header.Get("User-Agent") != "agent/1.0" {
+		t.Errorf("headers = %v, want the reconfigured token and user agent", req.Header)
+	}
+}
+
+func TestClientLiteral(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{CountryCode: "US"})
+	u, _ := url.Parse(srv.URL + "/")
+
+	client := &ipinfo.Client{BaseURL: u, Token: "literal"}
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q, want %q", info.City, "Mountain View")
+	}
+	reqs := srv.Requests()
+	if auth := reqs[len(reqs)-1].Header.Get("Authorization"); auth != "Bearer literal" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer literal")
+	}
+	if client.UserAgent == "" {
+		t.Error("UserAgent is empty, want the default")
+	}
+
+	liteURL, _ := url.Parse(srv.URL + "/lite/")
+	lite := &ipinfo.LiteClient{BaseURL: liteURL}
+	l, err := lite.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.CountryCode != "US" {
+		t.Errorf("CountryCode = %q, want %q", l.CountryCode, "US")
+	}
+}
+
+func TestClientZeroValue(t *testing.T) {
+	var client ipinfo.Client
+	client.SetToken("zero")
+	if client.Token != "zero" {
+		t.Errorf("Token = %q, want %q", client.Token, "zero")
+	}
+	if client.BaseURL == nil || client.BaseURL.String() != "https://ipinfo.io/" {
+		t.Errorf("BaseURL = %v, want the default", client.BaseURL)
+	}
+
+	// bogons are answered without a request.
+	info, err := client.GetIPInfo(net.ParseIP("127.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Bogon {
+		t.Error("Bogon = false for 127.0.0.1")
+	}
+
+	var lite ipinfo.LiteClient
+	if _, err := lite.GetIPInfo(net.ParseIP("10.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	if lite.BaseURL == nil || lite.BaseURL.String() != "https://api.ipinfo.io/lite/" {
+		t.Errorf("BaseURL = %v, want the Lite default", lite.BaseURL)
+	}
+}
@@ -1,16 +1,21 @@
 package ipinfo
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
 	"net/netip"
+	"reflect"
+	"time"
 )
 
 // Core represents data from the Core API.
 type Core struct {
-	IP              net.IP          `json:"ip" csv:"ip"`
+	IP              net.IP          `json:"ip" csv:"ip" yaml:"ip,omitempty" schema:"required"`
 	Hostname        string          `json:"hostname,omitempty" csv:"hostname" yaml:"hostname,omitempty"`
 	Bogon           bool            `json:"bogon,omitempty" csv:"bogon" yaml:"bogon,omitempty"`
+	BogonInfo       *BogonInfo      `json:"bogon_info,omitempty" csv:"bogon_,inline" yaml:"bogonInfo,omitempty"`
+	Local           *LocalNetwork   `json:"local,omitempty" csv:"local_,inline" yaml:"local,omitempty"`
 	Anycast         bool            `json:"anycast,omitempty" csv:"anycast" yaml:"anycast,omitempty"`
 	City            string          `json:"city,omitempty" csv:"city" yaml:"city,omitempty"`
 	Region          string          `json:"region,omitempty" csv:"region" yaml:"region,omitempty"`
@@ -31,57 +36,86 @@ type Core struct {
 	Privacy         *CorePrivacy    `json:"privacy,omitempty" csv:"privacy_,inline" yaml:"privacy,omitempty"`
 	Abuse           *CoreAbuse      `json:"abuse,omitempty" csv:"abuse_,inline" yaml:"abuse,omitempty"`
 	Domains         *CoreDomains    `json:"domains,omitempty" csv:"domains_,inline" yaml:"domains,omitempty"`
+
+	// Extra holds response fields unknown to this version of the library.
+	Extra map[string]json.RawMessage `json:"-" csv:"-" yaml:"-"`
 }
 
 // CoreASN represents ASN data for the Core API.
 type CoreASN struct {
-	ASN    string `json:"asn" csv:"id"`
-	Name   string `json:"name" csv:"asn"`
-	Domain string `json:"domain" csv:"domain"`
-	Route  string `json:"route" csv:"route"`
-	Type   string `json:"type" csv:"type"`
+	ASN    string `json:"asn" csv:"id" yaml:"asn,omitempty" schema:"required"`
+	Name   string `json:"name" csv:"asn" yaml:"name,omitempty" schema:"required"`
+	Domain string `json:"domain" csv:"domain" yaml:"domain,omitempty" schema:"required"`
+	Route  string `json:"route" csv:"route" yaml:"route,omitempty" schema:"required"`
+	Type   string `json:"type" csv:"type" yaml:"type,omitempty" schema:"required"`
 }
 
 // CoreCompany represents company data for the Core API.
 type CoreCompany struct {
-	Name   string `json:"name" csv:"name"`
-	Domain string `json:"domain" csv:"domain"`
-	Type   string `json:"type" csv:"type"`
+	Name   string `json:"name" csv:"name" yaml:"name,omitempty"`
+	Domain string `json:"domain" csv:"domain" yaml:"domain,omitempty"`
+	Type   string `json:"type" csv:"type" yaml:"type,omitempty"`
 }
 
 // CoreCarrier represents carrier data for the Core API.
 type CoreCarrier struct {
-	Name string `json:"name" csv:"name"`
-	MCC  string `json:"mcc" csv:"mcc"`
-	MNC  string `json:"mnc" csv:"mnc"`
+	Name string `json:"name" csv:"name" yaml:"name,omitempty"`
+	MCC  string `json:"mcc" csv:"mcc" yaml:"mcc,omitempty"`
+	MNC  string `json:"mnc" csv:"mnc" yaml:"mnc,omitempty"`
 }
 
 // CorePrivacy represents privacy data for the Core API.
 type CorePrivacy struct {
-	VPN     bool   `json:"vpn" csv:"vpn"`
-	Proxy   bool   `json:"proxy" csv:"proxy"`
-	Tor     bool   `json:"tor" csv:"tor"`
-	Relay   bool   `json:"relay" csv:"relay"`
-	Hosting bool   `json:"hosting" csv:"hosting"`
-	Service string `json:"service" csv:"service"`
+	VPN     bool   `json:"vpn" csv:"vpn" yaml:"vpn,omitempty"`
+	Proxy   bool   `json:"proxy" csv:"proxy" yaml:"proxy,omitempty"`
+	Tor     bool   `json:"tor" csv:"tor" yaml:"tor,omitempty"`
+	Relay   bool   `json:"relay" csv:"relay" yaml:"relay,omitempty"`
+	Hosting bool   `json:"hosting" csv:"hosting" yaml:"hosting,omitempty"`
+	Service string `json:"service" csv:"service" yaml:"service,omitempty"`
 }
 
 // CoreAbuse represents abuse data for the Core API.
 type CoreAbuse struct {
-	Address     string `json:"address" csv:"address"`
-	Country     string `json:"country" csv:"country"`
-	CountryName string `json:"country_name" csv:"country_name"`
-	Email       string `json:"email" csv:"email"`
-	Name        string `json:"name" csv:"name"`
-	Network     string `json:"network" csv:"network"`
-	Phone       string `json:"phone" csv:"phone"`
+	Address     string `json:"address" csv:"address" yaml:"address,omitempty"`
+	Country     string `json:"country" csv:"country" yaml:"country,omitempty"`
+	CountryName string `json:"country_name" csv:"country_name" yaml:"countryName,omitempty"`
+	Email       string `json:"email" csv:"email" yaml:"email,omitempty"`
+	Name        string `json:"name" csv:"name" yaml:"name,omitempty"`
+	Network     string `json:"network" csv:"network" yaml:"network,omitempty"`
+	Phone       string `json:"phone" csv:"phone" yaml:"phone,omitempty"`
 }
 
 // CoreDomains represents domains data for the Core API.
 type CoreDomains struct {
-	IP      string   `json:"ip" csv:"-"`
-	Total   uint64   `json:"total" csv:"total"`
-	Domains []string `json:"domains" csv:"-"`
+	IP      string   `json:"ip" csv:"-" yaml:"ip,omitempty"`
+	Total   uint64   `json:"total" csv:"total" yaml:"total,omitempty"`
+	Domains []string `json:"domains" csv:"-" yaml:"domains,omitempty"`
+}
+
+// UnmarshalJSON decodes `data` into `v`, retaining unknown fields in
+// `v.Extra`.
+func (v *Core) UnmarshalJSON(data []byte) error {
+	type core Core
+	if err := json.Unmarshal(data, (*core)(v)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, reflect.TypeOf(*v))
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes `v`, including any fields retained in `v.Extra`.
+func (v Core) MarshalJSON() ([]byte, error) {
+	type core Core
+	data, err := json.Marshal(core(v))
+	if err != nil {
+		return nil, err
+	}
+	return marshalWithExtra(data, v.Extra)
 }
 
 func (v *Core) setCountryName() {
@@ -113,65 +147,229 @@ func GetIPInfoV6(ip net.IP) (*Core, error) {
 	return DefaultClient.GetIPInfoV6(ip)
 }
 
+// GetIPInfoWithMeta returns the details for the specified IP along with
+// metadata about the API response.
+func GetIPInfoWithMeta(ip net.IP) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPInfoWithMeta(ip)
+}
+
+// GetIPInfoV6WithMeta returns the details for the specified IPv6 IP along with
+// metadata about the API response.
+func GetIPInfoV6WithMeta(ip net.IP) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPInfoV6WithMeta(ip)
+}
+
 // GetIPInfo returns the details for the specified IP.
 func (c *Client) GetIPInfo(ip net.IP) (*Core, error) {
-	return c.getIPInfoBase(ip, false)
+	v, _, err := c.getIPInfoBase(ip, false)
+	return v, err
 }
 
 // GetIPInfoV6 returns the details for the specified IPv6 IP.
 func (c *Client) GetIPInfoV6(ip net.IP) (*Core, error) {
+	v, _, err := c.getIPInfoBase(ip, true)
+	return v, err
+}
+
+// GetIPInfoWithMeta returns the details for the specified IP along with
+// metadata about the API response.
+func (c *Client) GetIPInfoWithMeta(ip net.IP) (*Core, *ResponseMeta, error) {
+	return c.getIPInfoBase(ip, false)
+}
+
+// GetIPInfoV6WithMeta returns the details for the specified IPv6 IP along with
+// metadata about the API response.
+func (c *Client) GetIPInfoV6WithMeta(ip net.IP) (*Core, *ResponseMeta, error) {
 	return c.getIPInfoBase(ip, true)
 }
 
-func (c *Client) getIPInfoBase(ip net.IP, ipv6 bool) (*Core, error) {
+func (c *Client) getIPInfoBase(
+	ip net.IP,
+	ipv6 bool,
+) (*Core, *ResponseMeta, error) {
+	start := time.Now()
+	cfg := c.transport().config()
+	cache := cfg.observedCache
 	relURL := ""
-	if ip != nil && isBogon(netip.MustParseAddr(ip.String())) {
+	addr, err := validIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := cfg.local.Lookup(addr); ok {
+		return n.core(ip), &ResponseMeta{Duration: time.Since(start)}, nil
+	}
+	if info := ClassifyBogon(addr); info != nil {
 		bogonResponse := new(Core)
 		bogonResponse.Bogon = true
+		bogonResponse.BogonInfo = info
 		bogonResponse.IP = ip
-		return bogonResponse, nil
+		return bogonResponse, &ResponseMeta{Duration: time.Since(start)}, nil
 	}
 	if ip != nil {
 		relURL = ip.String()
 	}
 
 	// perform cache lookup.
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey(relURL)); err == nil {
-			return res.(*Core), nil
+	if cache != nil {
+		if res, err := cache.Get(cacheKey(relURL)); err == nil {
+			return res.(*Core), cachedMeta(start), nil
 		}
 	}
 
 	// prepare req
-	var err error
 	var req *http.Request
 	if ipv6 {
-		req, err = c.newRequestV6(nil, "GET", relURL, nil)
+		req, err = c.transport().newRequestV6(nil, "GET", relURL, nil)
 	} else {
-		req, err = c.newRequest(nil, "GET", relURL, nil)
+		req, err = c.transport().newRequest(nil, "GET", relURL, nil)
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// do req
 	v := new(Core)
-	if _, err := c.do(req, v); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, v)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	// format
 	v.setCountryName()
 
 	// cache req result
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey(relURL), v); err != nil {
+	if cache != nil {
+		if err := cache.Set(cacheKey(relURL), v); err != nil {
 			// NOTE: still return the value even if the cache fails.
-			return v, err
+			return v, meta, err
 		}
 	}
 
-	return v, nil
+	return v, meta, nil
+}
+
+/* CORE (netip.Addr) */
+
+// GetIPAddrInfo returns the details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func GetIPAddrInfo(addr netip.Addr) (*Core, error) {
+	return DefaultClient.GetIPAddrInfo(addr)
+}
+
+// GetIPAddrInfoWithMeta is like `GetIPAddrInfo` but also returns metadata
+// about the API response.
+func GetIPAddrInfoWithMeta(addr netip.Addr) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPAddrInfoWithMeta(addr)
+}
+
+// GetIPAddrInfo returns the details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func (c *Client) GetIPAddrInfo(addr netip.Addr) (*Core, error) {
+	v, _, err := c.GetIPAddrInfoWithMeta(addr)
+	return v, err
+}
+
+// GetIPAddrInfoWithMeta is like `GetIPAddrInfo` but also returns metadata
+// about the API response.
+func (c *Client) GetIPAddrInfoWithMeta(addr netip.Addr) (*Core, *ResponseMeta, error) {
+	ip, err := addrIP(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.getIPInfoBase(ip, false)
+}
+
+/* CORE (string) */
+
+// GetIPStrInfo returns the details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func GetIPStrInfo(ip string) (*Core, error) {
+	return DefaultClient.GetIPStrInfo(ip)
+}
+
+// GetIPStrInfoWithMeta is like `GetIPStrInfo` but also returns metadata
+// about the API response.
+func GetIPStrInfoWithMeta(ip string) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPStrInfoWithMeta(ip)
+}
+
+// GetIPStrInfo returns the details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func (c *Client) GetIPStrInfo(ip string) (*Core, error) {
+	v, _, err := c.GetIPStrInfoWithMeta(ip)
+	return v, err
+}
+
+// GetIPStrInfoWithMeta is like `GetIPStrInfo` but also returns metadata
+// about the API response.
+func (c *Client) GetIPStrInfoWithMeta(ip string) (*Core, *ResponseMeta, error) {
+	parsed, err := parseIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.getIPInfoBase(parsed, false)
+}
+
+/* CORE V6 (netip.Addr) */
+
+// GetIPAddrInfoV6 returns the details for the specified IPv6 IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func GetIPAddrInfoV6(addr netip.Addr) (*Core, error) {
+	return DefaultClient.GetIPAddrInfoV6(addr)
+}
+
+// GetIPAddrInfoV6WithMeta is like `GetIPAddrInfoV6` but also returns
+// metadata about the API response.
+func GetIPAddrInfoV6WithMeta(addr netip.Addr) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPAddrInfoV6WithMeta(addr)
+}
+
+// GetIPAddrInfoV6 returns the details for the specified IPv6 IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func (c *Client) GetIPAddrInfoV6(addr netip.Addr) (*Core, error) {
+	v, _, err := c.GetIPAddrInfoV6WithMeta(addr)
+	return v, err
+}
+
+// GetIPAddrInfoV6WithMeta is like `GetIPAddrInfoV6` but also returns
+// metadata about the API response.
+func (c *Client) GetIPAddrInfoV6WithMeta(addr netip.Addr) (*Core, *ResponseMeta, error) {
+	ip, err := addrIP(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.getIPInfoBase(ip, true)
+}
+
+/* CORE V6 (string) */
+
+// GetIPStrInfoV6 returns the details for the specified IPv6 IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func GetIPStrInfoV6(ip string) (*Core, error) {
+	return DefaultClient.GetIPStrInfoV6(ip)
+}
+
+// GetIPStrInfoV6WithMeta is like `GetIPStrInfoV6` but also returns metadata
+// about the API response.
+func GetIPStrInfoV6WithMeta(ip string) (*Core, *ResponseMeta, error) {
+	return DefaultClient.GetIPStrInfoV6WithMeta(ip)
+}
+
+// GetIPStrInfoV6 returns the details for the specified IPv6 IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func (c *Client) GetIPStrInfoV6(ip string) (*Core, error) {
+	v, _, err := c.GetIPStrInfoV6WithMeta(ip)
+	return v, err
+}
+
+// GetIPStrInfoV6WithMeta is like `GetIPStrInfoV6` but also returns metadata
+// about the API response.
+func (c *Client) GetIPStrInfoV6WithMeta(ip string) (*Core, *ResponseMeta, error) {
+	parsed, err := parseIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.getIPInfoBase(parsed, true)
 }
 
 /* IP ADDRESS */
@@ -1,14 +1,14 @@
 package ipinfo
 
 import (
-	"context"
 	"encoding/json"
-	"io"
 	"net"
 	"net/http"
 	"net/netip"
 	"net/url"
-	"strings"
+	"reflect"
+	"sync"
+	"time"
 )
 
 const (
@@ -16,10 +16,9 @@ const (
 )
 
 // CoreClient is a client for the IPinfo Core API.
+//
+// The exported fields reflect the configuration of the client. See `Client`.
 type CoreClient struct {
-	// HTTP client used to communicate with the API.
-	client *http.Client
-
 	// Base URL for API requests.
 	BaseURL *url.URL
 
@@ -31,50 +30,86 @@ type CoreClient struct {
 
 	// The API token used for authorization.
 	Token string
+
+	// Transport of the client, created on first use if the client wasn't
+	// created by a constructor.
+	t    *transport
+	once sync.Once
 }
 
 // CoreResponse represents the response from the IPinfo Core API /lookup endpoint.
 type CoreResponse struct {
-	IP          net.IP   `json:"ip"`
-	Bogon       bool     `json:"bogon,omitempty"`
-	Geo         *CoreGeo `json:"geo,omitempty"`
-	AS          *CoreAS  `json:"as,omitempty"`
-	IsAnonymous bool     `json:"is_anonymous"`
-	IsAnycast   bool     `json:"is_anycast"`
-	IsHosting   bool     `json:"is_hosting"`
-	IsMobile    bool     `json:"is_mobile"`
-	IsSatellite bool     `json:"is_satellite"`
+	IP          net.IP        `json:"ip" csv:"ip" yaml:"ip,omitempty" schema:"required"`
+	Bogon       bool          `json:"bogon,omitempty" csv:"bogon" yaml:"bogon,omitempty"`
+	BogonInfo   *BogonInfo    `json:"bogon_info,omitempty" csv:"bogon_,inline" yaml:"bogonInfo,omitempty"`
+	Local       *LocalNetwork `json:"local,omitempty" csv:"local_,inline" yaml:"local,omitempty"`
+	Geo         *CoreGeo      `json:"geo,omitempty" csv:"geo_,inline" yaml:"geo,omitempty"`
+	AS          *CoreAS       `json:"as,omitempty" csv:"as_,inline" yaml:"as,omitempty"`
+	IsAnonymous bool          `json:"is_anonymous" csv:"is_anonymous" yaml:"isAnonymous,omitempty" schema:"required"`
+	IsAnycast   bool          `json:"is_anycast" csv:"is_anycast" yaml:"isAnycast,omitempty" schema:"required"`
+	IsHosting   bool          `json:"is_hosting" csv:"is_hosting" yaml:"isHosting,omitempty" schema:"required"`
+	IsMobile    bool          `json:"is_mobile" csv:"is_mobile" yaml:"isMobile,omitempty" schema:"required"`
+	IsSatellite bool          `json:"is_satellite" csv:"is_satellite" yaml:"isSatellite,omitempty" schema:"required"`
+
+	// Extra holds response fields unknown to this version of the library.
+	Extra map[string]json.RawMessage `json:"-" csv:"-" yaml:"-"`
 }
 
 // CoreGeo represents the geo object in Core API response.
 type CoreGeo struct {
-	City          string  `json:"city,omitempty"`
-	Region        string  `json:"region,omitempty"`
-	RegionCode    string  `json:"region_code,omitempty"`
-	Country       string  `json:"country,omitempty"`
-	CountryCode   string  `json:"country_code,omitempty"`
-	Continent     string  `json:"continent,omitempty"`
-	ContinentCode string  `json:"continent_code,omitempty"`
-	Latitude      float64 `json:"latitude"`
-	Longitude     float64 `json:"longitude"`
-	Timezone      string  `json:"timezone,omitempty"`
-	PostalCode    string  `json:"postal_code,omitempty"`
+	City          string  `json:"city,omitempty" csv:"city" yaml:"city,omitempty"`
+	Region        string  `json:"region,omitempty" csv:"region" yaml:"region,omitempty"`
+	RegionCode    string  `json:"region_code,omitempty" csv:"region_code" yaml:"regionCode,omitempty"`
+	Country       string  `json:"country,omitempty" csv:"country" yaml:"country,omitempty"`
+	CountryCode   string  `json:"country_code,omitempty" csv:"country_code" yaml:"countryCode,omitempty"`
+	Continent     string  `json:"continent,omitempty" csv:"continent" yaml:"continent,omitempty"`
+	ContinentCode string  `json:"continent_code,omitempty" csv:"continent_code" yaml:"continentCode,omitempty"`
+	Latitude      float64 `json:"latitude" csv:"latitude" yaml:"latitude,omitempty"`
+	Longitude     float64 `json:"longitude" csv:"longitude" yaml:"longitude,omitempty"`
+	Timezone      string  `json:"timezone,omitempty" csv:"timezone" yaml:"timezone,omitempty"`
+	PostalCode    string  `json:"postal_code,omitempty" csv:"postal_code" yaml:"postalCode,omitempty"`
 
 	// Extended fields using the same country data as legacy Core API
-	CountryName     string          `json:"-"`
-	IsEU            bool            `json:"-"`
-	CountryFlag     CountryFlag     `json:"-"`
-	CountryFlagURL  string          `json:"-"`
-	CountryCurrency CountryCurrency `json:"-"`
-	ContinentInfo   Continent       `json:"-"`
+	CountryName     string          `json:"-" csv:"country_name" yaml:"countryName,omitempty"`
+	IsEU            bool            `json:"-" csv:"isEU" yaml:"isEU,omitempty"`
+	CountryFlag     CountryFlag     `json:"-" csv:"country_flag_,inline" yaml:"countryFlag,omitempty"`
+	CountryFlagURL  string          `json:"-" csv:"country_flag_url" yaml:"countryFlagURL,omitempty"`
+	CountryCurrency CountryCurrency `json:"-" csv:"country_currency_,inline" yaml:"countryCurrency,omitempty"`
+	ContinentInfo   Continent       `json:"-" csv:"continent_info_,inline" yaml:"continentInfo,omitempty"`
 }
 
 // CoreAS represents the AS object in Core API response.
 type CoreAS struct {
-	ASN    string `json:"asn"`
-	Name   string `json:"name"`
-	Domain string `json:"domain"`
-	Type   string `json:"type"`
+	ASN    string `json:"asn" csv:"asn" yaml:"asn,omitempty" schema:"required"`
+	Name   string `json:"name" csv:"name" yaml:"name,omitempty" schema:"required"`
+	Domain string `json:"domain" csv:"domain" yaml:"domain,omitempty" schema:"required"`
+	Type   string `json:"type" csv:"type" yaml:"type,omitempty" schema:"required"`
+}
+
+// UnmarshalJSON decodes `data` into `v`, retaining unknown fields in
+// `v.Extra`.
+func (v *CoreResponse) UnmarshalJSON(data []byte) error {
+	type coreResponse CoreResponse
+	if err := json.Unmarshal(data, (*coreResponse)(v)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, reflect.TypeOf(*v))
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes `v`, including any fields retained in `v.Extra`.
+func (v CoreResponse) MarshalJSON() ([]byte, error) {
+	type coreResponse CoreResponse
+	data, err := json.Marshal(coreResponse(v))
+	if err != nil {
+		return nil, err
+	}
+	return marshalWithExtra(data, v.Extra)
 }
 
 func (v *CoreResponse) enrichGeo() {
@@ -93,132 +128,154 @@ func (v *CoreResponse) enrichGeo() {
 
 // NewCoreClient creates a new IPinfo Core API client.
 func NewCoreClient(httpClient *http.Client, cache *Cache, token string) *CoreClient {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
+	return newCoreClient(newTransport(httpClient, defaultCoreBaseURL, "CORE", cache, token))
+}
 
-	baseURL, _ := url.Parse(defaultCoreBaseURL)
-	return &CoreClient{
-		client:    httpClient,
-		BaseURL:   baseURL,
-		UserAgent: defaultUserAgent,
-		Cache:     cache,
-		Token:     token,
-	}
+// `transport` returns the transport of `c`, creating one with the defaults
+// of `NewCoreClient` if `c` wasn't created by a constructor.
+func (c *CoreClient) transport() *transport {
+	c.once.Do(func() {
+		if c.t == nil {
+			c.t = newTransport(nil, defaultCoreBaseURL, "CORE", nil, "")
+		}
+		c.t.bind(&clientFields{&c.BaseURL, &c.UserAgent, &c.Cache, &c.Token})
+	})
+	return c.t
+}
+
+// `newCoreClient` returns a CoreClient using `t`, with its exported fields set
+// from the configuration of `t`.
+func newCoreClient(t *transport) *CoreClient {
+	c := &CoreClient{t: t}
+	c.transport()
+	return c
 }
 
 // GetIPInfo returns the Core details for the specified IP.
 func (c *CoreClient) GetIPInfo(ip net.IP) (*CoreResponse, error) {
-	if ip != nil && isBogon(netip.MustParseAddr(ip.String())) {
+	v, _, err := c.GetIPInfoWithMeta(ip)
+	return v, err
+}
+
+// GetIPInfoWithMeta returns the Core details for the specified IP
+// along with metadata about the API response.
+func (c *CoreClient) GetIPInfoWithMeta(ip net.IP) (*CoreResponse, *ResponseMeta, error) {
+	start := time.Now()
+	cfg := c.transport().config()
+	cache := cfg.observedCache
+	addr, err := validIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := cfg.local.Lookup(addr); ok {
+		return n.coreResponse(ip), &ResponseMeta{Duration: time.Since(start)}, nil
+	}
+	if info := ClassifyBogon(addr); info != nil {
 		bogonResponse := new(CoreResponse)
 		bogonResponse.Bogon = true
+		bogonResponse.BogonInfo = info
 		bogonResponse.IP = ip
-		return bogonResponse, nil
+		return bogonResponse, &ResponseMeta{Duration: time.Since(start)}, nil
 	}
 	relUrl := ""
 	if ip != nil {
 		relUrl = ip.String()
 	}
 
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey(relUrl)); err == nil {
-			return res.(*CoreResponse), nil
+	key := cacheKey("core:" + relUrl)
+	if cache != nil {
+		if res, err := cache.Get(key); err == nil {
+			return res.(*CoreResponse), cachedMeta(start), nil
 		}
 	}
 
-	req, err := c.newRequest(nil, "GET", relUrl, nil)
+	req, err := c.transport().newRequest(nil, "GET", relUrl, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	res := new(CoreResponse)
-	if _, err := c.do(req, res); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, res)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	res.enrichGeo()
 
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey(relUrl), res); err != nil {
-			return res, err
+	if cache != nil {
+		if err := cache.Set(key, res); err != nil {
+			return res, meta, err
 		}
 	}
 
-	return res, nil
+	return res, meta, nil
 }
 
-func (c *CoreClient) newRequest(ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-) (*http.Request, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	u := new(url.URL)
-	baseURL := c.BaseURL
-	if rel, err := url.Parse(urlStr); err == nil {
-		u = baseURL.ResolveReference(rel)
-	} else if strings.ContainsRune(urlStr, ':') {
-		// IPv6 strings fail to parse as URLs, so let's add it as a URL Path.
-		*u = *baseURL
-		u.Path += urlStr
-	} else {
-		return nil, err
-	}
+// GetIPAddrInfo returns the Core details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func (c *CoreClient) GetIPAddrInfo(addr netip.Addr) (*CoreResponse, error) {
+	v, _, err := c.GetIPAddrInfoWithMeta(addr)
+	return v, err
+}
 
-	// get `http` package request object.
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+// GetIPAddrInfoWithMeta is like `GetIPAddrInfo` but also returns metadata
+// about the API response.
+func (c *CoreClient) GetIPAddrInfoWithMeta(addr netip.Addr) (*CoreResponse, *ResponseMeta, error) {
+	ip, err := addrIP(addr)
 	if err != nil {
-		return nil, err
-	}
-
-	// set common headers.
-	req.Header.Set("Accept", "application/json")
-	if c.UserAgent != "" {
-		req.Header.Set("User-Agent", c.UserAgent)
-	}
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+		return nil, nil, err
 	}
-
-	return req, nil
+	return c.GetIPInfoWithMeta(ip)
 }
 
-func (c *CoreClient) do(
-	req *http.Request,
-	v interface{},
-) (*http.Response, error) {
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// GetIPStrInfo returns the Core details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func (c *CoreClient) GetIPStrInfo(ip string) (*CoreResponse, error) {
+	v, _, err := c.GetIPStrInfoWithMeta(ip)
+	return v, err
+}
 
-	err = checkResponse(resp)
+// GetIPStrInfoWithMeta is like `GetIPStrInfo` but also returns metadata
+// about the API response.
+func (c *CoreClient) GetIPStrInfoWithMeta(ip string) (*CoreResponse, *ResponseMeta, error) {
+	parsed, err := parseIP(ip)
 	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
+		return nil, nil, err
 	}
-
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-			if err == io.EOF {
-				// ignore EOF errors caused by empty response body
-				err = nil
-			}
-		}
-	}
-
-	return resp, err
+	return c.GetIPInfoWithMeta(parsed)
 }
 
 // GetIPInfoCore returns the Core details for the specified IP.
 func GetIPInfoCore(ip net.IP) (*CoreResponse, error) {
 	return DefaultCoreClient.GetIPInfo(ip)
 }
+
+// GetIPInfoCoreWithMeta returns the Core details for the specified IP
+// along with metadata about the API response.
+func GetIPInfoCoreWithMeta(ip net.IP) (*CoreResponse, *ResponseMeta, error) {
+	return DefaultCoreClient.GetIPInfoWithMeta(ip)
+}
+
+// GetIPAddrInfoCore returns the Core details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func GetIPAddrInfoCore(addr netip.Addr) (*CoreResponse, error) {
+	return DefaultCoreClient.GetIPAddrInfo(addr)
+}
+
+// GetIPAddrInfoCoreWithMeta is like `GetIPAddrInfoCore` but also returns
+// metadata about the API response.
+func GetIPAddrInfoCoreWithMeta(addr netip.Addr) (*CoreResponse, *ResponseMeta, error) {
+	return DefaultCoreClient.GetIPAddrInfoWithMeta(addr)
+}
+
+// GetIPStrInfoCore returns the Core details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func GetIPStrInfoCore(ip string) (*CoreResponse, error) {
+	return DefaultCoreClient.GetIPStrInfo(ip)
+}
+
+// GetIPStrInfoCoreWithMeta is like `GetIPStrInfoCore` but also returns
+// metadata about the API response.
+func GetIPStrInfoCoreWithMeta(ip string) (*CoreResponse, *ResponseMeta, error) {
+	return DefaultCoreClient.GetIPStrInfoWithMeta(ip)
+}
@@ -64,18 +64,18 @@ func IsEU(country string) bool {
 }
 
 type CountryFlag struct {
-	Emoji   string `json:"emoji,omitempty" csv:"emoji"`
-	Unicode string `json:"unicode,omitempty" csv:"unicode"`
+	Emoji   string `json:"emoji,omitempty" csv:"emoji" yaml:"emoji,omitempty"`
+	Unicode string `json:"unicode,omitempty" csv:"unicode" yaml:"unicode,omitempty"`
 }
 
 type CountryCurrency struct {
-	Code   string `json:"code,omitempty" csv:"code"`
-	Symbol string `json:"symbol,omitempty" csv:"symbol"`
+	Code   string `json:"code,omitempty" csv:"code" yaml:"code,omitempty"`
+	Symbol string `json:"symbol,omitempty" csv:"symbol" yaml:"symbol,omitempty"`
 }
 
 type Continent struct {
-	Code string `json:"code,omitempty" csv:"code"`
-	Name string `json:"name,omitempty" csv:"name"`
+	Code string `json:"code,omitempty" csv:"code" yaml:"code,omitempty"`
+	Name string `json:"name,omitempty" csv:"name" yaml:"name,omitempty"`
 }
 
 var countriesMap = map[string]string{
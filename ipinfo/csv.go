@@ -0,0 +1,240 @@
+package ipinfo
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSVWriter writes results as CSV, one row per result, with a header row
+// naming the columns before the first result.
+//
+// Columns are derived from the `csv` tags of the fields of the result type:
+// `csv:"name"` makes a column, `csv:"prefix_,inline"` flattens the fields of
+// a nested struct into columns whose names start with the prefix, and fields
+// tagged `csv:"-"` or without a tag are left out. The columns are the same
+// for all results of a type, regardless of which fields are set, so all
+// results written must be of the same type.
+type CSVWriter struct {
+	w      *csv.Writer
+	typ    reflect.Type
+	fields []csvField
+}
+
+// csvField is a column of a struct type.
+type csvField struct {
+	name string
+
+	// Indices of the field in the nested structs leading to it, as for
+	// `reflect.Value.FieldByIndex`.
+	index []int
+}
+
+var csvFieldsCache sync.Map // map[reflect.Type][]csvField
+
+// NewCSVWriter returns a CSV writer writing to `w`.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes the result `v`, a struct or pointer to struct such as `*Core`
+// or `*Lite`, as a row. The header row is written before the first row.
+func (w *CSVWriter) Write(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("ipinfo: cannot write %T as CSV", v)
+	}
+
+	if w.typ == nil {
+		fields, err := csvFields(rv.Type())
+		if err != nil {
+			return err
+		}
+		header := make([]string, len(fields))
+		for i, f := range fields {
+			header[i] = f.name
+		}
+		if err := w.w.Write(header); err != nil {
+			return err
+		}
+		w.typ = rv.Type()
+		w.fields = fields
+	} else if rv.Type() != w.typ {
+		return fmt.Errorf(
+			"ipinfo: cannot write %s as CSV after %s",
+			rv.Type(), w.typ,
+		)
+	}
+
+	row := make([]string, len(w.fields))
+	for i, f := range w.fields {
+		row[i] = csvValue(rv, f.index)
+	}
+	return w.w.Write(row)
+}
+
+// WriteBatch writes the results of `batch` as rows, sorted by key. Nil
+// results are skipped.
+func (w *CSVWriter) WriteBatch(batch BatchCore) error {
+	keys := make([]string, 0, len(batch))
+	for k, v := range batch {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.Write(batch[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer and reports any
+// error that occurred during a previous write or the flush.
+func (w *CSVWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// `csvFields` returns the columns of the struct type `t`.
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if v, ok := csvFieldsCache.Load(t); ok {
+		return v.([]csvField), nil
+	}
+
+	var fields []csvField
+	if err := appendCSVFields(&fields, t, "", nil); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("ipinfo: csv: %s has no csv tagged fields", t)
+	}
+
+	csvFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+func appendCSVFields(
+	fields *[]csvField,
+	t reflect.Type,
+	prefix string,
+	index []int,
+) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("csv")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(index[:len(index):len(index)], i)
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if opts == "inline" {
+			if ft.Kind() != reflect.Struct {
+				return fmt.Errorf(
+					"ipinfo: csv: inline field %s.%s is not a struct",
+					t.Name(), sf.Name,
+				)
+			}
+			if err := appendCSVFields(fields, ft, prefix+name, fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isCSVScalar(sf.Type) {
+			return fmt.Errorf(
+				"ipinfo: csv: field %s.%s of type %s has no CSV representation",
+				t.Name(), sf.Name, sf.Type,
+			)
+		}
+		*fields = append(*fields, csvField{name: prefix + name, index: fieldIndex})
+	}
+	return nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// `isCSVScalar` reports whether values of `t` can be written as a single CSV
+// value.
+func isCSVScalar(t reflect.Type) bool {
+	if t.Implements(textMarshalerType) {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// `csvValue` formats the field of `v` at `index`, which is empty when a
+// pointer leading to it is nil.
+func csvValue(v reflect.Value, index []int) string {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return ""
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return ""
+}
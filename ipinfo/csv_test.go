@@ -0,0 +1,168 @@
+package ipinfo_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+// `writeCSV` writes `values` with a `CSVWriter` and returns the records
+// written.
+func writeCSV(t *testing.T, values ...interface{}) [][]string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := ipinfo.NewCSVWriter(&buf)
+	for _, v := range values {
+		if err := w.Write(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// `column` returns the value of the column `name` in row `i` of `records`.
+func column(t *testing.T, records [][]string, i int, name string) string {
+	t.Helper()
+	for j, h := range records[0] {
+		if h == name {
+			return records[i][j]
+		}
+	}
+	t.Fatalf("no column %q in %q", name, records[0])
+	return ""
+}
+
+func TestCSVWriterCore(t *testing.T) {
+	records := writeCSV(t, &ipinfo.Core{
+		IP:          net.ParseIP("8.8.8.8"),
+		City:        "Mountain View",
+		CountryFlag: ipinfo.CountryFlag{Emoji: "🇺🇸"},
+		ASN:         &ipinfo.CoreASN{ASN: "AS15169", Name: "Google LLC"},
+		Privacy:     &ipinfo.CorePrivacy{VPN: true},
+		Org:         "AS15169 Google, LLC",
+	}, &ipinfo.Core{IP: net.ParseIP("1.1.1.1")})
+
+	if len(records) != 3 {
+		t.Fatalf("wrote %d records, want a header and 2 rows", len(records))
+	}
+	tests := []struct{ name, want string }{
+		{"ip", "8.8.8.8"},
+		{"city", "Mountain View"},
+		{"country_flag_emoji", "🇺🇸"},
+		{"asn_id", "AS15169"},
+		{"asn_asn", "Google LLC"},
+		{"privacy_vpn", "true"},
+		{"privacy_proxy", "false"},
+		{"org", "AS15169 Google, LLC"},
+		{"bogon", "false"},
+	}
+	for _, tt := range tests {
+		if got := column(t, records, 1, tt.name); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// fields behind nil pointers are empty, but the columns are the same.
+	if len(records[2]) != len(records[0]) {
+		t.Errorf("row has %d columns, want %d", len(records[2]), len(records[0]))
+	}
+	for _, name := range []string{"asn_id", "privacy_vpn", "bogon_category"} {
+		if got := column(t, records, 2, name); got != "" {
+			t.Errorf("%s = %q for a nil struct, want empty", name, got)
+		}
+	}
+	for _, name := range records[0] {
+		if name == "extra" || name == "domains_domains" {
+			t.Errorf("untagged or excluded column %q written", name)
+		}
+	}
+}
+
+func TestCSVWriterTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		columns []string
+	}{
+		{"Lite", &ipinfo.Lite{ASN: "AS15169"}, []string{"ip", "asn", "country_code", "continent_info_code"}},
+		{"CoreResponse", &ipinfo.CoreResponse{Geo: &ipinfo.CoreGeo{City: "Paris"}}, []string{"geo_city", "as_asn", "is_anycast"}},
+		{"Plus", &ipinfo.Plus{}, []string{"geo_latitude", "mobile_mcc", "anonymous_is_vpn", "is_satellite"}},
+		{"ASNDetails", &ipinfo.ASNDetails{ASN: "AS15169", NumIPs: 42}, []string{"asn", "num_ips", "country_name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := writeCSV(t, tt.v)
+			for _, name := range tt.columns {
+				column(t, records, 1, name)
+			}
+		})
+	}
+
+	records := writeCSV(t, &ipinfo.ASNDetails{NumIPs: 42})
+	if got := column(t, records, 1, "num_ips"); got != "42" {
+		t.Errorf("num_ips = %q, want 42", got)
+	}
+	records = writeCSV(t, &ipinfo.Plus{Geo: &ipinfo.PlusGeo{Latitude: 37.4056}})
+	if got := column(t, records, 1, "geo_latitude"); got != "37.4056" {
+		t.Errorf("geo_latitude = %q, want 37.4056", got)
+	}
+}
+
+func TestCSVWriterBatch(t *testing.T) {
+	var buf bytes.Buffer
+	w := ipinfo.NewCSVWriter(&buf)
+	err := w.WriteBatch(ipinfo.BatchCore{
+		"8.8.8.8": {IP: net.ParseIP("8.8.8.8")},
+		"1.1.1.1": {IP: net.ParseIP("1.1.1.1")},
+		"9.9.9.9": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output:\n%s\nwant a header and a row per non-nil result", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "1.1.1.1,") || !strings.HasPrefix(lines[2], "8.8.8.8,") {
+		t.Errorf("rows = %q, want them sorted by key", lines[1:])
+	}
+}
+
+func TestCSVWriterErrors(t *testing.T) {
+	w := ipinfo.NewCSVWriter(&bytes.Buffer{})
+	if err := w.Write("8.8.8.8"); err == nil {
+		t.Error("err = nil for a string")
+	}
+	if err := w.Write(&ipinfo.Core{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(&ipinfo.Lite{}); err == nil {
+		t.Error("err = nil for a Lite result after a Core result")
+	}
+
+	type untagged struct{ IP string }
+	if err := ipinfo.NewCSVWriter(&bytes.Buffer{}).Write(untagged{}); err == nil {
+		t.Error("err = nil for a type without csv tags")
+	}
+	type nested struct {
+		Peers []string `csv:"peers"`
+	}
+	if err := ipinfo.NewCSVWriter(&bytes.Buffer{}).Write(nested{}); err == nil {
+		t.Error("err = nil for a slice column")
+	}
+}
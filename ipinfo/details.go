@@ -0,0 +1,414 @@
+package ipinfo
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// IPDetails is a product-independent representation of the details of an IP,
+// into which the responses of all API tiers can be converted.
+type IPDetails struct {
+	IP          net.IP         `json:"ip"`
+	Hostname    string         `json:"hostname,omitempty"`
+	Bogon       bool           `json:"bogon,omitempty"`
+	BogonInfo   *BogonInfo     `json:"bogon_info,omitempty"`
+	Local       *LocalNetwork  `json:"local,omitempty"`
+	Org         string         `json:"org,omitempty"`
+	Geo         *DetailsGeo    `json:"geo,omitempty"`
+	AS          *DetailsAS     `json:"as,omitempty"`
+	Mobile      *PlusMobile    `json:"mobile,omitempty"`
+	Anonymous   *PlusAnonymous `json:"anonymous,omitempty"`
+	IsAnonymous bool           `json:"is_anonymous"`
+	IsAnycast   bool           `json:"is_anycast"`
+	IsHosting   bool           `json:"is_hosting"`
+	IsMobile    bool           `json:"is_mobile"`
+	IsSatellite bool           `json:"is_satellite"`
+	Abuse       *PlusAbuse     `json:"abuse,omitempty"`
+	Company     *PlusCompany   `json:"company,omitempty"`
+	Privacy     *PlusPrivacy   `json:"privacy,omitempty"`
+	Domains     *PlusDomains   `json:"domains,omitempty"`
+
+	// Tier whose response the details were converted from.
+	Tier Tier `json:"tier"`
+
+	// Missing lists the fields, as dotted JSON paths, which `Tier` does not
+	// provide and which are therefore empty regardless of the IP, followed by
+	// those which could not be converted from the response, such as the
+	// coordinates of a malformed location.
+	Missing []string `json:"missing,omitempty"`
+
+	// Extra holds the response fields unknown to this version of the
+	// library, as retained by the response converted from.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DetailsGeo represents the geolocation of an IP in `IPDetails`.
+type DetailsGeo struct {
+	City          string  `json:"city,omitempty"`
+	Region        string  `json:"region,omitempty"`
+	RegionCode    string  `json:"region_code,omitempty"`
+	Country       string  `json:"country,omitempty"`
+	CountryCode   string  `json:"country_code,omitempty"`
+	Continent     string  `json:"continent,omitempty"`
+	ContinentCode string  `json:"continent_code,omitempty"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Timezone      string  `json:"timezone,omitempty"`
+	PostalCode    string  `json:"postal_code,omitempty"`
+	DMACode       string  `json:"dma_code,omitempty"`
+	GeonameID     string  `json:"geoname_id,omitempty"`
+	Radius        int     `json:"radius,omitempty"`
+	LastChanged   string  `json:"last_changed,omitempty"`
+
+	// Location is the "latitude,longitude" pair as returned by the legacy
+	// API, kept as is.
+	Location string `json:"location,omitempty"`
+
+	// Enrichments, named as in the legacy `Core` and enriched JSON.
+	CountryName     string          `json:"country_name,omitempty"`
+	IsEU            bool            `json:"isEU,omitempty"`
+	CountryFlag     CountryFlag     `json:"country_flag,omitempty"`
+	CountryFlagURL  string          `json:"country_flag_url,omitempty"`
+	CountryCurrency CountryCurrency `json:"country_currency,omitempty"`
+	ContinentInfo   Continent       `json:"continent_info,omitempty"`
+}
+
+// DetailsAS represents the autonomous system of an IP in `IPDetails`.
+type DetailsAS struct {
+	ASN         string `json:"asn"`
+	Name        string `json:"name"`
+	Domain      string `json:"domain"`
+	Type        string `json:"type,omitempty"`
+	Route       string `json:"route,omitempty"`
+	LastChanged string `json:"last_changed,omitempty"`
+}
+
+// Fields which each tier does not provide, as dotted JSON paths of
+// `IPDetails`.
+var (
+	liteMissingFields = []string{
+		"hostname", "org",
+		"geo.city", "geo.region", "geo.region_code", "geo.latitude",
+		"geo.longitude", "geo.timezone", "geo.postal_code", "geo.dma_code",
+		"geo.geoname_id", "geo.radius", "geo.last_changed",
+		"as.type", "as.route", "as.last_changed",
+		"mobile", "anonymous",
+		"is_anonymous", "is_anycast", "is_hosting", "is_mobile", "is_satellite",
+		"abuse", "company", "privacy", "domains",
+	}
+	coreMissingFields = []string{
+		"hostname", "org",
+		"geo.dma_code", "geo.geoname_id", "geo.radius", "geo.last_changed",
+		"as.route", "as.last_changed",
+		"mobile", "anonymous",
+		"abuse", "company", "privacy", "domains",
+	}
+	plusMissingFields = []string{
+		"org",
+		"as.route",
+	}
+	legacyMissingFields = []string{
+		"geo.region_code", "geo.dma_code", "geo.geoname_id", "geo.radius",
+		"geo.last_changed",
+		"as.last_changed",
+		"anonymous",
+		"is_anonymous", "is_hosting", "is_mobile", "is_satellite",
+	}
+)
+
+// MissingFields returns the fields of `IPDetails`, as dotted JSON paths,
+// which tier `t` does not provide.
+//
+// For `TierLegacy`, fields which depend on the plan of the token are not
+// listed.
+func (t Tier) MissingFields() []string {
+	switch t {
+	case TierLite:
+		return append([]string(nil), liteMissingFields...)
+	case TierCore:
+		return append([]string(nil), coreMissingFields...)
+	case TierPlus:
+		return append([]string(nil), plusMissingFields...)
+	case TierLegacy:
+		return append([]string(nil), legacyMissingFields...)
+	default:
+		return nil
+	}
+}
+
+// Details converts `v` into the product-independent `IPDetails`.
+//
+// The legacy API has no flags other than `Anycast`, so the other flags are
+// listed in `Missing` rather than guessed from `Carrier` or `Privacy`, which
+// are kept as `Mobile` and `Privacy`. The location is kept as is in
+// `Geo.Location`; if it is malformed, the coordinates are listed in `Missing`
+// too.
+func (v *Core) Details() *IPDetails {
+	d := &IPDetails{
+		IP:        v.IP,
+		Hostname:  v.Hostname,
+		Bogon:     v.Bogon,
+		BogonInfo: v.BogonInfo,
+		Local:     v.Local,
+		Org:       v.Org,
+		IsAnycast: v.Anycast,
+		Tier:      TierLegacy,
+		Missing:   TierLegacy.MissingFields(),
+		Extra:     v.Extra,
+	}
+	if v.City != "" || v.Region != "" || v.Country != "" || v.Location != "" ||
+		v.Postal != "" || v.Timezone != "" {
+		lat, lon, ok := parseLocation(v.Location)
+		if !ok {
+			d.Missing = append(d.Missing, "geo.latitude", "geo.longitude")
+		}
+		d.Geo = &DetailsGeo{
+			City:            v.City,
+			Region:          v.Region,
+			Country:         v.CountryName,
+			CountryCode:     v.Country,
+			Continent:       v.Continent.Name,
+			ContinentCode:   v.Continent.Code,
+			Latitude:        lat,
+			Longitude:       lon,
+			Timezone:        v.Timezone,
+			PostalCode:      v.Postal,
+			Location:        v.Location,
+			CountryName:     v.CountryName,
+			IsEU:            v.IsEU,
+			CountryFlag:     v.CountryFlag,
+			CountryFlagURL:  v.CountryFlagURL,
+			CountryCurrency: v.CountryCurrency,
+			ContinentInfo:   v.Continent,
+		}
+	}
+	if as := v.ASN; as != nil {
+		d.AS = &DetailsAS{
+			ASN:    as.ASN,
+			Name:   as.Name,
+			Domain: as.Domain,
+			Type:   as.Type,
+			Route:  as.Route,
+		}
+	}
+	if c := v.Carrier; c != nil {
+		d.Mobile = &PlusMobile{
+			Name: c.Name,
+			MCC:  c.MCC,
+			MNC:  c.MNC,
+		}
+	}
+	if p := v.Privacy; p != nil {
+		d.Privacy = &PlusPrivacy{
+			VPN:     p.VPN,
+			Proxy:   p.Proxy,
+			Tor:     p.Tor,
+			Relay:   p.Relay,
+			Hosting: p.Hosting,
+			Service: p.Service,
+		}
+	}
+	if a := v.Abuse; a != nil {
+		d.Abuse = &PlusAbuse{
+			Address:     a.Address,
+			Country:     a.Country,
+			CountryName: a.CountryName,
+			Email:       a.Email,
+			Name:        a.Name,
+			Network:     a.Network,
+			Phone:       a.Phone,
+		}
+	}
+	if c := v.Company; c != nil {
+		d.Company = &PlusCompany{
+			Name:   c.Name,
+			Domain: c.Domain,
+			Type:   c.Type,
+		}
+	}
+	if dom := v.Domains; dom != nil {
+		d.Domains = &PlusDomains{
+			IP:      dom.IP,
+			Total:   dom.Total,
+			Domains: dom.Domains,
+		}
+	}
+	return d
+}
+
+// `parseLocation` parses a "latitude,longitude" pair as found in the `loc`
+// field of the legacy API, reporting whether it is well-formed.
+func parseLocation(loc string) (float64, float64, bool) {
+	latStr, lonStr, ok := strings.Cut(loc, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Details converts `v` into the product-independent `IPDetails`.
+func (v *Lite) Details() *IPDetails {
+	d := &IPDetails{
+		IP:        v.IP,
+		Bogon:     v.Bogon,
+		BogonInfo: v.BogonInfo,
+		Local:     v.Local,
+		Tier:      TierLite,
+		Missing:   TierLite.MissingFields(),
+	}
+	if v.CountryCode != "" || v.Country != "" || v.ContinentCode != "" {
+		d.Geo = &DetailsGeo{
+			Country:         v.Country,
+			CountryCode:     v.CountryCode,
+			Continent:       v.Continent,
+			ContinentCode:   v.ContinentCode,
+			CountryName:     v.CountryName,
+			IsEU:            v.IsEU,
+			CountryFlag:     v.CountryFlag,
+			CountryFlagURL:  v.CountryFlagURL,
+			CountryCurrency: v.CountryCurrency,
+			ContinentInfo:   v.ContinentInfo,
+		}
+	}
+	if v.ASN != "" || v.ASName != "" || v.ASDomain != "" {
+		d.AS = &DetailsAS{
+			ASN:    v.ASN,
+			Name:   v.ASName,
+			Domain: v.ASDomain,
+		}
+	}
+	return d
+}
+
+// Details converts `v` into the product-independent `IPDetails`.
+func (v *CoreResponse) Details() *IPDetails {
+	d := &IPDetails{
+		IP:          v.IP,
+		Bogon:       v.Bogon,
+		BogonInfo:   v.BogonInfo,
+		Local:       v.Local,
+		IsAnonymous: v.IsAnonymous,
+		IsAnycast:   v.IsAnycast,
+		IsHosting:   v.IsHosting,
+		IsMobile:    v.IsMobile,
+		IsSatellite: v.IsSatellite,
+		Tier:        TierCore,
+		Missing:     TierCore.MissingFields(),
+		Extra:       v.Extra,
+	}
+	if g := v.Geo; g != nil {
+		d.Geo = &DetailsGeo{
+			City:            g.City,
+			Region:          g.Region,
+			RegionCode:      g.RegionCode,
+			Country:         g.Country,
+			CountryCode:     g.CountryCode,
+			Continent:       g.Continent,
+			ContinentCode:   g.ContinentCode,
+			Latitude:        g.Latitude,
+			Longitude:       g.Longitude,
+			Timezone:        g.Timezone,
+			PostalCode:      g.PostalCode,
+			CountryName:     g.CountryName,
+			IsEU:            g.IsEU,
+			CountryFlag:     g.CountryFlag,
+			CountryFlagURL:  g.CountryFlagURL,
+			CountryCurrency: g.CountryCurrency,
+			ContinentInfo:   g.ContinentInfo,
+		}
+	}
+	if as := v.AS; as != nil {
+		d.AS = &DetailsAS{
+			ASN:    as.ASN,
+			Name:   as.Name,
+			Domain: as.Domain,
+			Type:   as.Type,
+		}
+	}
+	return d
+}
+
+// Details converts `v` into the product-independent `IPDetails`.
+func (v *Plus) Details() *IPDetails {
+	d := &IPDetails{
+		IP:          v.IP,
+		Hostname:    v.Hostname,
+		Bogon:       v.Bogon,
+		BogonInfo:   v.BogonInfo,
+		Local:       v.Local,
+		Mobile:      v.Mobile,
+		Anonymous:   v.Anonymous,
+		IsAnonymous: v.IsAnonymous,
+		IsAnycast:   v.IsAnycast,
+		IsHosting:   v.IsHosting,
+		IsMobile:    v.IsMobile,
+		IsSatellite: v.IsSatellite,
+		Abuse:       v.Abuse,
+		Company:     v.Company,
+		Privacy:     v.Privacy,
+		Domains:     v.Domains,
+		Tier:        TierPlus,
+		Missing:     TierPlus.MissingFields(),
+		Extra:       v.Extra,
+	}
+	if g := v.Geo; g != nil {
+		d.Geo = &DetailsGeo{
+			City:            g.City,
+			Region:          g.Region,
+			RegionCode:      g.RegionCode,
+			Country:         g.Country,
+			CountryCode:     g.CountryCode,
+			Continent:       g.Continent,
+			ContinentCode:   g.ContinentCode,
+			Latitude:        g.Latitude,
+			Longitude:       g.Longitude,
+			Timezone:        g.Timezone,
+			PostalCode:      g.PostalCode,
+			DMACode:         g.DMACode,
+			GeonameID:       g.GeonameID,
+			Radius:          g.Radius,
+			LastChanged:     g.LastChanged,
+			CountryName:     g.CountryName,
+			IsEU:            g.IsEU,
+			CountryFlag:     g.CountryFlag,
+			CountryFlagURL:  g.CountryFlagURL,
+			CountryCurrency: g.CountryCurrency,
+			ContinentInfo:   g.ContinentInfo,
+		}
+	}
+	if as := v.AS; as != nil {
+		d.AS = &DetailsAS{
+			ASN:         as.ASN,
+			Name:        as.Name,
+			Domain:      as.Domain,
+			Type:        as.Type,
+			LastChanged: as.LastChanged,
+		}
+	}
+	return d
+}
+
+// Details converts the result of whichever tier answered into the
+// product-independent `IPDetails`.
+func (r *TierResult) Details() *IPDetails {
+	switch {
+	case r.Plus != nil:
+		return r.Plus.Details()
+	case r.Core != nil:
+		return r.Core.Details()
+	case r.Lite != nil:
+		return r.Lite.Details()
+	default:
+		return nil
+	}
+}
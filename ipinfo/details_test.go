@@ -0,0 +1,192 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+// `hasField` reports whether `fields` contains `field`.
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCoreDetails(t *testing.T) {
+	v := &ipinfo.Core{
+		IP:       net.ParseIP("8.8.8.8"),
+		Hostname: "dns.google",
+		Anycast:  true,
+		City:     "Mountain View",
+		Country:  "US",
+		Location: "37.4056,-122.0775",
+		Org:      "AS15169 Google LLC",
+		ASN:      &ipinfo.CoreASN{ASN: "AS15169", Name: "Google LLC", Route: "8.8.8.0/24"},
+		Carrier:  &ipinfo.CoreCarrier{Name: "Example Mobile", MCC: "310", MNC: "260"},
+		Privacy:  &ipinfo.CorePrivacy{VPN: true, Hosting: true},
+	}
+	d := v.Details()
+	if d.Tier != ipinfo.TierLegacy || d.Hostname != "dns.google" || d.Org != v.Org || !d.IsAnycast {
+		t.Errorf("details = %+v", d)
+	}
+	if d.Geo == nil || d.Geo.Latitude != 37.4056 || d.Geo.Longitude != -122.0775 || d.Geo.Location != v.Location {
+		t.Errorf("Geo = %+v, want the parsed and the raw location", d.Geo)
+	}
+	if d.AS == nil || d.AS.ASN != "AS15169" || d.AS.Route != "8.8.8.0/24" {
+		t.Errorf("AS = %+v", d.AS)
+	}
+	if d.Mobile == nil || d.Mobile.MCC != "310" || d.Privacy == nil || !d.Privacy.VPN || !d.Privacy.Hosting {
+		t.Errorf("Mobile = %+v, Privacy = %+v, want them kept", d.Mobile, d.Privacy)
+	}
+
+	// flags the legacy API lacks aren't guessed.
+	if d.IsMobile || d.IsHosting || d.IsAnonymous {
+		t.Errorf("flags = mobile %v, hosting %v, anonymous %v, want them unset",
+			d.IsMobile, d.IsHosting, d.IsAnonymous)
+	}
+	for _, field := range []string{"is_mobile", "is_hosting", "is_anonymous"} {
+		if !hasField(d.Missing, field) {
+			t.Errorf("Missing = %q, want %s", d.Missing, field)
+		}
+	}
+	if hasField(d.Missing, "geo.latitude") {
+		t.Errorf("Missing = %q, want the coordinates present", d.Missing)
+	}
+}
+
+func TestCoreDetailsMalformedLocation(t *testing.T) {
+	for _, loc := range []string{"", "37.4056", "north,west", "91,0", "0,181"} {
+		d := (&ipinfo.Core{City: "Somewhere", Location: loc}).Details()
+		if d.Geo == nil || d.Geo.Location != loc {
+			t.Errorf("%q: Geo = %+v, want the raw location kept", loc, d.Geo)
+			continue
+		}
+		if !hasField(d.Missing, "geo.latitude") || !hasField(d.Missing, "geo.longitude") {
+			t.Errorf("%q: Missing = %q, want the coordinates", loc, d.Missing)
+		}
+	}
+
+	// "0,0" is a valid location.
+	d := (&ipinfo.Core{Location: "0,0"}).Details()
+	if hasField(d.Missing, "geo.latitude") {
+		t.Errorf("Missing = %q for 0,0", d.Missing)
+	}
+}
+
+func TestLiteDetails(t *testing.T) {
+	d := (&ipinfo.Lite{
+		IP:            net.ParseIP("8.8.8.8"),
+		ASN:           "AS15169",
+		ASName:        "Google LLC",
+		CountryCode:   "US",
+		Country:       "United States",
+		ContinentCode: "NA",
+	}).Details()
+	if d.Tier != ipinfo.TierLite || !reflect.DeepEqual(d.Missing, ipinfo.TierLite.MissingFields()) {
+		t.Errorf("Tier = %v, Missing = %q", d.Tier, d.Missing)
+	}
+	if d.Geo == nil || d.Geo.CountryCode != "US" || d.Geo.ContinentCode != "NA" {
+		t.Errorf("Geo = %+v", d.Geo)
+	}
+	if d.AS == nil || d.AS.ASN != "AS15169" || d.AS.Name != "Google LLC" {
+		t.Errorf("AS = %+v", d.AS)
+	}
+
+	if d := (&ipinfo.Lite{IP: net.ParseIP("10.0.0.1"), Bogon: true}).Details(); d.Geo != nil || d.AS != nil {
+		t.Errorf("details of a bogon = %+v, want no Geo or AS", d)
+	}
+}
+
+func TestDetailsEnrichmentNames(t *testing.T) {
+	d := (&ipinfo.Core{IP: net.ParseIP("1.2.3.4"), Country: "DE", IsEU: true}).Details()
+	data, err := json.Marshal(d.Geo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	// the same name as in the legacy `Core`.
+	if m["isEU"] != true {
+		t.Errorf("Geo = %s, want isEU", data)
+	}
+}
+
+func TestCoreResponseDetails(t *testing.T) {
+	d := (&ipinfo.CoreResponse{
+		IP:       net.ParseIP("8.8.8.8"),
+		Geo:      &ipinfo.CoreGeo{City: "Mountain View", Latitude: 37.4056, Longitude: -122.0775},
+		AS:       &ipinfo.CoreAS{ASN: "AS15169", Type: "hosting"},
+		IsMobile: true,
+	}).Details()
+	if d.Tier != ipinfo.TierCore || !d.IsMobile {
+		t.Errorf("details = %+v", d)
+	}
+	if d.Geo == nil || d.Geo.City != "Mountain View" || d.Geo.Latitude != 37.4056 {
+		t.Errorf("Geo = %+v", d.Geo)
+	}
+	if d.AS == nil || d.AS.Type != "hosting" {
+		t.Errorf("AS = %+v", d.AS)
+	}
+}
+
+func TestPlusDetails(t *testing.T) {
+	v := &ipinfo.Plus{
+		IP:        net.ParseIP("8.8.8.8"),
+		Hostname:  "dns.google",
+		Geo:       &ipinfo.PlusGeo{City: "Mountain View", DMACode: "807", Radius: 20},
+		AS:        &ipinfo.PlusAS{ASN: "AS15169", LastChanged: "2024-01-02"},
+		Mobile:    &ipinfo.PlusMobile{Name: "Example Mobile"},
+		IsHosting: true,
+	}
+	d := v.Details()
+	if d.Tier != ipinfo.TierPlus || d.Hostname != "dns.google" || !d.IsHosting || d.Mobile != v.Mobile {
+		t.Errorf("details = %+v", d)
+	}
+	if d.Geo == nil || d.Geo.DMACode != "807" || d.Geo.Radius != 20 {
+		t.Errorf("Geo = %+v", d.Geo)
+	}
+	if d.AS == nil || d.AS.LastChanged != "2024-01-02" {
+		t.Errorf("AS = %+v", d.AS)
+	}
+}
+
+func TestTierResultDetails(t *testing.T) {
+	tests := []struct {
+		res  *ipinfo.TierResult
+		want ipinfo.Tier
+	}{
+		{&ipinfo.TierResult{Tier: ipinfo.TierLite, Lite: &ipinfo.Lite{}}, ipinfo.TierLite},
+		{&ipinfo.TierResult{Tier: ipinfo.TierCore, Core: &ipinfo.CoreResponse{}}, ipinfo.TierCore},
+		{&ipinfo.TierResult{Tier: ipinfo.TierPlus, Plus: &ipinfo.Plus{}}, ipinfo.TierPlus},
+	}
+	for _, tt := range tests {
+		if d := tt.res.Details(); d == nil || d.Tier != tt.want {
+			t.Errorf("Details of a %v result = %+v", tt.want, d)
+		}
+	}
+	if d := (&ipinfo.TierResult{}).Details(); d != nil {
+		t.Errorf("Details of an empty result = %+v, want nil", d)
+	}
+}
+
+func TestMissingFields(t *testing.T) {
+	if fields := ipinfo.TierUnknown.MissingFields(); fields != nil {
+		t.Errorf("MissingFields of TierUnknown = %q, want nil", fields)
+	}
+
+	// the result is a copy.
+	fields := ipinfo.TierPlus.MissingFields()
+	fields[0] = "changed"
+	if ipinfo.TierPlus.MissingFields()[0] == "changed" {
+		t.Error("MissingFields returned the shared slice")
+	}
+}
@@ -17,5 +17,23 @@ non-empty token to NewClient. For example:
 
 	client := ipinfo.NewClient(nil, nil, "MY_TOKEN")
 	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+
+# Configuration
+
+Clients can also be built from functional options, including from the
+environment with FromEnv. For example:
+
+	client, err := ipinfo.NewCoreClientWithOptions(
+		ipinfo.FromEnv(),
+		ipinfo.WithTimeout(5*time.Second),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 3}),
+	)
+
+# IP input
+
+Besides net.IP, lookups take IPs as netip.Addr or as strings through the Addr
+and Str variants, such as GetIPAddrInfo, GetIPStrInfoV6, GetIPAddrMap and
+LookupStr. Single-field helpers such as GetIPHostname and GetIPCity take only
+net.IP; read the field from GetIPAddrInfo or GetIPStrInfo instead.
 */
 package ipinfo
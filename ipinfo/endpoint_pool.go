@@ -0,0 +1,418 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	endpointFailureThresholdDefault = 3
+	endpointCooldownDefault         = 30 * time.Second
+	endpointLatencySamples          = 256
+	hedgePercentileDefault          = 0.95
+	hedgeMinSamplesDefault          = 20
+	hedgeMaxHedgesDefault           = 1
+)
+
+// EndpointPoolOpts are options for an `EndpointPool`.
+type EndpointPoolOpts struct {
+	// FailureThreshold is the number of consecutive failed requests after
+	// which an endpoint is considered unhealthy.
+	//
+	// 0 means to use a default of 3.
+	FailureThreshold int
+
+	// Cooldown is how long an unhealthy endpoint is only used as a last
+	// resort, after which it is preferred again.
+	//
+	// 0 means to use a default of 30 seconds.
+	Cooldown time.Duration
+
+	// Hedge enables hedged requests. See `HedgePolicy`.
+	//
+	// nil means requests are only sent to another endpoint after failing.
+	Hedge *HedgePolicy
+}
+
+// HedgePolicy configures hedged requests: when a request hasn't been
+// answered within a percentile of recent latencies, it is also sent to the
+// next endpoint and whichever response arrives first is used.
+type HedgePolicy struct {
+	// Percentile of recent latencies, between 0 and 1, after which a
+	// request is hedged.
+	//
+	// 0 means to use a default of 0.95.
+	Percentile float64
+
+	// MinDelay is the minimum wait before hedging, regardless of latencies.
+	MinDelay time.Duration
+
+	// MinSamples is the number of successful requests needed before
+	// hedging starts, so that the percentile is meaningful.
+	//
+	// 0 means to use a default of 20.
+	MinSamples int
+
+	// MaxHedges is the maximum number of additional requests per request.
+	//
+	// 0 means to use a default of 1.
+	MaxHedges int
+}
+
+// EndpointHealth reports the health of a single endpoint of an
+// `EndpointPool`.
+type EndpointHealth struct {
+	// Base URL of the endpoint.
+	URL string
+
+	// Number of requests sent to the endpoint.
+	Requests uint64
+
+	// Number of those requests which failed.
+	Failures uint64
+
+	// Number of failures since the last successful request.
+	ConsecutiveFailures int
+
+	// Time until which the endpoint is considered unhealthy; zero if it is
+	// healthy.
+	UnhealthyUntil time.Time
+}
+
+// EndpointPool spreads the requests of a client across several equivalent
+// base URLs, e.g. regional endpoints or an internal caching proxy, failing
+// over to the next endpoint when one fails and optionally hedging slow
+// requests.
+//
+// Endpoints are preferred in the order given; unhealthy endpoints are only
+// used once all healthy ones have failed. A request fails over on network
+// errors and 5xx responses, provided its body can be rewound.
+//
+// A pool is set on a client with `WithEndpointPool` and replaces the base
+// URL of the client. It may be shared by several clients of the same API.
+type EndpointPool struct {
+	opts      EndpointPoolOpts
+	endpoints []*endpoint
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// endpoint is a base URL of an `EndpointPool` along with its health, which
+// is guarded by the mutex of the pool.
+type endpoint struct {
+	url    *url.URL
+	health EndpointHealth
+}
+
+// NewEndpointPool returns a pool of `baseURLs`, which must be absolute. A
+// trailing slash is added to each if missing.
+func NewEndpointPool(
+	baseURLs []string,
+	opts EndpointPoolOpts,
+) (*EndpointPool, error) {
+	if len(baseURLs) == 0 {
+		return nil, errors.New("endpoint pool: no base URLs")
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = endpointFailureThresholdDefault
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = endpointCooldownDefault
+	}
+	if opts.Hedge != nil {
+		hedge := *opts.Hedge
+		if hedge.Percentile <= 0 || hedge.Percentile > 1 {
+			hedge.Percentile = hedgePercentileDefault
+		}
+		if hedge.MinSamples <= 0 {
+			hedge.MinSamples = hedgeMinSamplesDefault
+		}
+		if hedge.MaxHedges <= 0 {
+			hedge.MaxHedges = hedgeMaxHedgesDefault
+		}
+		opts.Hedge = &hedge
+	}
+
+	p := &EndpointPool{opts: opts}
+	for _, s := range baseURLs {
+		u, err := parseBaseURL(s)
+		if err != nil {
+			return nil, err
+		}
+		p.endpoints = append(p.endpoints, &endpoint{
+			url:    u,
+			health: EndpointHealth{URL: u.String()},
+		})
+	}
+	return p, nil
+}
+
+// Health returns a snapshot of the health of every endpoint in the pool, in
+// order of preference.
+func (p *EndpointPool) Health() []EndpointHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	health := make([]EndpointHealth, len(p.endpoints))
+	for i, e := range p.endpoints {
+		health[i] = e.health
+		if !now.Before(e.health.UnhealthyUntil) {
+			health[i].UnhealthyUntil = time.Time{}
+		}
+	}
+	return health
+}
+
+// `order` returns the endpoints in the order to try them: healthy ones in
+// order of preference, then unhealthy ones soonest to recover first.
+func (p *EndpointPool) order(now time.Time) []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order := make([]*endpoint, len(p.endpoints))
+	copy(order, p.endpoints)
+	sort.SliceStable(order, func(i, j int) bool {
+		ui, uj := order[i].health.UnhealthyUntil, order[j].health.UnhealthyUntil
+		iDown, jDown := now.Before(ui), now.Before(uj)
+		if iDown != jDown {
+			return jDown
+		}
+		return iDown && ui.Before(uj)
+	})
+	return order
+}
+
+// `record` records the outcome of a request to `e` which took `d`.
+func (p *EndpointPool) record(e *endpoint, failed bool, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := &e.health
+	h.Requests++
+	if failed {
+		h.Failures++
+		h.ConsecutiveFailures++
+		if h.ConsecutiveFailures >= p.opts.FailureThreshold {
+			h.UnhealthyUntil = time.Now().Add(p.opts.Cooldown)
+		}
+		return
+	}
+
+	h.ConsecutiveFailures = 0
+	h.UnhealthyUntil = time.Time{}
+	if len(p.latencies) < endpointLatencySamples {
+		p.latencies = append(p.latencies, d)
+	} else {
+		p.latencies[p.next] = d
+		p.next = (p.next + 1) % endpointLatencySamples
+	}
+}
+
+// `hedgeDelay` returns the wait before hedging a request, or 0 to not hedge.
+func (p *EndpointPool) hedgeDelay() time.Duration {
+	h := p.opts.Hedge
+	if h == nil || len(p.endpoints) < 2 {
+		return 0
+	}
+
+	p.mu.Lock()
+	if len(p.latencies) < h.MinSamples {
+		p.mu.Unlock()
+		return 0
+	}
+	sorted := make([]time.Duration, len(p.latencies))
+	copy(sorted, p.latencies)
+	p.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	d := sorted[int(h.Percentile*float64(len(sorted)-1))]
+	if d < h.MinDelay {
+		d = h.MinDelay
+	}
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	return d
+}
+
+// endpointResult is the outcome of a request sent to a single endpoint.
+type endpointResult struct {
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// `do` sends `req`, which targets `baseURL`, to the endpoints of the pool,
+// failing over and hedging as configured. Requests which don't target
+// `baseURL`, or whose body cannot be rewound, are sent unchanged.
+func (p *EndpointPool) do(
+	client *http.Client,
+	req *http.Request,
+	baseURL *url.URL,
+) (*http.Response, error) {
+	if !hasBaseURL(req.URL, baseURL) ||
+		(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return client.Do(req)
+	}
+	relPath := strings.TrimPrefix(req.URL.Path, baseURL.Path)
+
+	order := p.order(time.Now())
+	results := make(chan endpointResult, len(order))
+	launched, pending := 0, 0
+	launch := func() error {
+		e := order[launched]
+		base := e.url
+		launched++
+
+		ctx, cancel := context.WithCancel(req.Context())
+		r := req.Clone(ctx)
+		u := *req.URL
+		u.Scheme, u.Host = base.Scheme, base.Host
+		u.Path, u.RawPath = base.Path+relPath, ""
+		r.URL, r.Host = &u, ""
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			r.Body = body
+		}
+
+		pending++
+		go func() {
+			start := time.Now()
+			resp, err := client.Do(r)
+			if ctx.Err() == nil {
+				// requests canceled as losing hedges say nothing of health.
+				p.record(e, endpointFailed(ctx, resp, err), time.Since(start))
+			}
+			results <- endpointResult{resp: resp, err: err, cancel: cancel}
+		}()
+		return nil
+	}
+
+	if err := launch(); err != nil {
+		return nil, err
+	}
+
+	var hedgeC <-chan time.Time
+	hedges := 0
+	delay := p.hedgeDelay()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeC = timer.C
+	}
+
+	// the latest failure, returned if all endpoints fail.
+	var failure endpointResult
+	for {
+		select {
+		case <-hedgeC:
+			if launched == len(order) || hedges == p.opts.Hedge.MaxHedges {
+				hedgeC = nil
+				continue
+			}
+			hedges++
+			if err := launch(); err != nil {
+				discard(failure)
+				return p.finish(endpointResult{err: err}, results, pending)
+			}
+			hedgeC = time.After(delay)
+		case res := <-results:
+			pending--
+			discard(failure)
+			if !endpointFailed(req.Context(), res.resp, res.err) {
+				return p.finish(res, results, pending)
+			}
+
+			failure = res
+			if launched < len(order) && req.Context().Err() == nil {
+				if err := launch(); err != nil {
+					discard(failure)
+					return p.finish(endpointResult{err: err}, results, pending)
+				}
+			} else if pending == 0 {
+				return p.finish(failure, results, 0)
+			}
+		}
+	}
+}
+
+// `finish` returns the outcome of `res`, discarding the `pending` results
+// still to arrive on `results` in the background. The request context of
+// the response is canceled once its body is closed.
+func (p *EndpointPool) finish(
+	res endpointResult,
+	results <-chan endpointResult,
+	pending int,
+) (*http.Response, error) {
+	if pending > 0 {
+		go func() {
+			for i := 0; i < pending; i++ {
+				discard(<-results)
+			}
+		}()
+	}
+
+	if res.cancel != nil {
+		if res.resp != nil {
+			res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+		} else {
+			res.cancel()
+		}
+	}
+	return res.resp, res.err
+}
+
+// `discard` releases the resources of an unused result.
+func discard(res endpointResult) {
+	if res.resp != nil {
+		io.Copy(io.Discard, res.resp.Body)
+		res.resp.Body.Close()
+	}
+	if res.cancel != nil {
+		res.cancel()
+	}
+}
+
+// `endpointFailed` reports whether a request which ended with `resp` or
+// `err` should be sent to another endpoint. Requests canceled by the caller
+// don't count as failed.
+func endpointFailed(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return resp.StatusCode >= 500
+}
+
+// `hasBaseURL` reports whether `u` is beneath `baseURL`.
+func hasBaseURL(u, baseURL *url.URL) bool {
+	return baseURL != nil &&
+		u.Scheme == baseURL.Scheme &&
+		u.Host == baseURL.Host &&
+		strings.HasPrefix(u.Path, baseURL.Path)
+}
+
+// cancelBody cancels the context of its request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
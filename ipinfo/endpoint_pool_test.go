@@ -0,0 +1,217 @@
+package ipinfo_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `poolClient` returns a client of the legacy API sending requests to a pool
+// of `urls` configured by `opts`.
+func poolClient(
+	t *testing.T,
+	urls []string,
+	opts ipinfo.EndpointPoolOpts,
+) (*ipinfo.Client, *ipinfo.EndpointPool) {
+	t.Helper()
+	pool, err := ipinfo.NewEndpointPool(urls, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := ipinfo.NewClientWithOptions(ipinfo.WithEndpointPool(pool), ipinfo.WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, pool
+}
+
+func TestNewEndpointPoolErrors(t *testing.T) {
+	if _, err := ipinfo.NewEndpointPool(nil, ipinfo.EndpointPoolOpts{}); err == nil {
+		t.Error("err = nil for no base URLs")
+	}
+	if _, err := ipinfo.NewEndpointPool([]string{"https://ipinfo.io", "proxy"}, ipinfo.EndpointPoolOpts{}); err == nil {
+		t.Error("err = nil for a relative base URL")
+	}
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+	secondary.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+
+	client, pool := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{
+		FailureThreshold: 2,
+		Cooldown:         time.Hour,
+	})
+	for i := 0; i < 3; i++ {
+		info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.City != "Mountain View" {
+			t.Errorf("City = %q, want the secondary to answer", info.City)
+		}
+	}
+
+	// the primary is skipped once unhealthy.
+	if n := len(primary.Requests()); n != 2 {
+		t.Errorf("primary received %d requests, want 2", n)
+	}
+	health := pool.Health()
+	if health[0].URL != primary.URL+"/" || health[0].Failures != 2 || health[0].UnhealthyUntil.IsZero() {
+		t.Errorf("primary health = %+v, want unhealthy", health[0])
+	}
+	if health[1].Requests != 3 || health[1].Failures != 0 || !health[1].UnhealthyUntil.IsZero() {
+		t.Errorf("secondary health = %+v", health[1])
+	}
+}
+
+func TestEndpointPoolRecovers(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+
+	client, pool := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	})
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if h := pool.Health()[0]; !h.UnhealthyUntil.IsZero() {
+		t.Errorf("primary health = %+v after the cooldown", h)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(primary.Requests()); n != 2 {
+		t.Errorf("primary received %d requests, want it preferred again", n)
+	}
+}
+
+func TestEndpointPoolAllFail(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+	secondary.Fault("/", ipinfotest.Fault{Status: http.StatusServiceUnavailable})
+
+	client, _ := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{})
+	_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want the error of the last endpoint", err)
+	}
+}
+
+func TestEndpointPoolNoFailoverOn4xx(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/", ipinfotest.Fault{Status: http.StatusTooManyRequests})
+
+	client, _ := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{})
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Error("err = nil for a 429 response")
+	}
+	if n := len(secondary.Requests()); n != 0 {
+		t.Errorf("secondary received %d requests, want none", n)
+	}
+}
+
+func TestEndpointPoolBatch(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/batch", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	client, _ := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{})
+	batch, err := client.GetIPInfoBatch([]net.IP{net.ParseIP("8.8.8.8")}, ipinfo.BatchReqOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch["8.8.8.8"] == nil {
+		t.Errorf("batch = %v, want 8.8.8.8", batch)
+	}
+	p, s := primary.Requests(), secondary.Requests()
+	if len(p) != 1 || len(s) != 1 || string(p[0].Body) != string(s[0].Body) {
+		t.Errorf("requests = %+v and %+v, want the body sent to both", p, s)
+	}
+}
+
+func TestEndpointPoolBasePath(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	primary.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	pool, err := ipinfo.NewEndpointPool([]string{primary.URL + "/lite", secondary.URL + "/lite"}, ipinfo.EndpointPoolOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := ipinfo.NewLiteClientWithOptions(ipinfo.WithEndpointPool(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := secondary.Requests(); len(reqs) != 1 || reqs[0].Path != "/lite/8.8.8.8" {
+		t.Errorf("secondary received %+v, want /lite/8.8.8.8", reqs)
+	}
+}
+
+func TestEndpointPoolHedge(t *testing.T) {
+	primary := ipinfotest.NewServer()
+	defer primary.Close()
+	secondary := ipinfotest.NewServer()
+	defer secondary.Close()
+	secondary.SetIP("1.1.1.1", &ipinfo.Core{City: "Hedged"})
+
+	client, pool := poolClient(t, []string{primary.URL, secondary.URL}, ipinfo.EndpointPoolOpts{
+		Hedge: &ipinfo.HedgePolicy{MinSamples: 2, MinDelay: 10 * time.Millisecond},
+	})
+
+	// requests aren't hedged before enough latencies are known.
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(secondary.Requests()); n != 0 {
+		t.Fatalf("secondary received %d requests before hedging started", n)
+	}
+
+	primary.Slow("/", time.Second)
+	start := time.Now()
+	info, err := client.GetIPInfo(net.ParseIP("1.1.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("hedged lookup took %v", d)
+	}
+	if info.City != "Hedged" {
+		t.Errorf("City = %q, want the hedge to answer", info.City)
+	}
+
+	// the losing request doesn't count against the primary.
+	if h := pool.Health()[0]; h.Failures != 0 {
+		t.Errorf("primary health = %+v, want no failures", h)
+	}
+}
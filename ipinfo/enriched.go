@@ -0,0 +1,229 @@
+package ipinfo
+
+import (
+	"encoding/json"
+)
+
+// enrichment holds the fields which the library computes from the country
+// code of a result, as they appear in enriched JSON. The names follow those
+// of the legacy `Core`, with `continent_info` as the API already uses
+// `continent` for the continent name.
+type enrichment struct {
+	CountryName     string           `json:"country_name,omitempty"`
+	IsEU            bool             `json:"isEU,omitempty"`
+	CountryFlag     *CountryFlag     `json:"country_flag,omitempty"`
+	CountryFlagURL  string           `json:"country_flag_url,omitempty"`
+	CountryCurrency *CountryCurrency `json:"country_currency,omitempty"`
+	ContinentInfo   *Continent       `json:"continent_info,omitempty"`
+}
+
+func newEnrichment(
+	countryName string,
+	isEU bool,
+	flag CountryFlag,
+	flagURL string,
+	currency CountryCurrency,
+	continent Continent,
+) enrichment {
+	e := enrichment{
+		CountryName:    countryName,
+		IsEU:           isEU,
+		CountryFlagURL: flagURL,
+	}
+	if flag != (CountryFlag{}) {
+		e.CountryFlag = &flag
+	}
+	if currency != (CountryCurrency{}) {
+		e.CountryCurrency = &currency
+	}
+	if continent != (Continent{}) {
+		e.ContinentInfo = &continent
+	}
+	return e
+}
+
+// `set` stores the fields of `e` through the given pointers.
+func (e *enrichment) set(
+	countryName *string,
+	isEU *bool,
+	flag *CountryFlag,
+	flagURL *string,
+	currency *CountryCurrency,
+	continent *Continent,
+) {
+	*countryName = e.CountryName
+	*isEU = e.IsEU
+	*flagURL = e.CountryFlagURL
+	if e.CountryFlag != nil {
+		*flag = *e.CountryFlag
+	}
+	if e.CountryCurrency != nil {
+		*currency = *e.CountryCurrency
+	}
+	if e.ContinentInfo != nil {
+		*continent = *e.ContinentInfo
+	}
+}
+
+type enrichedLite struct {
+	*lite
+	enrichment
+}
+
+type lite Lite
+
+type enrichedCoreGeo struct {
+	*CoreGeo
+	enrichment
+}
+
+type enrichedCoreResponse struct {
+	*coreResponse
+	Geo *enrichedCoreGeo `json:"geo,omitempty"`
+}
+
+type coreResponse CoreResponse
+
+type enrichedPlusGeo struct {
+	*PlusGeo
+	enrichment
+}
+
+type enrichedPlus struct {
+	*plus
+	Geo *enrichedPlusGeo `json:"geo,omitempty"`
+}
+
+type plus Plus
+
+// enrichedGeo is the part of enriched Core and Plus API JSON holding the
+// computed fields.
+type enrichedGeo struct {
+	Geo *enrichment `json:"geo"`
+}
+
+// MarshalEnrichedJSON returns the JSON encoding of `v` including the fields
+// computed by the library from the country code, such as `CountryName`,
+// `IsEU`, `CountryFlag`, `CountryCurrency` and `ContinentInfo`, which the
+// plain JSON encoding of `Lite`, `CoreResponse` and `Plus` leaves out. They
+// are encoded under the names used by the legacy `Core`, next to the country
+// code, with `ContinentInfo` as "continent_info".
+//
+// `v` may be a `Lite`, `CoreResponse` or `Plus`, or a pointer to one; other
+// values are encoded as by `json.Marshal`. Use `UnmarshalEnrichedJSON` to
+// decode the result.
+func MarshalEnrichedJSON(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case Lite:
+		return marshalEnrichedLite(&v)
+	case *Lite:
+		if v != nil {
+			return marshalEnrichedLite(v)
+		}
+	case CoreResponse:
+		return marshalEnrichedCore(&v)
+	case *CoreResponse:
+		if v != nil {
+			return marshalEnrichedCore(v)
+		}
+	case Plus:
+		return marshalEnrichedPlus(&v)
+	case *Plus:
+		if v != nil {
+			return marshalEnrichedPlus(v)
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalEnrichedJSON decodes JSON produced by `MarshalEnrichedJSON` into
+// `v`, restoring the computed fields from `data` rather than leaving them
+// empty as the plain JSON decoding does. `v` may be a `*Lite`,
+// `*CoreResponse` or `*Plus`; other values are decoded as by
+// `json.Unmarshal`.
+func UnmarshalEnrichedJSON(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *Lite:
+		var e enrichment
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		e.set(
+			&v.CountryName, &v.IsEU, &v.CountryFlag,
+			&v.CountryFlagURL, &v.CountryCurrency, &v.ContinentInfo,
+		)
+	case *CoreResponse:
+		var e enrichedGeo
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if g := v.Geo; g != nil && e.Geo != nil {
+			e.Geo.set(
+				&g.CountryName, &g.IsEU, &g.CountryFlag,
+				&g.CountryFlagURL, &g.CountryCurrency, &g.ContinentInfo,
+			)
+		}
+	case *Plus:
+		var e enrichedGeo
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if g := v.Geo; g != nil && e.Geo != nil {
+			e.Geo.set(
+				&g.CountryName, &g.IsEU, &g.CountryFlag,
+				&g.CountryFlagURL, &g.CountryCurrency, &g.ContinentInfo,
+			)
+		}
+	}
+	return nil
+}
+
+func marshalEnrichedLite(v *Lite) ([]byte, error) {
+	return json.Marshal(enrichedLite{
+		lite: (*lite)(v),
+		enrichment: newEnrichment(
+			v.CountryName, v.IsEU, v.CountryFlag,
+			v.CountryFlagURL, v.CountryCurrency, v.ContinentInfo,
+		),
+	})
+}
+
+func marshalEnrichedCore(v *CoreResponse) ([]byte, error) {
+	w := enrichedCoreResponse{coreResponse: (*coreResponse)(v)}
+	if g := v.Geo; g != nil {
+		w.Geo = &enrichedCoreGeo{
+			CoreGeo: g,
+			enrichment: newEnrichment(
+				g.CountryName, g.IsEU, g.CountryFlag,
+				g.CountryFlagURL, g.CountryCurrency, g.ContinentInfo,
+			),
+		}
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	return marshalWithExtra(data, v.Extra)
+}
+
+func marshalEnrichedPlus(v *Plus) ([]byte, error) {
+	w := enrichedPlus{plus: (*plus)(v)}
+	if g := v.Geo; g != nil {
+		w.Geo = &enrichedPlusGeo{
+			PlusGeo: g,
+			enrichment: newEnrichment(
+				g.CountryName, g.IsEU, g.CountryFlag,
+				g.CountryFlagURL, g.CountryCurrency, g.ContinentInfo,
+			),
+		}
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	return marshalWithExtra(data, v.Extra)
+}
@@ -0,0 +1,162 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+var enrichedLite = &ipinfo.Lite{
+	IP:              net.ParseIP("8.8.8.8"),
+	CountryCode:     "US",
+	CountryName:     "United States",
+	IsEU:            false,
+	CountryFlag:     ipinfo.CountryFlag{Emoji: "🇺🇸", Unicode: "U+1F1FA U+1F1F8"},
+	CountryFlagURL:  "https://cdn.ipinfo.io/static/images/countries-flags/US.svg",
+	CountryCurrency: ipinfo.CountryCurrency{Code: "USD", Symbol: "$"},
+	ContinentInfo:   ipinfo.Continent{Code: "NA", Name: "North America"},
+}
+
+var enrichedGeo = &ipinfo.CoreGeo{
+	City:          "Paris",
+	CountryCode:   "FR",
+	CountryName:   "France",
+	IsEU:          true,
+	CountryFlag:   ipinfo.CountryFlag{Emoji: "🇫🇷"},
+	ContinentInfo: ipinfo.Continent{Code: "EU", Name: "Europe"},
+}
+
+func TestMarshalEnrichedJSON(t *testing.T) {
+	data, err := ipinfo.MarshalEnrichedJSON(enrichedLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ip", "country_code", "country_name", "country_flag", "country_flag_url", "country_currency", "continent_info"} {
+		if _, ok := m[key]; !ok {
+			t.Errorf("no %q in %s", key, data)
+		}
+	}
+	if _, ok := m["isEU"]; ok {
+		t.Errorf("isEU = false written in %s", data)
+	}
+
+	// the plain encoding leaves the computed fields out.
+	plain, err := json.Marshal(enrichedLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "country_name") {
+		t.Errorf("plain JSON %s has computed fields", plain)
+	}
+
+	// values are encoded alike.
+	byValue, err := ipinfo.MarshalEnrichedJSON(*enrichedLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(byValue) != string(data) {
+		t.Errorf("value encoded as %s, want %s", byValue, data)
+	}
+}
+
+func TestEnrichedJSONRoundTrip(t *testing.T) {
+	core := &ipinfo.CoreResponse{IP: net.ParseIP("1.2.3.4"), Geo: enrichedGeo}
+	plus := &ipinfo.Plus{
+		IP: net.ParseIP("1.2.3.4"),
+		Geo: &ipinfo.PlusGeo{
+			City:        "Paris",
+			CountryCode: "FR",
+			CountryName: "France",
+			IsEU:        true,
+			CountryFlag: ipinfo.CountryFlag{Emoji: "🇫🇷"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		new  func() interface{}
+	}{
+		{"Lite", enrichedLite, func() interface{} { return &ipinfo.Lite{} }},
+		{"CoreResponse", core, func() interface{} { return &ipinfo.CoreResponse{} }},
+		{"Plus", plus, func() interface{} { return &ipinfo.Plus{} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ipinfo.MarshalEnrichedJSON(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tt.new()
+			if err := ipinfo.UnmarshalEnrichedJSON(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.v) {
+				t.Errorf("round trip of %s = %+v, want %+v", data, got, tt.v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalEnrichedJSONKeepsFields(t *testing.T) {
+	// computed fields in the JSON win over those computed from the country
+	// code by the plain decoding.
+	data := `{"ip": "1.2.3.4", "country_code": "US", "country_name": "USA", "continent_info": {"code": "NA", "name": "Americas"}}`
+	var lite ipinfo.Lite
+	if err := ipinfo.UnmarshalEnrichedJSON([]byte(data), &lite); err != nil {
+		t.Fatal(err)
+	}
+	if lite.CountryName != "USA" || lite.ContinentInfo.Name != "Americas" {
+		t.Errorf("Lite = %+v, want the fields of the JSON", lite)
+	}
+
+	data = `{"ip": "1.2.3.4", "geo": {"country_code": "FR", "country_name": "République française"}, "new_field": 1}`
+	var core ipinfo.CoreResponse
+	if err := ipinfo.UnmarshalEnrichedJSON([]byte(data), &core); err != nil {
+		t.Fatal(err)
+	}
+	if core.Geo.CountryName != "République française" {
+		t.Errorf("CountryName = %q", core.Geo.CountryName)
+	}
+	if _, ok := core.Extra["new_field"]; !ok {
+		t.Errorf("Extra = %v, want unknown fields retained", core.Extra)
+	}
+
+	// and the unknown fields are written back.
+	out, err := ipinfo.MarshalEnrichedJSON(&core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"new_field":1`) || !strings.Contains(string(out), "République") {
+		t.Errorf("MarshalEnrichedJSON = %s", out)
+	}
+}
+
+func TestEnrichedJSONOtherValues(t *testing.T) {
+	for _, v := range []interface{}{(*ipinfo.Lite)(nil), (*ipinfo.Plus)(nil), &ipinfo.ASNDetails{ASN: "AS1"}, nil} {
+		data, err := ipinfo.MarshalEnrichedJSON(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(v)
+		if string(data) != string(want) {
+			t.Errorf("MarshalEnrichedJSON(%#v) = %s, want %s", v, data, want)
+		}
+	}
+
+	var asn ipinfo.ASNDetails
+	if err := ipinfo.UnmarshalEnrichedJSON([]byte(`{"asn": "AS1"}`), &asn); err != nil || asn.ASN != "AS1" {
+		t.Errorf("ASNDetails = %+v, %v", asn, err)
+	}
+	if err := ipinfo.UnmarshalEnrichedJSON([]byte(`{`), &ipinfo.Lite{}); err == nil {
+		t.Error("err = nil for invalid JSON")
+	}
+}
@@ -0,0 +1,34 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"time"
+)
+
+// ExpireDetectRetry makes the next lookup of `c` retry a failed detection of
+// its tier rather than waiting.
+func (c *AutoClient) ExpireDetectRetry() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retryDetect = time.Time{}
+}
+
+// ExpireDowngrade makes the next lookup of `c` start at its detected or
+// pinned tier again after a 403.
+func (c *AutoClient) ExpireDowngrade() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restoreTier = time.Time{}
+}
+
+// RedactURL exposes `redactURL` for tests.
+var RedactURL = redactURL
+
+// BogonPrefixes returns the prefixes of `bogonNetworks`.
+func BogonPrefixes() []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(bogonNetworks))
+	for i, b := range bogonNetworks {
+		prefixes[i] = b.prefix
+	}
+	return prefixes
+}
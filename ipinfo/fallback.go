@@ -0,0 +1,166 @@
+package ipinfo
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// FallbackClient is a client which looks up IPs in several tiers in turn,
+// falling back to the next tier whenever a lookup fails, e.g. because of
+// exhausted quota or an outage.
+type FallbackClient struct {
+	// Clients for the individual tiers.
+	Lite *LiteClient
+	Core *CoreClient
+	Plus *PlusClient
+
+	// Order in which tiers are tried.
+	//
+	// nil means to try Plus, then Core, then Lite.
+	Order []Tier
+
+	// ShouldFallback reports whether a lookup which failed in `tier` with
+	// `err` should be retried in the next tier.
+	//
+	// nil means to fall back on any error.
+	ShouldFallback func(tier Tier, err error) bool
+}
+
+// TierError is the error of a lookup in a single tier.
+type TierError struct {
+	Tier Tier
+	Err  error
+}
+
+func (e *TierError) Error() string {
+	return e.Tier.String() + ": " + e.Err.Error()
+}
+
+func (e *TierError) Unwrap() error {
+	return e.Err
+}
+
+// FallbackError is reported when a lookup failed in every tier tried.
+type FallbackError struct {
+	// Errors of every tier tried, in order.
+	Errors []*TierError
+}
+
+func (e *FallbackError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "all tiers failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the error of the last tier tried.
+func (e *FallbackError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+var defaultFallbackOrder = []Tier{TierPlus, TierCore, TierLite}
+
+// NewFallbackClient creates a new client falling back from Plus to Core to
+// Lite.
+//
+// If `httpClient` is nil, `http.DefaultClient` will be used.
+//
+// If `cache` is non-nil, it is shared by the clients of all tiers.
+func NewFallbackClient(
+	httpClient *http.Client,
+	cache *Cache,
+	token string,
+) *FallbackClient {
+	return &FallbackClient{
+		Lite: NewLiteClient(httpClient, cache, token),
+		Core: NewCoreClient(httpClient, cache, token),
+		Plus: NewPlusClient(httpClient, cache, token),
+	}
+}
+
+// NewFallbackClientWithOptions returns a new client falling back from Plus to
+// Core to Lite whose clients are all configured by `opts`.
+//
+// Note that `WithBaseURL` sets the same base URL for all tiers; use `FromEnv`
+// with the per-product variables such as `IPINFO_LITE_BASE_URL`, or
+// `Reconfigure` the clients of the individual tiers instead.
+func NewFallbackClientWithOptions(opts ...Option) (*FallbackClient, error) {
+	lite, err := NewLiteClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	core, err := NewCoreClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	plus, err := NewPlusClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &FallbackClient{Lite: lite, Core: core, Plus: plus}, nil
+}
+
+// GetIPInfo returns the details for the specified IP from the first tier
+// which answers. The `Tier` and `Missing` fields of the result tell which
+// tier that was and which fields it does not provide.
+func (c *FallbackClient) GetIPInfo(ip net.IP) (*IPDetails, error) {
+	res, err := c.GetIPInfoTiered(ip)
+	if err != nil {
+		return nil, err
+	}
+	return res.Details(), nil
+}
+
+// GetIPAddrInfo is like `GetIPInfo` but takes a `netip.Addr`. An
+// `*InvalidIPError` is returned if `addr` is not a valid IP without a zone.
+func (c *FallbackClient) GetIPAddrInfo(addr netip.Addr) (*IPDetails, error) {
+	ip, err := addrIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPInfo(ip)
+}
+
+// GetIPStrInfo is like `GetIPInfo` but takes a string. An `*InvalidIPError`
+// is returned if `ip` is not a valid IP.
+func (c *FallbackClient) GetIPStrInfo(ip string) (*IPDetails, error) {
+	parsed, err := parseIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPInfo(parsed)
+}
+
+// GetIPInfoTiered is like `GetIPInfo` but returns the response of the tier
+// which answered as is.
+func (c *FallbackClient) GetIPInfoTiered(ip net.IP) (*TierResult, error) {
+	// an invalid IP fails the same way in every tier.
+	if _, err := validIP(ip); err != nil {
+		return nil, err
+	}
+
+	order := c.Order
+	if order == nil {
+		order = defaultFallbackOrder
+	}
+
+	fallbackErr := &FallbackError{}
+	for _, tier := range order {
+		res, err := lookupTier(tier, c.Lite, c.Core, c.Plus, ip)
+		if err == nil {
+			return res, nil
+		}
+
+		fallbackErr.Errors = append(fallbackErr.Errors, &TierError{Tier: tier, Err: err})
+		if c.ShouldFallback != nil && !c.ShouldFallback(tier, err) {
+			break
+		}
+	}
+	return nil, fallbackErr
+}
@@ -0,0 +1,165 @@
+package ipinfo_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `fallbackClient` returns a `FallbackClient` whose clients are pointed at
+// `srv`.
+func fallbackClient(srv *ipinfotest.Server) *ipinfo.FallbackClient {
+	return &ipinfo.FallbackClient{
+		Lite: srv.LiteClient(),
+		Core: srv.CoreClient(),
+		Plus: srv.PlusClient(),
+	}
+}
+
+func TestFallbackClient(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetCore("8.8.8.8", &ipinfo.CoreResponse{
+		IP:  net.ParseIP("8.8.8.8"),
+		Geo: &ipinfo.CoreGeo{City: "Mountain View"},
+	})
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusTooManyRequests, Times: 1})
+
+	c := fallbackClient(srv)
+	details, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Tier != ipinfo.TierCore {
+		t.Errorf("Tier = %v, want %v", details.Tier, ipinfo.TierCore)
+	}
+	if details.Geo == nil || details.Geo.City != "Mountain View" {
+		t.Errorf("Geo = %+v, want the Core response", details.Geo)
+	}
+	if !reflect.DeepEqual(details.Missing, ipinfo.TierCore.MissingFields()) {
+		t.Errorf("Missing = %q, want the fields Core lacks", details.Missing)
+	}
+	if n := countRequests(srv, "/lookup/8.8.8.8"); n != 2 {
+		t.Errorf("Plus and Core queried %d times, want 2", n)
+	}
+}
+
+func TestFallbackClientOrder(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	c := fallbackClient(srv)
+	c.Order = []ipinfo.Tier{ipinfo.TierLite, ipinfo.TierPlus}
+	res, err := c.GetIPInfoTiered(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierLite || res.Lite == nil || res.Core != nil || res.Plus != nil {
+		t.Errorf("result = %+v, want a Lite result only", res)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 1 || reqs[0].Path != "/lite/8.8.8.8" {
+		t.Errorf("requests = %+v, want one to Lite", reqs)
+	}
+}
+
+func TestFallbackClientAllFail(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	_, err := fallbackClient(srv).GetIPInfo(net.ParseIP("8.8.8.8"))
+	var fallbackErr *ipinfo.FallbackError
+	if !errors.As(err, &fallbackErr) {
+		t.Fatalf("err = %v, want a FallbackError", err)
+	}
+	var tiers []ipinfo.Tier
+	for _, tierErr := range fallbackErr.Errors {
+		tiers = append(tiers, tierErr.Tier)
+	}
+	if want := []ipinfo.Tier{ipinfo.TierPlus, ipinfo.TierCore, ipinfo.TierLite}; !reflect.DeepEqual(tiers, want) {
+		t.Errorf("tiers tried = %v, want %v", tiers, want)
+	}
+
+	// the error unwraps to that of the last tier.
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.Request.URL.Path != "/lite/8.8.8.8" {
+		t.Errorf("err = %v, want the Lite error response", err)
+	}
+}
+
+func TestFallbackClientShouldFallback(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusNotFound})
+
+	c := fallbackClient(srv)
+	var calls []ipinfo.Tier
+	c.ShouldFallback = func(tier ipinfo.Tier, err error) bool {
+		calls = append(calls, tier)
+		var errResp *ipinfo.ErrorResponse
+		return !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusNotFound
+	}
+	_, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	var fallbackErr *ipinfo.FallbackError
+	if !errors.As(err, &fallbackErr) || len(fallbackErr.Errors) != 1 {
+		t.Fatalf("err = %v, want the Plus error only", err)
+	}
+	if !reflect.DeepEqual(calls, []ipinfo.Tier{ipinfo.TierPlus}) {
+		t.Errorf("ShouldFallback called for %v", calls)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestFallbackClientInvalidIP(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	_, err := fallbackClient(srv).GetIPInfo(net.IP{1, 2, 3})
+	var invalid *ipinfo.InvalidIPError
+	if !errors.As(err, &invalid) {
+		t.Errorf("err = %v, want an InvalidIPError", err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests for an invalid IP", n)
+	}
+}
+
+func TestNewFallbackClientWithOptions(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	t.Setenv("IPINFO_LITE_BASE_URL", srv.URL+"/lite")
+	t.Setenv("IPINFO_CORE_BASE_URL", srv.URL+"/lookup")
+	t.Setenv("IPINFO_PLUS_BASE_URL", srv.URL+"/lookup")
+
+	c, err := ipinfo.NewFallbackClientWithOptions(ipinfo.FromEnv(), ipinfo.WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v, want %v", details.Tier, ipinfo.TierLite)
+	}
+	for _, req := range srv.Requests() {
+		if auth := req.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("%s: Authorization = %q", req.Path, auth)
+		}
+	}
+
+	if _, err := ipinfo.NewFallbackClientWithOptions(ipinfo.WithTimeout(-1)); err == nil {
+		t.Error("err = nil for an invalid option")
+	}
+}
@@ -0,0 +1,208 @@
+package format
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Fields is a formatter which writes selected fields of results, separated
+// by `Separator`.
+//
+// Fields are selected by dotted paths of their JSON names, e.g. "geo.city".
+// Names match case-insensitively, and Go field names are accepted as well,
+// so that fields without a JSON name such as `CountryName` can be selected.
+// Values along a path which are nil are written as empty strings.
+type Fields struct {
+	// Separator written between values. `NewFields` sets it to a tab.
+	Separator string
+
+	paths [][]string
+}
+
+// NewFields returns a formatter for `list`, a comma-separated list of
+// dotted field paths. See `New` for `typ`.
+func NewFields(list string, typ interface{}) (*Fields, error) {
+	f := &Fields{Separator: "\t"}
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, fmt.Errorf("format: empty field in list %q", list)
+		}
+		f.paths = append(f.paths, strings.Split(path, "."))
+	}
+
+	if t := elemType(typ); t != nil {
+		for _, path := range f.paths {
+			if err := checkFieldPath(t, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// Names returns the paths of the selected fields, e.g. for a header.
+func (f *Fields) Names() []string {
+	names := make([]string, len(f.paths))
+	for i, path := range f.paths {
+		names[i] = strings.Join(path, ".")
+	}
+	return names
+}
+
+// Format writes the selected fields of `v` to `w`.
+func (f *Fields) Format(w io.Writer, v interface{}) error {
+	return each(v, func(v interface{}) error {
+		values := make([]string, len(f.paths))
+		for i, path := range f.paths {
+			fv, err := fieldValue(reflect.ValueOf(v), path)
+			if err != nil {
+				return err
+			}
+			if values[i], err = formatValue(fv); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, strings.Join(values, f.Separator)+"\n")
+		return err
+	})
+}
+
+// `checkFieldPath` checks that `path` resolves to a field of `t`. Paths
+// through interfaces and maps can't be checked beyond them.
+func checkFieldPath(t reflect.Type, path []string) error {
+	root := t
+	for i, name := range path {
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Interface, reflect.Map:
+			return nil
+		case reflect.Struct:
+			sf, ok := findField(t, name)
+			if !ok {
+				return &FieldError{Path: strings.Join(path[:i+1], "."), Type: root}
+			}
+			t = sf.Type
+		default:
+			return &FieldError{Path: strings.Join(path[:i+1], "."), Type: root}
+		}
+	}
+	return nil
+}
+
+// `fieldValue` returns the value of the field at `path` in `v`, or an
+// invalid value if a value along the path is nil.
+func fieldValue(v reflect.Value, path []string) (reflect.Value, error) {
+	for i, name := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				break
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return v, nil
+			}
+			continue
+		case reflect.Struct:
+			if sf, ok := findField(v.Type(), name); ok {
+				v = v.FieldByIndex(sf.Index)
+				continue
+			}
+		}
+		return reflect.Value{}, &FieldError{
+			Path: strings.Join(path[:i+1], "."),
+			Type: v.Type(),
+		}
+	}
+	return v, nil
+}
+
+// `findField` finds the exported field of the struct type `t` named `name`,
+// by its JSON name or Go name, preferring an exact match.
+func findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	var fold reflect.StructField
+	folded := false
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			jsonName = ""
+		}
+		if jsonName == name || sf.Name == name {
+			return sf, true
+		}
+		if !folded && (strings.EqualFold(jsonName, name) || strings.EqualFold(sf.Name, name)) {
+			fold, folded = sf, true
+		}
+	}
+	return fold, folded
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// `formatValue` formats a selected value. Scalars are written as text,
+// slices of scalars joined with commas, and anything else as JSON.
+func formatValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return "", nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		k := indirectType(v.Type().Elem()).Kind()
+		if k == reflect.Struct || k == reflect.Map || k == reflect.Slice {
+			break
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			s, err := formatValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	return string(data), err
+}
@@ -0,0 +1,127 @@
+// Package format renders results of the `ipinfo` package as lines of text,
+// either with a `text/template` or by selecting fields with dotted paths.
+//
+// A template refers to fields by their Go names:
+//
+//	f, err := format.New("{{.IP}} {{.Country}} {{.ASN.ASN}}", (*ipinfo.Core)(nil))
+//
+// A field list refers to fields by their JSON names, separated by commas,
+// and renders their values separated by tabs:
+//
+//	f, err := format.New("ip,geo.city,as.asn", (*ipinfo.CoreResponse)(nil))
+//
+// Field references are validated against the type of the results given to
+// `New`, so that typos are reported before any lookup is made.
+package format
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Formatter renders results as lines of text.
+type Formatter interface {
+	// Format writes `v` to `w`, followed by a newline. If `v` is a batch
+	// result, i.e. a map such as `ipinfo.BatchCore`, or a slice, each of its
+	// elements is written instead, maps sorted by key.
+	Format(w io.Writer, v interface{}) error
+}
+
+// New returns a formatter for `spec`, which is a `text/template` if it
+// contains "{{", and a field list otherwise.
+//
+// `typ` is a value of the type of the results to be formatted, e.g.
+// `(*ipinfo.Core)(nil)` or `ipinfo.BatchCore(nil)`, against which the fields
+// referenced by `spec` are validated. A nil `typ` skips validation.
+func New(spec string, typ interface{}) (Formatter, error) {
+	if strings.Contains(spec, "{{") {
+		return NewTemplate(spec, typ)
+	}
+	return NewFields(spec, typ)
+}
+
+// FieldError reports a reference to a field which doesn't exist.
+type FieldError struct {
+	// Path of the field as written, e.g. "geo.cty".
+	Path string
+
+	// Type in which the path was resolved.
+	Type reflect.Type
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("format: no field %q in %s", e.Path, e.Type)
+}
+
+// `elemType` returns the type of the results given by `typ`: the element type
+// of batch results, or the type itself. It returns nil if `typ` is nil.
+func elemType(typ interface{}) reflect.Type {
+	t := reflect.TypeOf(typ)
+	if t == nil {
+		return nil
+	}
+	if isBatchType(t) {
+		return t.Elem()
+	}
+	return t
+}
+
+func isBatchType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		// byte slices such as `net.IP` are single values.
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// `each` calls `fn` for `v`, or for each element of `v` if it's a batch
+// result. Nil elements are skipped.
+func each(v interface{}, fn func(v interface{}) error) error {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !isBatchType(rv.Type()) {
+		return fn(v)
+	}
+
+	var elems []reflect.Value
+	if rv.Kind() == reflect.Map {
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			elems = append(elems, rv.MapIndex(k))
+		}
+	} else {
+		for i := 0; i < rv.Len(); i++ {
+			elems = append(elems, rv.Index(i))
+		}
+	}
+
+	for _, e := range elems {
+		switch e.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if e.IsNil() {
+				continue
+			}
+		}
+		if err := fn(e.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// `indirectType` returns the type `t` points to, through any number of
+// pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
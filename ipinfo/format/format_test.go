@@ -0,0 +1,189 @@
+package format_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/format"
+)
+
+var core = &ipinfo.CoreResponse{
+	IP:  net.ParseIP("8.8.8.8"),
+	Geo: &ipinfo.CoreGeo{City: "Mountain View", CountryCode: "US"},
+	AS:  &ipinfo.CoreAS{ASN: "AS15169", Name: "Google LLC"},
+}
+
+// `render` formats `v` with `spec`, validated against the type of `v`.
+func render(t *testing.T, spec string, v interface{}) string {
+	t.Helper()
+	f, err := format.New(spec, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		spec string
+		v    interface{}
+		want string
+	}{
+		{"ip,geo.city,as.asn", core, "8.8.8.8\tMountain View\tAS15169\n"},
+		// names match case-insensitively, and Go names are accepted.
+		{"IP, Geo.City, AS.Name", core, "8.8.8.8\tMountain View\tGoogle LLC\n"},
+		{"geo.country_code,is_anycast", core, "US\tfalse\n"},
+		// nil values along a path are empty.
+		{"ip,privacy.vpn", &ipinfo.Core{IP: net.ParseIP("1.1.1.1")}, "1.1.1.1\t\n"},
+		{"CountryName", &ipinfo.Lite{CountryName: "France"}, "France\n"},
+		{"peers", &ipinfo.ASNDetails{Peers: []string{"AS1", "AS2"}}, "AS1,AS2\n"},
+		{"prefixes", &ipinfo.ASNDetails{Prefixes: []ipinfo.ASNDetailsPrefix{{Netblock: "8.8.8.0/24"}}}, `[{"netblock":"8.8.8.0/24","id":"","name":"","country":"","size":"","status":"","domain":""}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := render(t, tt.spec, tt.v); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldsNames(t *testing.T) {
+	f, err := format.NewFields("ip, geo.city", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := f.Names(); len(names) != 2 || names[0] != "ip" || names[1] != "geo.city" {
+		t.Errorf("Names = %q", names)
+	}
+	f.Separator = ","
+	var buf bytes.Buffer
+	if err := f.Format(&buf, core); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "8.8.8.8,Mountain View\n" {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		spec string
+		v    interface{}
+		want string
+	}{
+		{"{{.IP}} {{.Geo.City}} {{.AS.ASN}}", core, "8.8.8.8 Mountain View AS15169\n"},
+		{"{{.IP}}\n", core, "8.8.8.8\n"},
+		{"{{with .ASN}}{{.Name}}{{else}}none{{end}}", &ipinfo.Core{}, "none\n"},
+		{"{{range .Peers}}{{.}};{{end}}", &ipinfo.ASNDetails{Peers: []string{"AS1", "AS2"}}, "AS1;AS2;\n"},
+		{"{{.Geo.CountryCode}} {{$.IP}}", core, "US 8.8.8.8\n"},
+		// methods are accepted as well as fields.
+		{"{{.IP.String}}", core, "8.8.8.8\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := render(t, tt.spec, tt.v); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	batch := ipinfo.BatchCore{
+		"8.8.8.8": {IP: net.ParseIP("8.8.8.8")},
+		"1.1.1.1": {IP: net.ParseIP("1.1.1.1")},
+		"9.9.9.9": nil,
+	}
+	for _, spec := range []string{"ip", "{{.IP}}"} {
+		if got := render(t, spec, batch); got != "1.1.1.1\n8.8.8.8\n" {
+			t.Errorf("%s: output = %q, want the results sorted by key", spec, got)
+		}
+	}
+
+	// slices are written element by element, but a net.IP is a value.
+	results := []*ipinfo.Lite{{ASN: "AS1"}, nil, {ASN: "AS2"}}
+	if got := render(t, "asn", results); got != "AS1\nAS2\n" {
+		t.Errorf("output = %q", got)
+	}
+	if got := render(t, "{{.}}", net.ParseIP("8.8.8.8")); got != "8.8.8.8\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		spec string
+		typ  interface{}
+		path string
+	}{
+		{"ip,geo.cty", (*ipinfo.CoreResponse)(nil), "geo.cty"},
+		{"jsn", (*ipinfo.Core)(nil), "jsn"},
+		{"city.name", (*ipinfo.Core)(nil), "city.name"},
+		{"asn", ipinfo.BatchCore(nil), ""},
+		{"{{.Geo.Cty}}", (*ipinfo.CoreResponse)(nil), ".Geo.Cty"},
+		{"{{if .Bogus}}x{{end}}", (*ipinfo.Core)(nil), ".Bogus"},
+		{"{{with .ASN}}{{$.Nope}}{{end}}", (*ipinfo.Core)(nil), ".Nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := format.New(tt.spec, tt.typ)
+			if tt.path == "" {
+				if err != nil {
+					t.Errorf("err = %v for a valid spec", err)
+				}
+				return
+			}
+			var fe *format.FieldError
+			if !errors.As(err, &fe) || fe.Path != tt.path {
+				t.Errorf("err = %v, want a FieldError for %q", err, tt.path)
+			}
+		})
+	}
+
+	// fields within `with` and `range`, and of maps, can't be checked.
+	for _, spec := range []string{"{{with .ASN}}{{.Whatever}}{{end}}", "extra.anything"} {
+		if _, err := format.New(spec, (*ipinfo.Core)(nil)); err != nil {
+			t.Errorf("%s: err = %v", spec, err)
+		}
+	}
+	// nor can anything without a type.
+	if _, err := format.New("jsn", nil); err != nil {
+		t.Errorf("err = %v without a type", err)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for _, spec := range []string{"ip,,asn", "", "{{.IP", "{{end}}"} {
+		if _, err := format.New(spec, nil); err == nil {
+			t.Errorf("%q: err = nil", spec)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	// unchecked paths fail when rendered.
+	f, err := format.New("ip.host", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fe *format.FieldError
+	if err := f.Format(&bytes.Buffer{}, core); !errors.As(err, &fe) {
+		t.Errorf("err = %v, want a FieldError", err)
+	}
+
+	f, err = format.New("{{.Nope}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Format(&bytes.Buffer{}, core); err == nil {
+		t.Error("err = nil for an unknown template field")
+	}
+}
@@ -0,0 +1,155 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template is a formatter which renders results with a `text/template`.
+//
+// Templates refer to fields by their Go names, e.g. `{{.ASN.ASN}}`. As with
+// any template, evaluating a field through a nil pointer fails; use `with`
+// for optional parts, e.g. `{{with .ASN}}{{.ASN}}{{end}}`.
+type Template struct {
+	tmpl    *template.Template
+	newline bool
+}
+
+// NewTemplate returns a formatter rendering results with the template
+// `text`. A newline is written after each result unless `text` ends with one.
+// See `New` for `typ`.
+//
+// Fields referenced relative to the result, i.e. outside of `with` and
+// `range` actions, or through `$`, are validated against `typ`.
+func NewTemplate(text string, typ interface{}) (*Template, error) {
+	tmpl, err := template.New("format").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("format: %w", err)
+	}
+
+	if t := elemType(typ); t != nil {
+		c := &templateChecker{root: t}
+		if err := c.walk(tmpl.Tree.Root, t); err != nil {
+			return nil, err
+		}
+	}
+	return &Template{tmpl: tmpl, newline: !strings.HasSuffix(text, "\n")}, nil
+}
+
+// Format renders `v` to `w`.
+func (t *Template) Format(w io.Writer, v interface{}) error {
+	return each(v, func(v interface{}) error {
+		if err := t.tmpl.Execute(w, v); err != nil {
+			return fmt.Errorf("format: %w", err)
+		}
+		if t.newline {
+			_, err := io.WriteString(w, "\n")
+			return err
+		}
+		return nil
+	})
+}
+
+// templateChecker validates the field references of a template against the
+// type of its data.
+type templateChecker struct {
+	root reflect.Type
+}
+
+// `walk` checks `node`, in which dot has type `dot`, or an unknown type if
+// `dot` is nil.
+func (c *templateChecker) walk(node parse.Node, dot reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.walk(child, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return c.walk(n.Pipe, dot)
+	case *parse.IfNode:
+		return c.walkBranch(&n.BranchNode, dot, dot)
+	case *parse.WithNode:
+		return c.walkBranch(&n.BranchNode, dot, nil)
+	case *parse.RangeNode:
+		return c.walkBranch(&n.BranchNode, dot, nil)
+	case *parse.TemplateNode:
+		return c.walk(n.Pipe, dot)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := c.walk(arg, dot); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.ChainNode:
+		return c.walk(n.Node, dot)
+	case *parse.FieldNode:
+		if dot != nil {
+			return c.check(dot, n.Ident)
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			return c.check(c.root, n.Ident[1:])
+		}
+	}
+	return nil
+}
+
+// `walkBranch` checks an if, with or range action, whose body has a dot of
+// type `inner`.
+func (c *templateChecker) walkBranch(
+	n *parse.BranchNode,
+	dot reflect.Type,
+	inner reflect.Type,
+) error {
+	if err := c.walk(n.Pipe, dot); err != nil {
+		return err
+	}
+	if err := c.walk(n.List, inner); err != nil {
+		return err
+	}
+	return c.walk(n.ElseList, dot)
+}
+
+// `check` checks that the chain of field or method names `idents` resolves
+// in `t`.
+func (c *templateChecker) check(t reflect.Type, idents []string) error {
+	for i, name := range idents {
+		if t.Kind() != reflect.Interface {
+			m, ok := t.MethodByName(name)
+			if !ok && t.Kind() != reflect.Ptr {
+				m, ok = reflect.PtrTo(t).MethodByName(name)
+			}
+			if ok && m.Type.NumOut() > 0 {
+				t = m.Type.Out(0)
+				continue
+			}
+		}
+
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Interface, reflect.Map:
+			return nil
+		case reflect.Struct:
+			if sf, ok := t.FieldByName(name); ok && sf.PkgPath == "" {
+				t = sf.Type
+				continue
+			}
+		}
+		return &FieldError{Path: "." + strings.Join(idents[:i+1], "."), Type: c.root}
+	}
+	return nil
+}
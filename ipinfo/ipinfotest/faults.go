@@ -0,0 +1,245 @@
+package ipinfotest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+// FaultRule describes a fault which a `FaultInjector` injects into matching
+// requests. A single rule may combine several faults, e.g. latency followed
+// by a truncated body.
+type FaultRule struct {
+	// Path prefix of the requests the rule applies to, e.g. "/lite/"; empty
+	// means all requests.
+	Path string
+
+	// Rate is the probability, between 0 and 1, that the rule applies to a
+	// matching request.
+	//
+	// 0 means to apply to every matching request.
+	Rate float64
+
+	// Times is the maximum number of requests the rule applies to.
+	//
+	// 0 means no limit.
+	Times int
+
+	// Latency added before the request is sent. It is cut short if the
+	// request is canceled.
+	Latency time.Duration
+
+	// Reset fails the request with a connection reset error instead of
+	// sending it.
+	Reset bool
+
+	// Status, if non-zero, answers the request with this status code and
+	// `Body` instead of sending it.
+	Status int
+
+	// Body of responses synthesized for `Status`.
+	Body string
+
+	// Header is added to the response, replacing existing values, e.g. to
+	// simulate `Retry-After` or rate limit headers.
+	Header http.Header
+
+	// RemoveHeader lists headers removed from the response.
+	RemoveHeader []string
+
+	// Truncate cuts the response body in half, after which reading it fails
+	// with `io.ErrUnexpectedEOF`.
+	Truncate bool
+}
+
+// FaultInjector is an `http.RoundTripper` which makes requests misbehave
+// according to its rules, for testing retry, fallback and circuit breaking
+// code:
+//
+//	faults := ipinfotest.NewFaultInjector(1,
+//		ipinfotest.FaultRule{Rate: 0.2, Status: http.StatusServiceUnavailable},
+//		ipinfotest.FaultRule{Path: "/batch", Rate: 0.1, Reset: true},
+//	)
+//	client := ipinfo.NewClient(faults.HTTPClient(), nil, token)
+//
+// For each request, the first matching rule which fires is applied. Whether
+// a rule fires is decided by a random source seeded with the seed given, so
+// that a sequence of requests sees the same faults on every run.
+type FaultInjector struct {
+	// Transport used to send requests; nil means `http.DefaultTransport`.
+	Transport http.RoundTripper
+
+	rules []FaultRule
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	applied  []int
+	injected int
+}
+
+// NewFaultInjector returns a fault injector applying `rules`, seeded with
+// `seed`.
+func NewFaultInjector(seed int64, rules ...FaultRule) *FaultInjector {
+	return &FaultInjector{
+		rules:   rules,
+		rng:     rand.New(rand.NewSource(seed)),
+		applied: make([]int, len(rules)),
+	}
+}
+
+// HTTPClient returns an HTTP client which sends requests through `f`.
+func (f *FaultInjector) HTTPClient() *http.Client {
+	return &http.Client{Transport: f}
+}
+
+// Middleware returns `f` as middleware around `next`, for use with
+// `ipinfo.WithMiddleware`. The returned transport shares the rules and
+// random source of `f`.
+func (f *FaultInjector) Middleware(next http.RoundTripper) http.RoundTripper {
+	return ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return f.roundTrip(next, req)
+	})
+}
+
+// Injected returns the number of requests faults were injected into.
+func (f *FaultInjector) Injected() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.injected
+}
+
+// RoundTrip sends `req`, injecting faults according to the rules of `f`.
+func (f *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f.roundTrip(f.Transport, req)
+}
+
+func (f *FaultInjector) roundTrip(
+	next http.RoundTripper,
+	req *http.Request,
+) (*http.Response, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	rule := f.pick(req.URL.Path)
+	if rule == nil {
+		return next.RoundTrip(req)
+	}
+
+	if rule.Latency > 0 {
+		timer := time.NewTimer(rule.Latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	if rule.Reset {
+		closeBody(req)
+		return nil, &net.OpError{
+			Op:  "read",
+			Net: "tcp",
+			Err: os.NewSyscallError("read", syscall.ECONNRESET),
+		}
+	}
+
+	var resp *http.Response
+	if rule.Status != 0 {
+		closeBody(req)
+		resp = &http.Response{
+			Status:        fmt.Sprintf("%d %s", rule.Status, http.StatusText(rule.Status)),
+			StatusCode:    rule.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			Body:          io.NopCloser(strings.NewReader(rule.Body)),
+			ContentLength: int64(len(rule.Body)),
+			Request:       req,
+		}
+	} else {
+		var err error
+		if resp, err = next.RoundTrip(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, vs := range rule.Header {
+		resp.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	for _, k := range rule.RemoveHeader {
+		resp.Header.Del(k)
+	}
+
+	if rule.Truncate {
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(io.MultiReader(
+			bytes.NewReader(data[:len(data)/2]),
+			errReader{io.ErrUnexpectedEOF},
+		))
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+	}
+
+	return resp, nil
+}
+
+// `pick` returns the rule to apply to a request for `path`, or nil.
+func (f *FaultInjector) pick(path string) *FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !strings.HasPrefix(path, rule.Path) {
+			continue
+		}
+		if rule.Times > 0 && f.applied[i] >= rule.Times {
+			continue
+		}
+		if rule.Rate > 0 && f.rng.Float64() >= rule.Rate {
+			continue
+		}
+		f.applied[i]++
+		f.injected++
+		return rule
+	}
+	return nil
+}
+
+// `closeBody` closes the body of a request which is not sent, as a
+// transport must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// errReader is a reader which always fails with its error.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// Check if FaultInjector implements http.RoundTripper
+var _ http.RoundTripper = (*FaultInjector)(nil)
@@ -0,0 +1,186 @@
+package ipinfotest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `faultClient` returns a legacy API client for `srv` sending requests
+// through `f`.
+func faultClient(srv *ipinfotest.Server, f *ipinfotest.FaultInjector, opts ...ipinfo.Option) *ipinfo.Client {
+	f.Transport = srv.Server.Client().Transport
+	return srv.Client(append([]ipinfo.Option{ipinfo.WithHTTPClient(f.HTTPClient())}, opts...)...)
+}
+
+func TestFaultInjectorStatus(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{
+		Status: http.StatusServiceUnavailable,
+		Body:   `{"error":"down"}`,
+		Times:  1,
+	})
+	client := faultClient(srv, faults)
+	_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want a 503 error response", err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v after the rule was used up", err)
+	}
+
+	// synthesized responses aren't sent.
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+	if n := faults.Injected(); n != 1 {
+		t.Errorf("Injected = %d, want 1", n)
+	}
+}
+
+func TestFaultInjectorReset(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{Path: "/batch", Reset: true})
+	client := faultClient(srv, faults)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v outside the path of the rule", err)
+	}
+	_, err := client.GetIPInfoBatch([]net.IP{net.ParseIP("8.8.8.8")}, ipinfo.BatchReqOpts{})
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("err = %v, want a connection reset", err)
+	}
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{Latency: time.Minute})
+	client := faultClient(srv, faults, ipinfo.WithTimeout(20*time.Millisecond))
+	start := time.Now()
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("latency not cut short: %v", d)
+	}
+}
+
+func TestFaultInjectorHeaders(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	header := http.Header{}
+	header.Set("X-RateLimit-Remaining", "3")
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{
+		Header:       header,
+		RemoveHeader: []string{"Content-Type"},
+	})
+	_, meta, err := faultClient(srv, faults).GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Header.Get("X-RateLimit-Remaining") != "3" || meta.Header.Get("Content-Type") != "" {
+		t.Errorf("headers = %v", meta.Header)
+	}
+	if meta.RateLimit == nil || meta.RateLimit.Remaining != 3 {
+		t.Errorf("RateLimit = %+v, want the injected header parsed", meta.RateLimit)
+	}
+}
+
+func TestFaultInjectorTruncate(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{Truncate: true})
+	_, err := faultClient(srv, faults).GetIPInfo(net.ParseIP("8.8.8.8"))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestFaultInjectorRate(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	// the same seed injects the same faults.
+	run := func() []bool {
+		faults := ipinfotest.NewFaultInjector(42, ipinfotest.FaultRule{
+			Rate:   0.5,
+			Status: http.StatusBadGateway,
+		})
+		client := faultClient(srv, faults)
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+	first, second := run(), run()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("faults differ between runs: %v and %v", first, second)
+	}
+	n := 0
+	for _, f := range first {
+		if f {
+			n++
+		}
+	}
+	if n == 0 || n == len(first) {
+		t.Errorf("%d of %d requests failed, want some", n, len(first))
+	}
+}
+
+func TestFaultInjectorFirstRule(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	faults := ipinfotest.NewFaultInjector(1,
+		ipinfotest.FaultRule{Path: "/8.8.8.8", Status: http.StatusBadGateway},
+		ipinfotest.FaultRule{Status: http.StatusServiceUnavailable},
+	)
+	client := faultClient(srv, faults)
+	var errResp *ipinfo.ErrorResponse
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusBadGateway {
+		t.Errorf("err = %v, want the first matching rule", err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want the second rule", err)
+	}
+}
+
+func TestFaultInjectorMiddleware(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	faults := ipinfotest.NewFaultInjector(1, ipinfotest.FaultRule{Status: http.StatusTooManyRequests, Times: 1})
+	client := srv.LiteClient(
+		ipinfo.WithMiddleware(faults.Middleware),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if n := faults.Injected(); n != 1 {
+		t.Errorf("Injected = %d, want 1", n)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want the retry only", n)
+	}
+}
@@ -0,0 +1,335 @@
+package ipinfotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mode is the mode of a `Recorder`.
+type Mode int
+
+const (
+	// ModeReplay serves requests from the fixture file, failing requests
+	// which have no recorded counterpart.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests to the API and records them, to be written
+	// to the fixture file by `Save`.
+	ModeRecord
+)
+
+// Recorder is an `http.RoundTripper` which records API interactions to a
+// fixture file and replays them, for deterministic tests without network
+// access:
+//
+//	rec, err := ipinfotest.NewRecorder("testdata/lookup.json", ipinfotest.ModeReplay)
+//	...
+//	client, err := ipinfo.NewClientWithOptions(
+//		ipinfo.WithHTTPClient(rec.HTTPClient()),
+//		ipinfo.WithToken(os.Getenv("IPINFO_TOKEN")),
+//	)
+//
+// Requests are matched by method, path and body. JSON array bodies, as sent
+// by batch requests, match regardless of the order of their elements, since
+// the client chunks and sends them concurrently. Interactions recorded for
+// the same request are replayed in order, the last one repeatedly.
+//
+// Tokens are never recorded: request headers are not stored, and the token
+// is redacted from URLs and response bodies.
+type Recorder struct {
+	// Transport used to send requests when recording; nil means
+	// `http.DefaultTransport`.
+	Transport http.RoundTripper
+
+	mode Mode
+	path string
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	replayed     map[string]int
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request recorded by a `Recorder`.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response recorded by a `Recorder`.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+type fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// UnmatchedRequestError is returned by a replaying `Recorder` for requests
+// without a recorded counterpart.
+type UnmatchedRequestError struct {
+	Method string
+	Path   string
+	Body   string
+
+	// Requests which were recorded, as "METHOD path" strings.
+	Recorded []string
+}
+
+func (e *UnmatchedRequestError) Error() string {
+	return fmt.Sprintf(
+		"ipinfotest: no recorded interaction for %s %s with body %q; recorded: %s",
+		e.Method, e.Path, e.Body, strings.Join(e.Recorded, ", "),
+	)
+}
+
+// NewRecorder returns a recorder using the fixture file at `path`. In
+// `ModeReplay`, the file is loaded immediately and must exist.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		mode:     mode,
+		path:     path,
+		replayed: make(map[string]int),
+	}
+	if mode != ModeReplay {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("ipinfotest: invalid fixture %s: %w", path, err)
+	}
+	r.interactions = f.Interactions
+	return r, nil
+}
+
+// HTTPClient returns an HTTP client which sends requests through `r`.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]Interaction, len(r.interactions))
+	for i, in := range r.interactions {
+		res[i] = *in
+	}
+	return res
+}
+
+// Save writes the recorded interactions to the fixture file. It does
+// nothing in `ModeReplay`.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(fixture{Interactions: r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// RoundTrip records or replays `req` depending on the mode of `r`.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	rt := r.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := rt.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	token := requestToken(req)
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	in := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    redact(redactQuery(req.URL.String()), token),
+			Body:   redact(string(body), token),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       redact(string(respBody), token),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, in)
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := matchKey(req.Method, req.URL.Path, string(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []*Interaction
+	for _, in := range r.interactions {
+		if interactionKey(in) == key {
+			matches = append(matches, in)
+		}
+	}
+	if len(matches) == 0 {
+		recorded := make([]string, len(r.interactions))
+		for i, in := range r.interactions {
+			recorded[i] = in.Request.Method + " " + interactionPath(in)
+		}
+		return nil, &UnmatchedRequestError{
+			Method:   req.Method,
+			Path:     req.URL.Path,
+			Body:     string(body),
+			Recorded: recorded,
+		}
+	}
+
+	n := r.replayed[key]
+	r.replayed[key]++
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	in := matches[n]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// `interactionKey` returns the match key of a recorded interaction.
+func interactionKey(in *Interaction) string {
+	return matchKey(in.Request.Method, interactionPath(in), in.Request.Body)
+}
+
+// `interactionPath` returns the path of the URL of a recorded interaction.
+func interactionPath(in *Interaction) string {
+	u := in.Request.URL
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+		if j := strings.IndexByte(u, '/'); j >= 0 {
+			u = u[j:]
+		} else {
+			u = "/"
+		}
+	}
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		u = u[:i]
+	}
+	return u
+}
+
+// `matchKey` returns the key by which requests are matched. JSON array
+// bodies are normalized to sorted order.
+func matchKey(method, path, body string) string {
+	var elems []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &elems); err == nil {
+		keys := make([]string, len(elems))
+		for i, e := range elems {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, e); err != nil {
+				keys[i] = string(e)
+			} else {
+				keys[i] = buf.String()
+			}
+		}
+		sort.Strings(keys)
+		body = "[" + strings.Join(keys, ",") + "]"
+	}
+	return method + " " + path + "\n" + body
+}
+
+// `requestToken` returns the token `req` is authorized with, if any.
+func requestToken(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return req.URL.Query().Get("token")
+}
+
+// `redactQuery` replaces any token in the query of `rawURL`.
+func redactQuery(rawURL string) string {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL
+	}
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		if strings.HasPrefix(p, "token=") {
+			params[i] = "token=REDACTED"
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// `redact` replaces all occurrences of `token` in `s`.
+func redact(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, "REDACTED")
+}
+
+// Check if Recorder implements http.RoundTripper
+var _ http.RoundTripper = (*Recorder)(nil)
@@ -0,0 +1,199 @@
+package ipinfotest_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `recorderClient` returns a legacy API client for `srv` sending requests
+// through `rec`.
+func recorderClient(t *testing.T, srv *ipinfotest.Server, rec *ipinfotest.Recorder) *ipinfo.Client {
+	t.Helper()
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithHTTPClient(rec.HTTPClient()),
+		ipinfo.WithBaseURL(srv.URL),
+		ipinfo.WithToken("secret"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRecorder(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	rec, err := ipinfotest.NewRecorder(path, ipinfotest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := recorderClient(t, srv, rec)
+	ips := []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1")}
+	if _, err := client.GetIPInfo(ips[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Interactions()); n != 2 {
+		t.Fatalf("%d interactions recorded, want 2", n)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("fixture contains the token:\n%s", data)
+	}
+
+	// replay without the server, matching the batch regardless of order.
+	srv.Close()
+	rec, err = ipinfotest.NewRecorder(path, ipinfotest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = recorderClient(t, srv, rec)
+	info, err := client.GetIPInfo(ips[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q, want the recorded one", info.City)
+	}
+	batch, err := client.GetIPInfoBatch([]net.IP{ips[1], ips[0]}, ipinfo.BatchReqOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch["8.8.8.8"] == nil || batch["1.1.1.1"] == nil {
+		t.Errorf("batch = %v, want both IPs", batch)
+	}
+}
+
+func TestRecorderRedactsQueryToken(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	rec, err := ipinfotest.NewRecorder(filepath.Join(t.TempDir(), "fixture.json"), ipinfotest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rec.HTTPClient().Get(srv.URL + "/8.8.8.8?token=secret&x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if u := rec.Interactions()[0].Request.URL; strings.Contains(u, "secret") || !strings.HasSuffix(u, "?token=REDACTED&x=1") {
+		t.Errorf("URL = %q, want the token redacted", u)
+	}
+}
+
+func TestRecorderReplayOrder(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 0)
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	rec, err := ipinfotest.NewRecorder(path, ipinfotest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := recorderClient(t, srv, rec)
+	client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the 429 is replayed first, then the success repeatedly.
+	rec, err = ipinfotest.NewRecorder(path, ipinfotest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = recorderClient(t, srv, rec)
+	var errResp *ipinfo.ErrorResponse
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); !errors.As(err, &errResp) || errResp.Response.StatusCode != 429 {
+		t.Errorf("err = %v, want the recorded 429", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+			t.Errorf("replay %d: %v", i, err)
+		}
+	}
+}
+
+func TestRecorderUnmatched(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	rec, _ := ipinfotest.NewRecorder(path, ipinfotest.ModeRecord)
+	recorderClient(t, srv, rec).GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := ipinfotest.NewRecorder(path, ipinfotest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = recorderClient(t, srv, rec).GetIPInfo(net.ParseIP("1.1.1.1"))
+	var unmatched *ipinfotest.UnmatchedRequestError
+	if !errors.As(err, &unmatched) {
+		t.Fatalf("err = %v, want an UnmatchedRequestError", err)
+	}
+	if unmatched.Path != "/1.1.1.1" || len(unmatched.Recorded) != 1 || unmatched.Recorded[0] != "GET /8.8.8.8" {
+		t.Errorf("err = %+v", unmatched)
+	}
+}
+
+func TestNewRecorderErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ipinfotest.NewRecorder(filepath.Join(dir, "missing.json"), ipinfotest.ModeReplay); err == nil {
+		t.Error("err = nil for a missing fixture")
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipinfotest.NewRecorder(invalid, ipinfotest.ModeReplay); err == nil {
+		t.Error("err = nil for an invalid fixture")
+	}
+
+	// recording doesn't need the file, and replaying doesn't write it.
+	path := filepath.Join(dir, "new.json")
+	rec, err := ipinfotest.NewRecorder(path, ipinfotest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err = ipinfotest.NewRecorder(path, ipinfotest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save in replay mode wrote the fixture: %v", err)
+	}
+}
@@ -0,0 +1,571 @@
+// Package ipinfotest provides an in-process fake of the IPinfo API for tests
+// of code using the `ipinfo` package.
+//
+// A `Server` serves the legacy, Lite, Core and Plus `/lookup`, batch, ASN,
+// residential proxy, summarize and map endpoints from fixtures, and can
+// simulate rate limiting, slow responses and malformed JSON:
+//
+//	srv := ipinfotest.NewServer()
+//	defer srv.Close()
+//
+//	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View", Country: "US"})
+//	srv.RateLimit("/", 1, time.Second)
+//
+//	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2}))
+//	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+//
+// IPs without a fixture are answered with a response containing only the IP.
+//
+// The package also provides a `Recorder`, which records and replays real API
+// interactions, and a `FaultInjector`, which makes requests misbehave.
+package ipinfotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+// Server is a fake IPinfo API server. Its methods are safe to call
+// concurrently with requests.
+type Server struct {
+	// Underlying test server; use `URL` and `Close` through it.
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	callerIP string
+	legacy   map[string]interface{}
+	lite     map[string]interface{}
+	lookup   map[string]interface{}
+	asn      map[string]interface{}
+	resproxy map[string]interface{}
+	summary  interface{}
+	ipMap    interface{}
+	account  interface{}
+	faults   []*fault
+	requests []Request
+}
+
+// Request is a request received by a `Server`.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Fault alters the responses of a `Server` to simulate misbehavior of the
+// API. Unset fields leave the response unchanged.
+type Fault struct {
+	// Status code to respond with instead of the fixture.
+	Status int
+
+	// Raw body to respond with instead of the fixture, e.g. malformed JSON.
+	Body string
+
+	// Headers added to the response.
+	Header http.Header
+
+	// Delay before responding. The delay is cut short if the client
+	// cancels the request.
+	Delay time.Duration
+
+	// Number of requests the fault applies to; 0 means all.
+	Times int
+}
+
+type fault struct {
+	prefix string
+	Fault
+	used int
+}
+
+// NewServer starts and returns a new fake IPinfo API server. The caller
+// should call `Close` when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		callerIP: "203.0.113.1",
+		legacy:   make(map[string]interface{}),
+		lite:     make(map[string]interface{}),
+		lookup:   make(map[string]interface{}),
+		asn:      make(map[string]interface{}),
+		resproxy: make(map[string]interface{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+/* FIXTURES */
+
+// RequireToken makes the server reject requests not authorized with
+// `token` with 403 responses. An empty token accepts all requests.
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetCallerIP sets the IP which the server reports for lookups of the
+// caller's own IP. It defaults to 203.0.113.1.
+func (s *Server) SetCallerIP(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callerIP = ip
+}
+
+// SetIP sets the legacy API response for `ip`. The IP of `v` defaults to
+// `ip`.
+func (s *Server) SetIP(ip string, v *ipinfo.Core) {
+	if v.IP == nil {
+		v.IP = net.ParseIP(ip)
+	}
+	s.set(s.legacy, ip, v)
+}
+
+// SetLite sets the Lite API response for `ip`. The IP of `v` defaults to
+// `ip`.
+func (s *Server) SetLite(ip string, v *ipinfo.Lite) {
+	if v.IP == nil {
+		v.IP = net.ParseIP(ip)
+	}
+	s.set(s.lite, ip, v)
+}
+
+// SetCore sets the `/lookup` response for `ip` to a Core API response. As
+// the Core and Plus APIs share the endpoint, this replaces any response set
+// with `SetPlus`. The IP of `v` defaults to `ip`.
+func (s *Server) SetCore(ip string, v *ipinfo.CoreResponse) {
+	if v.IP == nil {
+		v.IP = net.ParseIP(ip)
+	}
+	s.set(s.lookup, ip, v)
+}
+
+// SetPlus sets the `/lookup` response for `ip` to a Plus API response. As
+// the Core and Plus APIs share the endpoint, this replaces any response set
+// with `SetCore`. The IP of `v` defaults to `ip`.
+func (s *Server) SetPlus(ip string, v *ipinfo.Plus) {
+	if v.IP == nil {
+		v.IP = net.ParseIP(ip)
+	}
+	s.set(s.lookup, ip, v)
+}
+
+// SetASN sets the response for `asn`, e.g. "AS15169". The ASN of `v`
+// defaults to `asn`.
+func (s *Server) SetASN(asn string, v *ipinfo.ASNDetails) {
+	if v.ASN == "" {
+		v.ASN = asn
+	}
+	s.set(s.asn, asn, v)
+}
+
+// SetResproxy sets the residential proxy response for `ip`. The IP of `v`
+// defaults to `ip`.
+func (s *Server) SetResproxy(ip string, v *ipinfo.ResproxyDetails) {
+	if v.IP == "" {
+		v.IP = ip
+	}
+	s.set(s.resproxy, ip, v)
+}
+
+// SetSummary sets the response of the summarize endpoint. By default, only
+// the total and unique counts of the IPs sent are reported.
+func (s *Server) SetSummary(v *ipinfo.IPSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summary = v
+}
+
+// SetMap sets the response of the map endpoint. By default, a successful
+// response with a report URL on the server is returned.
+func (s *Server) SetMap(v *ipinfo.IPMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ipMap = v
+}
+
+// SetTokenDetails sets the response of the `/me` endpoint.
+func (s *Server) SetTokenDetails(v *ipinfo.TokenDetails) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account = v
+}
+
+func (s *Server) set(m map[string]interface{}, key string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m[key] = v
+}
+
+/* FAULTS */
+
+// Fault applies `f` to requests whose path starts with `pathPrefix`, e.g.
+// "/lite/" for the Lite API; "/" applies to all requests.
+//
+// All faults matching a request apply together: their delays add up, all of
+// their headers are added, and the status and body are those of the first
+// fault, in the order added, which sets them. E.g. a `Slow` fault and a
+// `RateLimit` fault answer with a 429 response after the delay.
+func (s *Server) Fault(pathPrefix string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{prefix: pathPrefix, Fault: f})
+}
+
+// RateLimit answers the next `times` requests whose path starts with
+// `pathPrefix` with 429 responses asking to retry after `retryAfter`.
+func (s *Server) RateLimit(pathPrefix string, times int, retryAfter time.Duration) {
+	header := http.Header{}
+	header.Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	header.Set("X-RateLimit-Remaining", "0")
+	s.Fault(pathPrefix, Fault{
+		Status: http.StatusTooManyRequests,
+		Body:   `{"error":"rate limit exceeded"}`,
+		Header: header,
+		Times:  times,
+	})
+}
+
+// Slow delays all responses to requests whose path starts with `pathPrefix`
+// by `d`.
+func (s *Server) Slow(pathPrefix string, d time.Duration) {
+	s.Fault(pathPrefix, Fault{Delay: d})
+}
+
+// Malformed answers the next `times` requests whose path starts with
+// `pathPrefix` with truncated JSON.
+func (s *Server) Malformed(pathPrefix string, times int) {
+	s.Fault(pathPrefix, Fault{Body: `{"ip": "8.8.8.8", "city": `, Times: times})
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+/* CLIENTS */
+
+// Client returns a legacy API client pointed at the server, authorized with
+// the token required by the server, if any, and configured further by
+// `opts`. It panics if an option fails.
+func (s *Server) Client(opts ...ipinfo.Option) *ipinfo.Client {
+	c, err := ipinfo.NewClientWithOptions(s.options("/", opts)...)
+	if err != nil {
+		panic("ipinfotest: " + err.Error())
+	}
+	return c
+}
+
+// LiteClient returns a Lite API client pointed at the server. See `Client`.
+func (s *Server) LiteClient(opts ...ipinfo.Option) *ipinfo.LiteClient {
+	c, err := ipinfo.NewLiteClientWithOptions(s.options("/lite/", opts)...)
+	if err != nil {
+		panic("ipinfotest: " + err.Error())
+	}
+	return c
+}
+
+// CoreClient returns a Core API client pointed at the server. See `Client`.
+func (s *Server) CoreClient(opts ...ipinfo.Option) *ipinfo.CoreClient {
+	c, err := ipinfo.NewCoreClientWithOptions(s.options("/lookup/", opts)...)
+	if err != nil {
+		panic("ipinfotest: " + err.Error())
+	}
+	return c
+}
+
+// PlusClient returns a Plus API client pointed at the server. See `Client`.
+func (s *Server) PlusClient(opts ...ipinfo.Option) *ipinfo.PlusClient {
+	c, err := ipinfo.NewPlusClientWithOptions(s.options("/lookup/", opts)...)
+	if err != nil {
+		panic("ipinfotest: " + err.Error())
+	}
+	return c
+}
+
+func (s *Server) options(path string, opts []ipinfo.Option) []ipinfo.Option {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+
+	return append([]ipinfo.Option{
+		ipinfo.WithHTTPClient(s.Server.Client()),
+		ipinfo.WithBaseURL(s.URL + path),
+		ipinfo.WithIPv6BaseURL(s.URL + path),
+		ipinfo.WithToken(token),
+	}, opts...)
+}
+
+/* HANDLER */
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	token := s.token
+	f := s.matchFaults(r.URL.Path)
+	s.mu.Unlock()
+
+	if f != nil {
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		for k, vs := range f.Header {
+			for _, v := range vs {
+				w.Header().Add(k, v)
+			}
+		}
+		if f.Status != 0 || f.Body != "" {
+			status := f.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(status)
+			io.WriteString(w, f.Body)
+			return
+		}
+	}
+
+	if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+		writeError(w, http.StatusForbidden, "Wrong token.")
+		return
+	}
+
+	status, v := s.route(r, body)
+	if status != http.StatusOK {
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+	if text, ok := v.(string); ok {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, text+"\n")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// `matchFaults` returns the composition of the faults applying to `path`,
+// counting their uses, or nil if none applies. Must be called with `s.mu`
+// held.
+func (s *Server) matchFaults(path string) *Fault {
+	var composed *Fault
+	for _, f := range s.faults {
+		if !strings.HasPrefix(path, f.prefix) {
+			continue
+		}
+		if f.Times > 0 && f.used >= f.Times {
+			continue
+		}
+		f.used++
+
+		if composed == nil {
+			composed = &Fault{Header: http.Header{}}
+		}
+		composed.Delay += f.Delay
+		for k, vs := range f.Header {
+			for _, v := range vs {
+				composed.Header.Add(k, v)
+			}
+		}
+		if composed.Status == 0 {
+			composed.Status = f.Status
+		}
+		if composed.Body == "" {
+			composed.Body = f.Body
+		}
+	}
+	return composed
+}
+
+// `route` returns the status and response for request `r` with `body`.
+func (s *Server) route(r *http.Request, body []byte) (int, interface{}) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	switch {
+	case r.Method == http.MethodPost && path == "batch":
+		var urls []string
+		if err := json.Unmarshal(body, &urls); err != nil {
+			return http.StatusBadRequest, nil
+		}
+		return http.StatusOK, s.batch(urls, r.URL.Query().Get("filter") == "1")
+	case r.Method == http.MethodPost && path == "summarize":
+		var ips []string
+		if err := json.Unmarshal(body, &ips); err != nil {
+			return http.StatusBadRequest, nil
+		}
+		return http.StatusOK, s.summarize(ips)
+	case r.Method == http.MethodPost && path == "map":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.ipMap != nil {
+			return http.StatusOK, s.ipMap
+		}
+		return http.StatusOK, &ipinfo.IPMap{
+			Status:    "success",
+			ReportURL: s.URL + "/tools/map/ipinfotest",
+		}
+	case r.Method != http.MethodGet:
+		return http.StatusMethodNotAllowed, nil
+	case strings.HasPrefix(path, "lite/"):
+		ip := s.resolveIP(strings.TrimPrefix(path, "lite/"))
+		return s.lookupIn(s.lite, ip, &ipinfo.Lite{IP: net.ParseIP(ip)})
+	case strings.HasPrefix(path, "lookup/"):
+		ip := s.resolveIP(strings.TrimPrefix(path, "lookup/"))
+		return s.lookupIn(s.lookup, ip, &ipinfo.Plus{IP: net.ParseIP(ip)})
+	case strings.HasPrefix(path, "resproxy/"):
+		ip := strings.TrimPrefix(path, "resproxy/")
+		return s.lookupIn(s.resproxy, ip, &ipinfo.ResproxyDetails{IP: ip})
+	case path == "me":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.account == nil {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, s.account
+	default:
+		v, ok := s.legacyValue(path)
+		if !ok {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, v
+	}
+}
+
+// `resolveIP` returns the IP to look up for path element `ip`, which is
+// "me" or empty for the caller's IP.
+func (s *Server) resolveIP(ip string) string {
+	if ip != "" && ip != "me" {
+		return ip
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.callerIP
+}
+
+// `lookupIn` returns the fixture for `key` in `m`, or `fallback`.
+func (s *Server) lookupIn(
+	m map[string]interface{},
+	key string,
+	fallback interface{},
+) (int, interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := m[key]; ok {
+		return http.StatusOK, v
+	}
+	return http.StatusOK, fallback
+}
+
+// `legacyValue` returns the legacy API response for `path`, which is empty
+// for the caller's IP, an IP, an ASN, or an IP followed by a field name.
+// Single fields are returned as strings if they are strings in JSON.
+func (s *Server) legacyValue(path string) (interface{}, bool) {
+	ip, field, _ := strings.Cut(path, "/")
+	if strings.HasPrefix(ip, "AS") && field == "" {
+		_, v := s.lookupIn(s.asn, ip, &ipinfo.ASNDetails{ASN: ip})
+		return v, true
+	}
+
+	ip = s.resolveIP(ip)
+	if net.ParseIP(ip) == nil {
+		return nil, false
+	}
+	_, v := s.lookupIn(s.legacy, ip, &ipinfo.Core{IP: net.ParseIP(ip)})
+	if field == "" {
+		return v, true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return "", true
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, true
+	}
+	return raw, true
+}
+
+// `batch` returns the batch response for `urls`, omitting empty values if
+// `filter` is set.
+func (s *Server) batch(urls []string, filter bool) map[string]interface{} {
+	res := make(map[string]interface{}, len(urls))
+	for _, u := range urls {
+		v, ok := s.legacyValue(u)
+		if !ok || (filter && v == "") {
+			continue
+		}
+		res[u] = v
+	}
+	return res
+}
+
+// `summarize` returns the summarize response for `ips`.
+func (s *Server) summarize(ips []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summary != nil {
+		return s.summary
+	}
+
+	unique := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		unique[ip] = true
+	}
+	return &ipinfo.IPSummary{
+		Total:  uint64(len(ips)),
+		Unique: uint64(len(unique)),
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"status":"%d","error":{"title":%q,"message":%q}}`,
+		status, http.StatusText(status), msg)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,244 @@
+package ipinfotest_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestServerFixtures(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{CountryCode: "US"})
+	srv.SetPlus("8.8.8.8", &ipinfo.Plus{Hostname: "dns.google"})
+	srv.SetASN("AS15169", &ipinfo.ASNDetails{Name: "Google LLC"})
+	srv.SetResproxy("1.2.3.4", &ipinfo.ResproxyDetails{Service: "example"})
+	srv.SetTokenDetails(&ipinfo.TokenDetails{Token: "t", Plan: "Plus"})
+
+	ip := net.ParseIP("8.8.8.8")
+	client := srv.Client()
+	if v, err := client.GetIPInfo(ip); err != nil || v.City != "Mountain View" || !v.IP.Equal(ip) {
+		t.Errorf("legacy = %+v, %v", v, err)
+	}
+	if v, err := client.GetIPCity(ip); err != nil || v != "Mountain View" {
+		t.Errorf("legacy city = %q, %v", v, err)
+	}
+	if v, err := srv.LiteClient().GetIPInfo(ip); err != nil || v.CountryCode != "US" {
+		t.Errorf("Lite = %+v, %v", v, err)
+	}
+	if v, err := srv.PlusClient().GetIPInfo(ip); err != nil || v.Hostname != "dns.google" {
+		t.Errorf("Plus = %+v, %v", v, err)
+	}
+	if v, err := client.GetASNDetails("AS15169"); err != nil || v.Name != "Google LLC" || v.ASN != "AS15169" {
+		t.Errorf("ASN = %+v, %v", v, err)
+	}
+	if v, err := client.GetResproxy("1.2.3.4"); err != nil || v.Service != "example" || v.IP != "1.2.3.4" {
+		t.Errorf("resproxy = %+v, %v", v, err)
+	}
+	if v, err := client.GetTokenDetails(); err != nil || v.Plan != "Plus" {
+		t.Errorf("token details = %+v, %v", v, err)
+	}
+
+	// IPs without a fixture are answered with the IP only.
+	if v, err := srv.CoreClient().GetIPInfo(net.ParseIP("1.1.1.1")); err != nil || !v.IP.Equal(net.ParseIP("1.1.1.1")) || v.Geo != nil {
+		t.Errorf("Core = %+v, %v", v, err)
+	}
+}
+
+func TestServerCallerIP(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetCallerIP("192.0.2.7")
+
+	if ip, err := srv.Client().GetIPAddr(); err != nil || ip != "192.0.2.7" {
+		t.Errorf("caller IP = %q, %v", ip, err)
+	}
+	if v, err := srv.LiteClient().GetIPInfo(nil); err != nil || !v.IP.Equal(net.ParseIP("192.0.2.7")) {
+		t.Errorf("Lite = %+v, %v", v, err)
+	}
+}
+
+func TestServerBatchSummaryMap(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+
+	client := srv.Client()
+	ips := []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1")}
+	batch, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 2 || batch["8.8.8.8"].City != "Mountain View" {
+		t.Errorf("batch = %v", batch)
+	}
+
+	summary, err := client.GetIPSummary(ips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total != 3 || summary.Unique != 2 {
+		t.Errorf("summary = %+v, want 3 total and 2 unique", summary)
+	}
+	srv.SetSummary(&ipinfo.IPSummary{Total: 42})
+	if summary, err := client.GetIPSummary(ips); err != nil || summary.Total != 42 {
+		t.Errorf("summary = %+v, %v, want the fixture", summary, err)
+	}
+
+	m, err := client.GetIPMap(ips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != "success" || m.ReportURL == "" {
+		t.Errorf("map = %+v", m)
+	}
+}
+
+func TestServerRequireToken(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	_, err := srv.Client(ipinfo.WithToken("wrong")).GetIPInfo(net.ParseIP("8.8.8.8"))
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("err = %v, want an ErrorResponse", err)
+	}
+	if errResp.Response.StatusCode != http.StatusForbidden || errResp.Status != "403" ||
+		errResp.Err.Title != "Forbidden" || errResp.Err.Message != "Wrong token." {
+		t.Errorf("err = %+v, want a decoded 403 error", errResp)
+	}
+
+	// clients of the server are authorized.
+	if _, err := srv.Client().GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerNotFound(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client().GetTokenDetails()
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Status != "404" {
+		t.Errorf("err = %v, want a decoded 404 error", err)
+	}
+}
+
+func TestServerFaultTimes(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/lite/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 2})
+
+	client := srv.LiteClient()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+			t.Errorf("request %d: err = nil", i)
+		}
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v after the fault was used up", err)
+	}
+
+	// the fault only applies beneath its prefix.
+	srv.Fault("/lite/", ipinfotest.Fault{Status: http.StatusBadGateway})
+	if _, err := srv.Client().GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("legacy: %v", err)
+	}
+	srv.ClearFaults()
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v after ClearFaults", err)
+	}
+}
+
+func TestServerFaultsCompose(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	header := http.Header{}
+	header.Set("X-Test", "1")
+	srv.Slow("/", 50*time.Millisecond)
+	srv.Fault("/", ipinfotest.Fault{Header: header})
+	srv.RateLimit("/", 1, 0)
+
+	start := time.Now()
+	_, meta, err := srv.Client().GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("response after %v, want the delay of the slow fault", d)
+	}
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want the 429 of the rate limit despite the earlier faults", err)
+	}
+	if meta.Header.Get("X-Test") != "1" || meta.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("headers = %v, want those of all faults", meta.Header)
+	}
+
+	// the status of the first fault setting one is used.
+	srv.ClearFaults()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+	srv.RateLimit("/", 1, 0)
+	_, err = srv.Client().GetIPInfo(net.ParseIP("8.8.8.8"))
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusBadGateway {
+		t.Errorf("err = %v, want the 502 of the first fault", err)
+	}
+}
+
+func TestServerMalformed(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Malformed("/", 1)
+
+	client := srv.Client()
+	_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	var errResp *ipinfo.ErrorResponse
+	if err == nil || errors.As(err, &errResp) {
+		t.Errorf("err = %v, want a decoding error", err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v after the fault", err)
+	}
+}
+
+func TestServerSlowCanceled(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Slow("/", time.Minute)
+
+	start := time.Now()
+	if _, err := srv.Client(ipinfo.WithTimeout(20 * time.Millisecond)).GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Error("err = nil despite the timeout")
+	}
+	srv.Close()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("server took %v to close, want the delay cut short", d)
+	}
+}
+
+func TestServerRequests(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	client := srv.Client()
+	client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	client.GetIPInfoBatch([]net.IP{net.ParseIP("1.1.1.1")}, ipinfo.BatchReqOpts{Filter: true})
+
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %+v, want 2", reqs)
+	}
+	if r := reqs[0]; r.Method != http.MethodGet || r.Path != "/8.8.8.8" || r.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("request = %+v", r)
+	}
+	if r := reqs[1]; r.Method != http.MethodPost || r.Path != "/batch" || r.Query != "filter=1" || string(r.Body) != `["1.1.1.1"]` {
+		t.Errorf("request = %+v", r)
+	}
+}
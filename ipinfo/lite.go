@@ -1,14 +1,12 @@
 package ipinfo
 
 import (
-	"context"
-	"encoding/json"
-	"io"
 	"net"
 	"net/http"
 	"net/netip"
 	"net/url"
-	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -16,10 +14,9 @@ const (
 )
 
 // LiteClient is a client for the IPinfo Lite API.
+//
+// The exported fields reflect the configuration of the client. See `Client`.
 type LiteClient struct {
-	// HTTP client used to communicate with the API.
-	client *http.Client
-
 	// Base URL for API requests.
 	BaseURL *url.URL
 
@@ -31,27 +28,34 @@ type LiteClient struct {
 
 	// The API token used for authorization.
 	Token string
+
+	// Transport of the client, created on first use if the client wasn't
+	// created by a constructor.
+	t    *transport
+	once sync.Once
 }
 
 // Lite represents the response from the IPinfo Lite API.
 type Lite struct {
-	IP            net.IP `json:"ip"`
-	ASN           string `json:"asn"`
-	ASName        string `json:"as_name"`
-	ASDomain      string `json:"as_domain"`
-	CountryCode   string `json:"country_code"`
-	Country       string `json:"country"`
-	ContinentCode string `json:"continent_code"`
-	Continent     string `json:"continent"`
-	Bogon         bool   `json:"bogon"`
+	IP            net.IP        `json:"ip" csv:"ip" yaml:"ip,omitempty" schema:"required"`
+	ASN           string        `json:"asn" csv:"asn" yaml:"asn,omitempty"`
+	ASName        string        `json:"as_name" csv:"as_name" yaml:"asName,omitempty"`
+	ASDomain      string        `json:"as_domain" csv:"as_domain" yaml:"asDomain,omitempty"`
+	CountryCode   string        `json:"country_code" csv:"country_code" yaml:"countryCode,omitempty" schema:"required"`
+	Country       string        `json:"country" csv:"country" yaml:"country,omitempty" schema:"required"`
+	ContinentCode string        `json:"continent_code" csv:"continent_code" yaml:"continentCode,omitempty" schema:"required"`
+	Continent     string        `json:"continent" csv:"continent" yaml:"continent,omitempty" schema:"required"`
+	Bogon         bool          `json:"bogon" csv:"bogon" yaml:"bogon,omitempty"`
+	BogonInfo     *BogonInfo    `json:"bogon_info,omitempty" csv:"bogon_,inline" yaml:"bogonInfo,omitempty"`
+	Local         *LocalNetwork `json:"local,omitempty" csv:"local_,inline" yaml:"local,omitempty"`
 
 	// Extended fields using the same country data as Core API
-	CountryName     string          `json:"-"`
-	CountryFlag     CountryFlag     `json:"-"`
-	CountryFlagURL  string          `json:"-"`
-	CountryCurrency CountryCurrency `json:"-"`
-	ContinentInfo   Continent       `json:"-"`
-	IsEU            bool            `json:"-"`
+	CountryName     string          `json:"-" csv:"country_name" yaml:"countryName,omitempty"`
+	CountryFlag     CountryFlag     `json:"-" csv:"country_flag_,inline" yaml:"countryFlag,omitempty"`
+	CountryFlagURL  string          `json:"-" csv:"country_flag_url" yaml:"countryFlagURL,omitempty"`
+	CountryCurrency CountryCurrency `json:"-" csv:"country_currency_,inline" yaml:"countryCurrency,omitempty"`
+	ContinentInfo   Continent       `json:"-" csv:"continent_info_,inline" yaml:"continentInfo,omitempty"`
+	IsEU            bool            `json:"-" csv:"isEU" yaml:"isEU,omitempty"`
 }
 
 func (v *Lite) setCountryName() {
@@ -70,132 +74,154 @@ func (v *Lite) setCountryName() {
 
 // NewLiteClient creates a new IPinfo Lite API client.
 func NewLiteClient(httpClient *http.Client, cache *Cache, token string) *LiteClient {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
+	return newLiteClient(newTransport(httpClient, defaultLiteBaseURL, "LITE", cache, token))
+}
 
-	baseURL, _ := url.Parse(defaultLiteBaseURL)
-	return &LiteClient{
-		client:    httpClient,
-		BaseURL:   baseURL,
-		UserAgent: defaultUserAgent,
-		Cache:     cache,
-		Token:     token,
-	}
+// `transport` returns the transport of `c`, creating one with the defaults
+// of `NewLiteClient` if `c` wasn't created by a constructor.
+func (c *LiteClient) transport() *transport {
+	c.once.Do(func() {
+		if c.t == nil {
+			c.t = newTransport(nil, defaultLiteBaseURL, "LITE", nil, "")
+		}
+		c.t.bind(&clientFields{&c.BaseURL, &c.UserAgent, &c.Cache, &c.Token})
+	})
+	return c.t
+}
+
+// `newLiteClient` returns a LiteClient using `t`, with its exported fields set
+// from the configuration of `t`.
+func newLiteClient(t *transport) *LiteClient {
+	c := &LiteClient{t: t}
+	c.transport()
+	return c
 }
 
 // GetIPInfo returns the lite details for the specified IP.
 func (c *LiteClient) GetIPInfo(ip net.IP) (*Lite, error) {
-	if ip != nil && isBogon(netip.MustParseAddr(ip.String())) {
+	v, _, err := c.GetIPInfoWithMeta(ip)
+	return v, err
+}
+
+// GetIPInfoWithMeta returns the lite details for the specified IP
+// along with metadata about the API response.
+func (c *LiteClient) GetIPInfoWithMeta(ip net.IP) (*Lite, *ResponseMeta, error) {
+	start := time.Now()
+	cfg := c.transport().config()
+	cache := cfg.observedCache
+	addr, err := validIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := cfg.local.Lookup(addr); ok {
+		return n.lite(ip), &ResponseMeta{Duration: time.Since(start)}, nil
+	}
+	if info := ClassifyBogon(addr); info != nil {
 		bogonResponse := new(Lite)
 		bogonResponse.Bogon = true
+		bogonResponse.BogonInfo = info
 		bogonResponse.IP = ip
-		return bogonResponse, nil
+		return bogonResponse, &ResponseMeta{Duration: time.Since(start)}, nil
 	}
 	relUrl := "me"
 	if ip != nil {
 		relUrl = ip.String()
 	}
 
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey(relUrl)); err == nil {
-			return res.(*Lite), nil
+	key := cacheKey("lite:" + relUrl)
+	if cache != nil {
+		if res, err := cache.Get(key); err == nil {
+			return res.(*Lite), cachedMeta(start), nil
 		}
 	}
 
-	req, err := c.newRequest(nil, "GET", relUrl, nil)
+	req, err := c.transport().newRequest(nil, "GET", relUrl, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	res := new(Lite)
-	if _, err := c.do(req, res); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, res)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	res.setCountryName()
 
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey(relUrl), res); err != nil {
-			return res, err
+	if cache != nil {
+		if err := cache.Set(key, res); err != nil {
+			return res, meta, err
 		}
 	}
 
-	return res, nil
+	return res, meta, nil
 }
 
-func (c *LiteClient) newRequest(ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-) (*http.Request, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	u := new(url.URL)
-	baseURL := c.BaseURL
-	if rel, err := url.Parse(urlStr); err == nil {
-		u = baseURL.ResolveReference(rel)
-	} else if strings.ContainsRune(urlStr, ':') {
-		// IPv6 strings fail to parse as URLs, so let's add it as a URL Path.
-		*u = *baseURL
-		u.Path += urlStr
-	} else {
-		return nil, err
-	}
+// GetIPAddrInfo returns the lite details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func (c *LiteClient) GetIPAddrInfo(addr netip.Addr) (*Lite, error) {
+	v, _, err := c.GetIPAddrInfoWithMeta(addr)
+	return v, err
+}
 
-	// get `http` package request object.
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+// GetIPAddrInfoWithMeta is like `GetIPAddrInfo` but also returns metadata
+// about the API response.
+func (c *LiteClient) GetIPAddrInfoWithMeta(addr netip.Addr) (*Lite, *ResponseMeta, error) {
+	ip, err := addrIP(addr)
 	if err != nil {
-		return nil, err
-	}
-
-	// set common headers.
-	req.Header.Set("Accept", "application/json")
-	if c.UserAgent != "" {
-		req.Header.Set("User-Agent", c.UserAgent)
+		return nil, nil, err
 	}
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
-	}
-
-	return req, nil
+	return c.GetIPInfoWithMeta(ip)
 }
 
-func (c *LiteClient) do(
-	req *http.Request,
-	v interface{},
-) (*http.Response, error) {
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// GetIPStrInfo returns the lite details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func (c *LiteClient) GetIPStrInfo(ip string) (*Lite, error) {
+	v, _, err := c.GetIPStrInfoWithMeta(ip)
+	return v, err
+}
 
-	err = checkResponse(resp)
+// GetIPStrInfoWithMeta is like `GetIPStrInfo` but also returns metadata
+// about the API response.
+func (c *LiteClient) GetIPStrInfoWithMeta(ip string) (*Lite, *ResponseMeta, error) {
+	parsed, err := parseIP(ip)
 	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
+		return nil, nil, err
 	}
-
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-			if err == io.EOF {
-				// ignore EOF errors caused by empty response body
-				err = nil
-			}
-		}
-	}
-
-	return resp, err
+	return c.GetIPInfoWithMeta(parsed)
 }
 
 // GetIPInfo returns the details for the specified IP.
 func GetIPInfoLite(ip net.IP) (*Lite, error) {
 	return DefaultLiteClient.GetIPInfo(ip)
 }
+
+// GetIPInfoLiteWithMeta returns the lite details for the specified IP
+// along with metadata about the API response.
+func GetIPInfoLiteWithMeta(ip net.IP) (*Lite, *ResponseMeta, error) {
+	return DefaultLiteClient.GetIPInfoWithMeta(ip)
+}
+
+// GetIPAddrInfoLite returns the lite details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func GetIPAddrInfoLite(addr netip.Addr) (*Lite, error) {
+	return DefaultLiteClient.GetIPAddrInfo(addr)
+}
+
+// GetIPAddrInfoLiteWithMeta is like `GetIPAddrInfoLite` but also returns
+// metadata about the API response.
+func GetIPAddrInfoLiteWithMeta(addr netip.Addr) (*Lite, *ResponseMeta, error) {
+	return DefaultLiteClient.GetIPAddrInfoWithMeta(addr)
+}
+
+// GetIPStrInfoLite returns the lite details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func GetIPStrInfoLite(ip string) (*Lite, error) {
+	return DefaultLiteClient.GetIPStrInfo(ip)
+}
+
+// GetIPStrInfoLiteWithMeta is like `GetIPStrInfoLite` but also returns
+// metadata about the API response.
+func GetIPStrInfoLiteWithMeta(ip string) (*Lite, *ResponseMeta, error) {
+	return DefaultLiteClient.GetIPStrInfoWithMeta(ip)
+}
@@ -0,0 +1,291 @@
+package ipinfo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalNetwork is an entry of a `LocalNetworks` table, describing a network
+// whose lookups are answered locally rather than by the API.
+type LocalNetwork struct {
+	// The network, e.g. "10.20.0.0/16".
+	Network netip.Prefix `json:"network" csv:"network" yaml:"network,omitempty"`
+
+	// City of the network.
+	City string `json:"city,omitempty" csv:"-" yaml:"city,omitempty"`
+
+	// Country code of the network, e.g. "US".
+	Country string `json:"country,omitempty" csv:"-" yaml:"country,omitempty"`
+
+	// Name of the organization using the network.
+	Org string `json:"org,omitempty" csv:"-" yaml:"org,omitempty"`
+
+	// ASN of the network, e.g. "AS64512".
+	ASN string `json:"asn,omitempty" csv:"-" yaml:"asn,omitempty"`
+
+	// Custom tags, e.g. "vpn" or "partner".
+	Tags []string `json:"tags,omitempty" csv:"-" yaml:"tags,omitempty"`
+}
+
+// LocalNetworks is a table of networks whose lookups are answered locally,
+// such as internal networks which are bogons to the API. See
+// `WithLocalNetworks`.
+//
+// The table is immutable, so it may be shared between clients.
+type LocalNetworks struct {
+	networks *PrefixMap[*LocalNetwork]
+}
+
+// NewLocalNetworks returns a table of `networks`. If a network is listed more
+// than once, the last entry wins.
+//
+// ASNs are normalized to the form "AS64512", and country codes to upper
+// case.
+func NewLocalNetworks(networks ...LocalNetwork) (*LocalNetworks, error) {
+	var b PrefixMapBuilder[*LocalNetwork]
+	for i := range networks {
+		n := networks[i].clone()
+		n.Network = n.Network.Masked()
+		n.Country = strings.ToUpper(n.Country)
+		if n.ASN != "" {
+			n.ASN = "AS" + strings.TrimPrefix(strings.ToUpper(n.ASN), "AS")
+		}
+		if err := b.Add(n.Network, n); err != nil {
+			return nil, fmt.Errorf("ipinfo: local network %d (%s): %w", i+1, n.Network, err)
+		}
+	}
+	return &LocalNetworks{networks: b.Build()}, nil
+}
+
+// LoadLocalNetworks reads a table of local networks from the file at `path`,
+// which is read as JSON if its name ends with ".json" and as CSV otherwise.
+// See `ReadLocalNetworksJSON` and `ReadLocalNetworksCSV`.
+func LoadLocalNetworks(path string) (*LocalNetworks, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ReadLocalNetworksJSON(f)
+	}
+	return ReadLocalNetworksCSV(f)
+}
+
+// ReadLocalNetworksJSON reads a table of local networks from a JSON array of
+// `LocalNetwork` objects, e.g.:
+//
+//	[{"network": "10.20.0.0/16", "city": "Berlin", "country": "DE",
+//	  "org": "Example Corp", "asn": "AS64512", "tags": ["office"]}]
+func ReadLocalNetworksJSON(r io.Reader) (*LocalNetworks, error) {
+	var networks []LocalNetwork
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&networks); err != nil {
+		return nil, fmt.Errorf("ipinfo: local networks: %w", err)
+	}
+	return NewLocalNetworks(networks...)
+}
+
+// ReadLocalNetworksCSV reads a table of local networks from CSV. The first
+// record is a header naming the columns, out of "network", "city",
+// "country", "org", "asn" and "tags", in any order; only "network" is
+// required. Tags are separated by semicolons, e.g.:
+//
+//	network,city,country,org,asn,tags
+//	10.20.0.0/16,Berlin,DE,Example Corp,AS64512,office;vpn
+func ReadLocalNetworksCSV(r io.Reader) (*LocalNetworks, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("ipinfo: local networks: missing header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ipinfo: local networks: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "network", "city", "country", "org", "asn", "tags":
+		default:
+			return nil, fmt.Errorf("ipinfo: local networks: unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["network"]; !ok {
+		return nil, errors.New(`ipinfo: local networks: missing column "network"`)
+	}
+
+	var networks []LocalNetwork
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ipinfo: local networks: %w", err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		line, _ := cr.FieldPos(0)
+		prefix, err := netip.ParsePrefix(field("network"))
+		if err != nil {
+			return nil, fmt.Errorf("ipinfo: local networks: line %d: %w", line, err)
+		}
+		n := LocalNetwork{
+			Network: prefix,
+			City:    field("city"),
+			Country: field("country"),
+			Org:     field("org"),
+			ASN:     field("asn"),
+		}
+		for _, tag := range strings.Split(field("tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				n.Tags = append(n.Tags, tag)
+			}
+		}
+		networks = append(networks, n)
+	}
+	return NewLocalNetworks(networks...)
+}
+
+// Lookup returns the most specific network of the table containing `ip`.
+func (t *LocalNetworks) Lookup(ip netip.Addr) (*LocalNetwork, bool) {
+	if t == nil {
+		return nil, false
+	}
+	n, ok := t.networks.Lookup(ip.Unmap())
+	if !ok {
+		return nil, false
+	}
+	return n.clone(), true
+}
+
+// Len returns the number of networks in the table.
+func (t *LocalNetworks) Len() int {
+	if t == nil {
+		return 0
+	}
+	return t.networks.Len()
+}
+
+// `lookupURL` returns the legacy API result for the batch URL `url` if it is
+// an IP in a network of the table, or nil.
+func (t *LocalNetworks) lookupURL(url string) *Core {
+	ip := net.ParseIP(url)
+	if ip == nil {
+		return nil
+	}
+	if n, ok := t.Lookup(ipAddr(ip)); ok {
+		return n.core(ip)
+	}
+	return nil
+}
+
+func (n *LocalNetwork) clone() *LocalNetwork {
+	c := *n
+	c.Tags = append([]string(nil), n.Tags...)
+	return &c
+}
+
+// `core` returns the legacy API result for `ip` in `n`.
+func (n *LocalNetwork) core(ip net.IP) *Core {
+	v := &Core{
+		IP:      ip,
+		City:    n.City,
+		Country: n.Country,
+		Org:     strings.TrimSpace(n.ASN + " " + n.Org),
+		Local:   n,
+	}
+	if n.ASN != "" {
+		v.ASN = &CoreASN{ASN: n.ASN, Name: n.Org}
+	}
+	if n.Org != "" {
+		v.Company = &CoreCompany{Name: n.Org}
+	}
+	v.BogonInfo = ClassifyBogon(ipAddr(ip))
+	v.Bogon = v.BogonInfo != nil
+	v.setCountryName()
+	return v
+}
+
+// `lite` returns the Lite API result for `ip` in `n`.
+func (n *LocalNetwork) lite(ip net.IP) *Lite {
+	v := &Lite{
+		IP:          ip,
+		ASN:         n.ASN,
+		ASName:      n.Org,
+		CountryCode: n.Country,
+		Local:       n,
+	}
+	if n.Country != "" {
+		v.Country = GetCountryName(n.Country)
+		v.ContinentCode = GetContinentCode(n.Country)
+		v.Continent = GetContinentName(n.Country)
+	}
+	v.BogonInfo = ClassifyBogon(ipAddr(ip))
+	v.Bogon = v.BogonInfo != nil
+	v.setCountryName()
+	return v
+}
+
+// `coreResponse` returns the Core API result for `ip` in `n`.
+func (n *LocalNetwork) coreResponse(ip net.IP) *CoreResponse {
+	v := &CoreResponse{IP: ip, Local: n}
+	if n.City != "" || n.Country != "" {
+		v.Geo = &CoreGeo{City: n.City, CountryCode: n.Country}
+		if n.Country != "" {
+			v.Geo.Country = GetCountryName(n.Country)
+			v.Geo.ContinentCode = GetContinentCode(n.Country)
+			v.Geo.Continent = GetContinentName(n.Country)
+		}
+	}
+	if n.ASN != "" || n.Org != "" {
+		v.AS = &CoreAS{ASN: n.ASN, Name: n.Org}
+	}
+	v.BogonInfo = ClassifyBogon(ipAddr(ip))
+	v.Bogon = v.BogonInfo != nil
+	v.enrichGeo()
+	return v
+}
+
+// `plus` returns the Plus API result for `ip` in `n`.
+func (n *LocalNetwork) plus(ip net.IP) *Plus {
+	v := &Plus{IP: ip, Local: n}
+	if n.City != "" || n.Country != "" {
+		v.Geo = &PlusGeo{City: n.City, CountryCode: n.Country}
+		if n.Country != "" {
+			v.Geo.Country = GetCountryName(n.Country)
+			v.Geo.ContinentCode = GetContinentCode(n.Country)
+			v.Geo.Continent = GetContinentName(n.Country)
+		}
+	}
+	if n.ASN != "" || n.Org != "" {
+		v.AS = &PlusAS{ASN: n.ASN, Name: n.Org}
+	}
+	if n.Org != "" {
+		v.Company = &PlusCompany{Name: n.Org}
+	}
+	v.BogonInfo = ClassifyBogon(ipAddr(ip))
+	v.Bogon = v.BogonInfo != nil
+	v.enrichGeo()
+	return v
+}
@@ -0,0 +1,283 @@
+package ipinfo_test
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+const localNetworksCSV = `network, city, country, org, asn, tags
+10.0.0.0/8,,us,Example Corp,64512,
+10.20.0.0/16,Berlin,de,Example Corp,as64512,office; vpn
+fd00::/8,,,IPv6 Lab,,lab
+`
+
+const localNetworksJSON = `[
+	{"network": "10.0.0.0/8", "country": "us", "org": "Example Corp", "asn": "64512"},
+	{"network": "10.20.0.0/16", "city": "Berlin", "country": "de", "org": "Example Corp", "asn": "as64512", "tags": ["office", "vpn"]},
+	{"network": "fd00::/8", "org": "IPv6 Lab", "tags": ["lab"]}
+]`
+
+// `localNetworks` returns the table of `localNetworksCSV`.
+func localNetworks(t *testing.T) *ipinfo.LocalNetworks {
+	t.Helper()
+	networks, err := ipinfo.ReadLocalNetworksCSV(strings.NewReader(localNetworksCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return networks
+}
+
+func TestLocalNetworksLoad(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "networks.csv")
+	jsonPath := filepath.Join(dir, "networks.JSON")
+	if err := os.WriteFile(csvPath, []byte(localNetworksCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(localNetworksJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ipinfo.LocalNetwork{
+		Network: netip.MustParsePrefix("10.20.0.0/16"),
+		City:    "Berlin",
+		Country: "DE",
+		Org:     "Example Corp",
+		ASN:     "AS64512",
+		Tags:    []string{"office", "vpn"},
+	}
+	for _, path := range []string{csvPath, jsonPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			networks, err := ipinfo.LoadLocalNetworks(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if networks.Len() != 3 {
+				t.Errorf("Len = %d, want 3", networks.Len())
+			}
+			n, ok := networks.Lookup(netip.MustParseAddr("10.20.1.1"))
+			if !ok || !reflect.DeepEqual(n, want) {
+				t.Errorf("Lookup = %+v, %v, want %+v", n, ok, want)
+			}
+			if n, ok := networks.Lookup(netip.MustParseAddr("10.1.1.1")); !ok || n.ASN != "AS64512" || n.Country != "US" {
+				t.Errorf("Lookup = %+v, %v, want the normalized /8", n, ok)
+			}
+		})
+	}
+
+	if _, err := ipinfo.LoadLocalNetworks(filepath.Join(dir, "missing.csv")); !os.IsNotExist(err) {
+		t.Errorf("err = %v for a missing file", err)
+	}
+}
+
+func TestLocalNetworksErrors(t *testing.T) {
+	csvTests := []struct{ name, data, err string }{
+		{"empty", "", "missing header"},
+		{"unknown column", "network,owner\n", `unknown column "owner"`},
+		{"no network column", "city,country\n", `missing column "network"`},
+		{"invalid network", "network\n10.0.0.0/8\n10.0.0.0/33\n", "line 3"},
+		{"ragged", "network,city\n10.0.0.0/8\n", "wrong number of fields"},
+	}
+	for _, tt := range csvTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ipinfo.ReadLocalNetworksCSV(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	jsonTests := []struct{ name, data string }{
+		{"syntax", `[{"network": `},
+		{"unknown field", `[{"network": "10.0.0.0/8", "owner": "x"}]`},
+		{"invalid network", `[{"network": "10.0.0.0"}]`},
+		{"missing network", `[{"city": "Berlin"}]`},
+	}
+	for _, tt := range jsonTests {
+		t.Run("JSON "+tt.name, func(t *testing.T) {
+			if _, err := ipinfo.ReadLocalNetworksJSON(strings.NewReader(tt.data)); err == nil {
+				t.Error("err = nil")
+			}
+		})
+	}
+	// the error of the table names the entry.
+	_, err := ipinfo.NewLocalNetworks(
+		ipinfo.LocalNetwork{Network: netip.MustParsePrefix("10.0.0.0/8")},
+		ipinfo.LocalNetwork{},
+	)
+	if err == nil || !strings.Contains(err.Error(), "local network 2 (invalid Prefix)") || errors.Unwrap(err) == nil {
+		t.Errorf("err = %v, want the wrapped error of network 2", err)
+	}
+}
+
+func TestLocalNetworksLookup(t *testing.T) {
+	networks, err := ipinfo.NewLocalNetworks(
+		ipinfo.LocalNetwork{Network: netip.MustParsePrefix("10.1.2.3/8"), Org: "first", Tags: []string{"a"}},
+		ipinfo.LocalNetwork{Network: netip.MustParsePrefix("10.0.0.0/8"), Org: "last"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host bits are ignored, and the last entry of a network wins.
+	n, ok := networks.Lookup(netip.MustParseAddr("10.9.9.9"))
+	if !ok || n.Org != "last" || n.Network != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Lookup = %+v, %v", n, ok)
+	}
+	// IPv4-mapped addresses match IPv4 networks.
+	if _, ok := networks.Lookup(netip.MustParseAddr("::ffff:10.9.9.9")); !ok {
+		t.Error("IPv4-mapped address not found")
+	}
+	if _, ok := networks.Lookup(netip.MustParseAddr("11.0.0.1")); ok {
+		t.Error("11.0.0.1 found")
+	}
+
+	// results are copies.
+	n.Tags = append(n.Tags, "changed")
+	n, _ = networks.Lookup(netip.MustParseAddr("10.9.9.9"))
+	if len(n.Tags) != 0 {
+		t.Errorf("Tags = %q after changing a result", n.Tags)
+	}
+
+	var nilTable *ipinfo.LocalNetworks
+	if _, ok := nilTable.Lookup(netip.MustParseAddr("10.0.0.1")); ok || nilTable.Len() != 0 {
+		t.Error("nil table isn't empty")
+	}
+	if _, err := ipinfo.NewLocalNetworks(ipinfo.LocalNetwork{}); err == nil {
+		t.Error("err = nil for a network without a prefix")
+	}
+}
+
+func TestLocalNetworksClients(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	opt := ipinfo.WithLocalNetworks(localNetworks(t))
+	ip := net.ParseIP("10.20.1.1")
+
+	core, err := srv.Client(opt).GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if core.Local == nil || core.City != "Berlin" || core.Country != "DE" || core.CountryName != "Germany" ||
+		core.Org != "AS64512 Example Corp" || core.ASN == nil || core.ASN.ASN != "AS64512" || !core.Bogon {
+		t.Errorf("Core = %+v", core)
+	}
+
+	lite, err := srv.LiteClient(opt).GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lite.Local == nil || lite.ASN != "AS64512" || lite.CountryCode != "DE" || lite.Country != "Germany" ||
+		lite.ContinentCode != "EU" || !lite.IsEU || !lite.Bogon {
+		t.Errorf("Lite = %+v", lite)
+	}
+
+	resp, err := srv.CoreClient(opt).GetIPInfo(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Local == nil || resp.Geo == nil || resp.Geo.City != "Berlin" || resp.Geo.CountryName != "Germany" ||
+		resp.AS == nil || resp.AS.Name != "Example Corp" {
+		t.Errorf("CoreResponse = %+v", resp)
+	}
+
+	plus, err := srv.PlusClient(opt).GetIPInfo(net.ParseIP("fd00::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plus.Local == nil || plus.Geo != nil || plus.Company == nil || plus.Company.Name != "IPv6 Lab" ||
+		plus.BogonInfo == nil || plus.BogonInfo.Category != ipinfo.BogonUniqueLocal {
+		t.Errorf("Plus = %+v", plus)
+	}
+
+	// other IPs are looked up by the API.
+	other, err := srv.Client(opt).GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Local != nil {
+		t.Errorf("Local = %+v for 8.8.8.8", other.Local)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || reqs[0].Path != "/8.8.8.8" {
+		t.Errorf("requests = %+v, want one for 8.8.8.8", reqs)
+	}
+}
+
+func TestLocalNetworksBatch(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	client := srv.Client(ipinfo.WithToken("secret"), ipinfo.WithLocalNetworks(localNetworks(t)))
+	batch, err := client.GetIPInfoBatch(
+		[]net.IP{net.ParseIP("10.20.1.1"), net.ParseIP("8.8.8.8"), net.ParseIP("10.1.1.1")},
+		ipinfo.BatchReqOpts{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := batch["10.20.1.1"]; v == nil || v.Local == nil || v.City != "Berlin" {
+		t.Errorf("10.20.1.1 = %+v, want the local network", v)
+	}
+	if v := batch["10.1.1.1"]; v == nil || v.Local == nil || v.Country != "US" {
+		t.Errorf("10.1.1.1 = %+v, want the local network", v)
+	}
+	if v := batch["8.8.8.8"]; v == nil || v.Local != nil {
+		t.Errorf("8.8.8.8 = %+v, want the API result", v)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 || strings.Contains(string(reqs[0].Body), "10.") {
+		t.Errorf("requests = %+v, want one batch without the local IPs", reqs)
+	}
+
+	// a batch of local IPs only needs no request.
+	if _, err := client.GetIPInfoBatch([]net.IP{net.ParseIP("10.0.0.1")}, ipinfo.BatchReqOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want no more", n)
+	}
+}
+
+func TestLocalNetworksFromEnv(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "networks.json")
+	if err := os.WriteFile(path, []byte(localNetworksJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IPINFO_BASE_URL", srv.URL)
+	t.Setenv("IPINFO_LOCAL_NETWORKS", path)
+
+	client, err := ipinfo.NewClientWithOptions(ipinfo.FromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := client.GetIPInfo(net.ParseIP("10.20.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Local == nil || v.City != "Berlin" {
+		t.Errorf("result = %+v, want the local network", v)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests", n)
+	}
+
+	t.Setenv("IPINFO_LOCAL_NETWORKS", filepath.Join(t.TempDir(), "missing.csv"))
+	_, err = ipinfo.NewClientWithOptions(ipinfo.FromEnv())
+	if err == nil || !strings.Contains(err.Error(), "IPINFO_LOCAL_NETWORKS") {
+		t.Errorf("err = %v, want the variable named", err)
+	}
+}
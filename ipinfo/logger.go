@@ -0,0 +1,156 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is a minimal structured logger, taking a message followed by
+// alternating keys and values in the style of `log/slog`. A `*slog.Logger`
+// implements it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+}
+
+// WithLogger sets a logger which receives a debug entry for every request,
+// retry and cache access of the client. Tokens are never logged.
+func WithLogger(l Logger) Option {
+	return WithObserver(&loggingObserver{logger: l})
+}
+
+// NewTextLogger returns a `Logger` which writes entries to `w` as lines of
+// key=value pairs, for use where `log/slog` is not available:
+//
+//	time=2006-01-02T15:04:05.000Z07:00 level=DEBUG msg="ipinfo request" status=200
+func NewTextLogger(w io.Writer) Logger {
+	return &textLogger{w: w}
+}
+
+type textLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *textLogger) Debug(msg string, args ...interface{}) {
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(time.Now().Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteString(" level=DEBUG msg=")
+	b.WriteString(logValue(msg))
+	for i := 0; i < len(args); i += 2 {
+		b.WriteByte(' ')
+		if i+1 == len(args) {
+			b.WriteString("!BADKEY=")
+			b.WriteString(logValue(fmt.Sprint(args[i])))
+			break
+		}
+		b.WriteString(fmt.Sprint(args[i]))
+		b.WriteByte('=')
+		b.WriteString(logValue(fmt.Sprint(args[i+1])))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+// `logValue` quotes `s` if it cannot be logged bare.
+func logValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// loggingObserver is an `Observer` which logs all events to a `Logger`.
+type loggingObserver struct {
+	logger Logger
+}
+
+func (o *loggingObserver) RequestStart(
+	ctx context.Context,
+	e *RequestEvent,
+) context.Context {
+	o.logger.Debug("ipinfo request",
+		"method", e.Method,
+		"url", redactURL(e.URL),
+		"product", metricsProduct(e.Product),
+	)
+	return ctx
+}
+
+func (o *loggingObserver) RequestEnd(ctx context.Context, e *RequestEvent) {
+	args := []interface{}{
+		"method", e.Method,
+		"url", redactURL(e.URL),
+		"status", e.StatusCode,
+		"duration", e.Duration,
+	}
+	if e.Err != nil {
+		args = append(args, "error", e.Err.Error())
+	}
+	o.logger.Debug("ipinfo response", args...)
+}
+
+func (o *loggingObserver) Retry(ctx context.Context, e *RetryEvent) {
+	args := []interface{}{
+		"method", e.Method,
+		"url", redactURL(e.URL),
+		"attempt", e.Attempt,
+		"wait", e.Wait,
+		"status", e.StatusCode,
+	}
+	if e.Err != nil {
+		args = append(args, "error", e.Err.Error())
+	}
+	o.logger.Debug("ipinfo retry", args...)
+}
+
+func (o *loggingObserver) Cache(e *CacheEvent) {
+	args := []interface{}{
+		"op", e.Op.String(),
+		"key", e.Key,
+	}
+	if e.Err != nil {
+		args = append(args, "error", e.Err.Error())
+	}
+	o.logger.Debug("ipinfo cache", args...)
+}
+
+func (o *loggingObserver) BatchChunk(ctx context.Context, e *BatchChunkEvent) {
+	args := []interface{}{
+		"size", e.Size,
+		"duration", e.Duration,
+	}
+	if e.Err != nil {
+		args = append(args, "error", e.Err.Error())
+	}
+	o.logger.Debug("ipinfo batch chunk", args...)
+}
+
+// `redactURL` returns `rawURL` with any token in its query or user info
+// replaced.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if q := u.Query(); q.Has("token") {
+		q.Set("token", "REDACTED")
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// Check if loggingObserver implements Observer
+var _ Observer = (*loggingObserver)(nil)
@@ -0,0 +1,171 @@
+package ipinfo
+
+import (
+	"net"
+	"net/netip"
+)
+
+// Lookuper looks up the details of IPs independently of the API product,
+// so that application code can switch products without changes.
+//
+// It is implemented by all clients, including `FallbackClient` and
+// `AutoClient`.
+type Lookuper interface {
+	// Lookup returns the details for the specified IP.
+	Lookup(ip net.IP) (*IPDetails, error)
+}
+
+// AddrLookuper is a `Lookuper` which also takes IPs as `netip.Addr` and as
+// strings. It is implemented by all clients.
+type AddrLookuper interface {
+	Lookuper
+
+	// LookupAddr is like `Lookup` but takes a `netip.Addr`. An
+	// `*InvalidIPError` is returned if `addr` is not a valid IP without a
+	// zone.
+	LookupAddr(addr netip.Addr) (*IPDetails, error)
+
+	// LookupStr is like `Lookup` but takes a string. An `*InvalidIPError` is
+	// returned if `ip` is not a valid IP.
+	LookupStr(ip string) (*IPDetails, error)
+}
+
+// Lookup returns the details for the specified IP as `IPDetails`.
+func (c *Client) Lookup(ip net.IP) (*IPDetails, error) {
+	v, err := c.GetIPInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return v.Details(), nil
+}
+
+// Lookup returns the lite details for the specified IP as `IPDetails`.
+func (c *LiteClient) Lookup(ip net.IP) (*IPDetails, error) {
+	v, err := c.GetIPInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return v.Details(), nil
+}
+
+// Lookup returns the core details for the specified IP as `IPDetails`.
+func (c *CoreClient) Lookup(ip net.IP) (*IPDetails, error) {
+	v, err := c.GetIPInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return v.Details(), nil
+}
+
+// Lookup returns the plus details for the specified IP as `IPDetails`.
+func (c *PlusClient) Lookup(ip net.IP) (*IPDetails, error) {
+	v, err := c.GetIPInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return v.Details(), nil
+}
+
+// Lookup returns the details for the specified IP from the first tier which
+// answers. It is equivalent to `GetIPInfo`.
+func (c *FallbackClient) Lookup(ip net.IP) (*IPDetails, error) {
+	return c.GetIPInfo(ip)
+}
+
+// Lookup returns the details for the specified IP from the highest tier the
+// token has access to.
+func (c *AutoClient) Lookup(ip net.IP) (*IPDetails, error) {
+	res, err := c.GetIPInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return res.Details(), nil
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *Client) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *Client) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *LiteClient) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *LiteClient) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *CoreClient) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *CoreClient) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *PlusClient) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *PlusClient) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *FallbackClient) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *FallbackClient) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// LookupAddr is like `Lookup` but takes a `netip.Addr`.
+func (c *AutoClient) LookupAddr(addr netip.Addr) (*IPDetails, error) {
+	return lookupAddr(c, addr)
+}
+
+// LookupStr is like `Lookup` but takes a string.
+func (c *AutoClient) LookupStr(ip string) (*IPDetails, error) {
+	return lookupStr(c, ip)
+}
+
+// `lookupAddr` looks up `addr` with `l`, failing if it's invalid.
+func lookupAddr(l Lookuper, addr netip.Addr) (*IPDetails, error) {
+	ip, err := addrIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return l.Lookup(ip)
+}
+
+// `lookupStr` looks up `ip` with `l`, failing if it's invalid.
+func lookupStr(l Lookuper, ip string) (*IPDetails, error) {
+	parsed, err := parseIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return l.Lookup(parsed)
+}
+
+// Check if all clients implement Lookuper and AddrLookuper
+var (
+	_ AddrLookuper = (*Client)(nil)
+	_ AddrLookuper = (*LiteClient)(nil)
+	_ AddrLookuper = (*CoreClient)(nil)
+	_ AddrLookuper = (*PlusClient)(nil)
+	_ AddrLookuper = (*FallbackClient)(nil)
+	_ AddrLookuper = (*AutoClient)(nil)
+)
@@ -0,0 +1,48 @@
+package ipinfo_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestLookuper(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Core", "core"))
+	srv.SetIP("8.8.8.8", &ipinfo.Core{IP: net.ParseIP("8.8.8.8"), City: "Mountain View"})
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{IP: net.ParseIP("8.8.8.8"), CountryCode: "US"})
+	srv.SetPlus("8.8.8.8", &ipinfo.Plus{
+		IP:  net.ParseIP("8.8.8.8"),
+		Geo: &ipinfo.PlusGeo{City: "Mountain View", CountryCode: "US"},
+	})
+
+	tests := []struct {
+		name     string
+		lookuper ipinfo.Lookuper
+		tier     ipinfo.Tier
+	}{
+		{"Client", srv.Client(), ipinfo.TierLegacy},
+		{"LiteClient", srv.LiteClient(), ipinfo.TierLite},
+		{"CoreClient", srv.CoreClient(), ipinfo.TierCore},
+		{"PlusClient", srv.PlusClient(), ipinfo.TierPlus},
+		{"FallbackClient", fallbackClient(srv), ipinfo.TierPlus},
+		{"AutoClient", autoClient(srv), ipinfo.TierCore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.lookuper.Lookup(net.ParseIP("8.8.8.8"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Tier != tt.tier {
+				t.Errorf("Tier = %v, want %v", d.Tier, tt.tier)
+			}
+			if !d.IP.Equal(net.ParseIP("8.8.8.8")) || d.Geo == nil {
+				t.Errorf("details = %+v", d)
+			}
+		})
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net"
+	"net/netip"
 )
 
 // IPMap is the full JSON response from the IP Map API.
 type IPMap struct {
-	Status    string `json:"status"`
-	ReportURL string `json:"reportUrl"`
+	Status    string `json:"status" csv:"status"`
+	ReportURL string `json:"reportUrl" csv:"report_url"`
 }
 
 // GetIPMap returns an IPMap result for a group of IPs.
@@ -34,16 +35,50 @@ func (c *Client) GetIPMap(ips []net.IP) (*IPMap, error) {
 	}
 	jsonBuf := bytes.NewBuffer(jsonArrStr)
 
-	req, err := c.newRequest(nil, "POST", "map?cli=1", jsonBuf)
+	req, err := c.transport().newRequest(nil, "POST", "map?cli=1", jsonBuf)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	result := new(IPMap)
-	if _, err := c.do(req, result); err != nil {
+	if _, err := c.transport().do(req, result); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
+
+// GetIPAddrMap is like `GetIPMap` but takes `netip.Addr`s. An
+// `*InvalidIPError` is returned if any of `addrs` is not a valid IP without a
+// zone.
+func GetIPAddrMap(addrs []netip.Addr) (*IPMap, error) {
+	return DefaultClient.GetIPAddrMap(addrs)
+}
+
+// GetIPAddrMap is like `GetIPMap` but takes `netip.Addr`s. An
+// `*InvalidIPError` is returned if any of `addrs` is not a valid IP without a
+// zone.
+func (c *Client) GetIPAddrMap(addrs []netip.Addr) (*IPMap, error) {
+	ips, err := addrIPs(addrs)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPMap(ips)
+}
+
+// GetIPStrMap is like `GetIPMap` but takes strings. An `*InvalidIPError` is
+// returned if any of `ips` is not a valid IP.
+func GetIPStrMap(ips []string) (*IPMap, error) {
+	return DefaultClient.GetIPStrMap(ips)
+}
+
+// GetIPStrMap is like `GetIPMap` but takes strings. An `*InvalidIPError` is
+// returned if any of `ips` is not a valid IP.
+func (c *Client) GetIPStrMap(ips []string) (*IPMap, error) {
+	parsed, err := parseIPs(ips)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPMap(parsed)
+}
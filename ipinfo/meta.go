@@ -0,0 +1,98 @@
+package ipinfo
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ResponseMeta describes the HTTP exchange behind a single API call.
+//
+// For calls answered without a network request (cache hits, locally detected
+// bogons and local networks), `StatusCode` is 0 and `Header` is nil.
+type ResponseMeta struct {
+	// HTTP status code of the API response.
+	StatusCode int
+
+	// Headers of the API response.
+	Header http.Header
+
+	// Rate limit information reported by the API, or nil if the response
+	// contained no rate limit headers.
+	RateLimit *RateLimit
+
+	// Time taken to answer the call, including decoding.
+	Duration time.Duration
+
+	// Whether the result was served from the client's cache.
+	FromCache bool
+
+	// Raw response body, only set for clients configured with
+	// `WithKeepRawBody`.
+	Body []byte
+}
+
+// RateLimit is the quota information reported in API response headers.
+//
+// Values which the API did not report are left as zero.
+type RateLimit struct {
+	// Total number of requests allowed in the current window.
+	Limit int64
+
+	// Number of requests remaining in the current window.
+	Remaining int64
+
+	// Time at which the current window resets.
+	Reset time.Time
+
+	// Time to wait before retrying, as reported by `Retry-After`.
+	RetryAfter time.Duration
+}
+
+// `parseRateLimit` extracts rate limit information from `h`, returning nil if
+// none of the known headers are present.
+func parseRateLimit(h http.Header, now time.Time) *RateLimit {
+	var rl RateLimit
+	found := false
+
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Limit"), 10, 64); err == nil {
+		rl.Limit = v
+		found = true
+	}
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		rl.Remaining = v
+		found = true
+	}
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		// the header is either a unix timestamp or a number of seconds
+		// relative to now; anything before 2001 is taken as relative.
+		if v > 1e9 {
+			rl.Reset = time.Unix(v, 0)
+		} else {
+			rl.Reset = now.Add(time.Duration(v) * time.Second)
+		}
+		found = true
+	}
+	if ra := h.Get("Retry-After"); ra != "" {
+		if v, err := strconv.ParseInt(ra, 10, 64); err == nil {
+			rl.RetryAfter = time.Duration(v) * time.Second
+			found = true
+		} else if t, err := http.ParseTime(ra); err == nil {
+			rl.RetryAfter = t.Sub(now)
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &rl
+}
+
+// `cachedMeta` returns the metadata for a call answered from the cache.
+func cachedMeta(start time.Time) *ResponseMeta {
+	return &ResponseMeta{
+		FromCache: true,
+		Duration:  time.Since(start),
+	}
+}
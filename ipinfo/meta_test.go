@@ -0,0 +1,173 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestGetIPInfoWithMeta(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View", Country: "US"})
+
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "50000")
+	header.Set("X-RateLimit-Remaining", "49999")
+	header.Set("X-RateLimit-Reset", "60")
+	srv.Fault("/", ipinfotest.Fault{Header: header})
+
+	client := srv.Client()
+	before := time.Now()
+	info, meta, err := client.GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q, want %q", info.City, "Mountain View")
+	}
+	if meta.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", meta.StatusCode, http.StatusOK)
+	}
+	if meta.Header.Get("X-RateLimit-Limit") != "50000" {
+		t.Errorf("Header lacks the rate limit headers: %v", meta.Header)
+	}
+	if meta.FromCache {
+		t.Error("FromCache = true for a network request")
+	}
+	if meta.Duration <= 0 {
+		t.Errorf("Duration = %v, want > 0", meta.Duration)
+	}
+	if meta.Body != nil {
+		t.Errorf("Body = %q without KeepRawBody", meta.Body)
+	}
+
+	rl := meta.RateLimit
+	if rl == nil {
+		t.Fatal("RateLimit = nil")
+	}
+	if rl.Limit != 50000 || rl.Remaining != 49999 {
+		t.Errorf("RateLimit = %d/%d, want 49999/50000", rl.Remaining, rl.Limit)
+	}
+	if rl.Reset.Before(before.Add(59*time.Second)) || rl.Reset.After(time.Now().Add(61*time.Second)) {
+		t.Errorf("RateLimit.Reset = %v, want about a minute from now", rl.Reset)
+	}
+}
+
+func TestGetIPInfoWithMetaRateLimited(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 30*time.Second)
+
+	_, meta, err := srv.Client().GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err == nil {
+		t.Fatal("err = nil for a 429 response")
+	}
+	if meta == nil {
+		t.Fatal("meta = nil for an API error")
+	}
+	if meta.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("StatusCode = %d, want %d", meta.StatusCode, http.StatusTooManyRequests)
+	}
+	if meta.RateLimit == nil || meta.RateLimit.RetryAfter != 30*time.Second {
+		t.Errorf("RateLimit = %+v, want RetryAfter 30s", meta.RateLimit)
+	}
+}
+
+func TestGetIPInfoWithMetaRawBody(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+
+	_, meta, err := srv.Client(ipinfo.WithKeepRawBody()).GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(meta.Body, &raw); err != nil {
+		t.Fatalf("Body is not the JSON response: %v: %q", err, meta.Body)
+	}
+	if raw["city"] != "Mountain View" {
+		t.Errorf("Body = %s, want the city", meta.Body)
+	}
+}
+
+func TestGetIPInfoWithMetaFromCache(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client(ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())))
+	if _, _, err := client.GetIPInfoWithMeta(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	_, meta, err := client.GetIPInfoWithMeta(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.FromCache {
+		t.Error("FromCache = false for a cached result")
+	}
+	if meta.StatusCode != 0 || meta.Header != nil {
+		t.Errorf("StatusCode = %d, Header = %v for a cached result", meta.StatusCode, meta.Header)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestGetIPInfoWithMetaBogon(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	info, meta, err := srv.Client().GetIPInfoWithMeta(net.ParseIP("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Bogon {
+		t.Error("Bogon = false for 10.0.0.1")
+	}
+	if meta.StatusCode != 0 || meta.FromCache {
+		t.Errorf("meta = %+v for a locally answered bogon", meta)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server received %d requests for a bogon", n)
+	}
+}
+
+func TestWithMetaAllClients(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetLite("1.1.1.1", &ipinfo.Lite{CountryCode: "AU"})
+
+	ip := net.ParseIP("1.1.1.1")
+	check := func(name string, meta *ipinfo.ResponseMeta, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		if meta == nil || meta.StatusCode != http.StatusOK {
+			t.Errorf("%s: meta = %+v, want status 200", name, meta)
+		}
+	}
+
+	lite, meta, err := srv.LiteClient().GetIPInfoWithMeta(ip)
+	check("Lite", meta, err)
+	if err == nil && lite.CountryCode != "AU" {
+		t.Errorf("Lite: CountryCode = %q, want %q", lite.CountryCode, "AU")
+	}
+	_, meta, err = srv.CoreClient().GetIPInfoWithMeta(ip)
+	check("Core", meta, err)
+	_, meta, err = srv.PlusClient().GetIPInfoWithMeta(ip)
+	check("Plus", meta, err)
+	_, meta, err = srv.Client().GetASNDetailsWithMeta("AS15169")
+	check("ASN", meta, err)
+	_, meta, err = srv.Client().GetResproxyWithMeta("1.1.1.1")
+	check("Resproxy", meta, err)
+}
@@ -0,0 +1,258 @@
+package ipinfo
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Upper bounds, in seconds, of the request duration histogram buckets.
+var metricsDurationBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// Metrics is an `Observer` which counts requests, retries, cache accesses
+// and batch chunks, and exports them through `expvar` or in the Prometheus
+// text format, without depending on a metrics library:
+//
+//	metrics := ipinfo.NewMetrics()
+//	client, err := ipinfo.NewClientWithOptions(ipinfo.WithObserver(metrics))
+//	...
+//	http.Handle("/metrics", metrics)
+//
+// A single `Metrics` may observe several clients.
+type Metrics struct {
+	NopObserver
+
+	mu          sync.Mutex
+	requests    map[metricsRequestKey]uint64
+	durations   map[string]*metricsHistogram
+	retries     map[string]uint64
+	cache       map[CacheOp]uint64
+	chunks      uint64
+	chunkErrors uint64
+	chunkURLs   uint64
+}
+
+type metricsRequestKey struct {
+	product string
+	code    string
+}
+
+type metricsHistogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewMetrics returns a new metrics observer with all counts at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[metricsRequestKey]uint64),
+		durations: make(map[string]*metricsHistogram),
+		retries:   make(map[string]uint64),
+		cache:     make(map[CacheOp]uint64),
+	}
+}
+
+// RequestEnd counts the request by product and status code, and records
+// its duration.
+func (m *Metrics) RequestEnd(ctx context.Context, e *RequestEvent) {
+	code := "error"
+	if e.StatusCode != 0 {
+		code = strconv.Itoa(e.StatusCode)
+	}
+	product := metricsProduct(e.Product)
+	seconds := e.Duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[metricsRequestKey{product: product, code: code}]++
+	h := m.durations[product]
+	if h == nil {
+		h = &metricsHistogram{buckets: make([]uint64, len(metricsDurationBuckets))}
+		m.durations[product] = h
+	}
+	for i, le := range metricsDurationBuckets {
+		if seconds <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Retry counts the retry by the status code of the failed attempt.
+func (m *Metrics) Retry(ctx context.Context, e *RetryEvent) {
+	code := "error"
+	if e.StatusCode != 0 {
+		code = strconv.Itoa(e.StatusCode)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[code]++
+}
+
+// Cache counts the cache access by kind.
+func (m *Metrics) Cache(e *CacheEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache[e.Op]++
+}
+
+// BatchChunk counts the chunk and its URLs.
+func (m *Metrics) BatchChunk(ctx context.Context, e *BatchChunkEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunks++
+	m.chunkURLs += uint64(e.Size)
+	if e.Err != nil {
+		m.chunkErrors++
+	}
+}
+
+// WritePrometheus writes the metrics to `w` in the Prometheus text
+// exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP ipinfo_requests_total API requests by product and status code.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_requests_total counter")
+	keys := make([]metricsRequestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].product != keys[j].product {
+			return keys[i].product < keys[j].product
+		}
+		return keys[i].code < keys[j].code
+	})
+	for _, k := range keys {
+		fmt.Fprintf(bw, "ipinfo_requests_total{product=%q,code=%q} %d\n",
+			k.product, k.code, m.requests[k])
+	}
+
+	fmt.Fprintln(bw, "# HELP ipinfo_request_duration_seconds API request durations by product.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_request_duration_seconds histogram")
+	for _, product := range sortedKeys(m.durations) {
+		h := m.durations[product]
+		for i, le := range metricsDurationBuckets {
+			fmt.Fprintf(bw, "ipinfo_request_duration_seconds_bucket{product=%q,le=%q} %d\n",
+				product, strconv.FormatFloat(le, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(bw, "ipinfo_request_duration_seconds_bucket{product=%q,le=\"+Inf\"} %d\n",
+			product, h.count)
+		fmt.Fprintf(bw, "ipinfo_request_duration_seconds_sum{product=%q} %g\n", product, h.sum)
+		fmt.Fprintf(bw, "ipinfo_request_duration_seconds_count{product=%q} %d\n", product, h.count)
+	}
+
+	fmt.Fprintln(bw, "# HELP ipinfo_retries_total Retried requests by status code of the failed attempt.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_retries_total counter")
+	for _, code := range sortedKeys(m.retries) {
+		fmt.Fprintf(bw, "ipinfo_retries_total{code=%q} %d\n", code, m.retries[code])
+	}
+
+	fmt.Fprintln(bw, "# HELP ipinfo_cache_operations_total Cache accesses by result.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_cache_operations_total counter")
+	for _, op := range []CacheOp{CacheHit, CacheMiss, CacheSet, CacheError} {
+		fmt.Fprintf(bw, "ipinfo_cache_operations_total{op=%q} %d\n", op, m.cache[op])
+	}
+
+	fmt.Fprintln(bw, "# HELP ipinfo_batch_chunks_total Completed batch request chunks.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_batch_chunks_total counter")
+	fmt.Fprintf(bw, "ipinfo_batch_chunks_total %d\n", m.chunks)
+	fmt.Fprintln(bw, "# HELP ipinfo_batch_chunk_errors_total Failed batch request chunks.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_batch_chunk_errors_total counter")
+	fmt.Fprintf(bw, "ipinfo_batch_chunk_errors_total %d\n", m.chunkErrors)
+	fmt.Fprintln(bw, "# HELP ipinfo_batch_urls_total URLs sent in batch request chunks.")
+	fmt.Fprintln(bw, "# TYPE ipinfo_batch_urls_total counter")
+	fmt.Fprintf(bw, "ipinfo_batch_urls_total %d\n", m.chunkURLs)
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// Snapshot returns the current metrics as a JSON-friendly map, as published
+// by `Publish`.
+func (m *Metrics) Snapshot() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := make(map[string]map[string]uint64)
+	for k, n := range m.requests {
+		if requests[k.product] == nil {
+			requests[k.product] = make(map[string]uint64)
+		}
+		requests[k.product][k.code] = n
+	}
+	durations := make(map[string]map[string]interface{})
+	for product, h := range m.durations {
+		durations[product] = map[string]interface{}{
+			"count":       h.count,
+			"sum_seconds": h.sum,
+		}
+	}
+	retries := make(map[string]uint64, len(m.retries))
+	for code, n := range m.retries {
+		retries[code] = n
+	}
+	cache := make(map[string]uint64, len(m.cache))
+	for _, op := range []CacheOp{CacheHit, CacheMiss, CacheSet, CacheError} {
+		cache[op.String()] = m.cache[op]
+	}
+
+	return map[string]interface{}{
+		"requests":           requests,
+		"request_durations":  durations,
+		"retries":            retries,
+		"cache":              cache,
+		"batch_chunks":       m.chunks,
+		"batch_chunk_errors": m.chunkErrors,
+		"batch_urls":         m.chunkURLs,
+	}
+}
+
+// Publish publishes the metrics as the `expvar` variable `name`. Like
+// `expvar.Publish`, it panics if the name is already in use.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+// `metricsProduct` returns the label value for the API product `product`.
+func metricsProduct(product string) string {
+	if product == "" {
+		return "legacy"
+	}
+	return product
+}
+
+// `sortedKeys` returns the keys of `m` in ascending order.
+func sortedKeys[V interface{}](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Check if Metrics implements Observer
+var _ Observer = (*Metrics)(nil)
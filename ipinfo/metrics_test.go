@@ -0,0 +1,125 @@
+package ipinfo_test
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `observeTraffic` sends a few requests observed by `metrics` to `srv`: a
+// retried and a cached lookup of the legacy API, a Lite lookup and a batch
+// of two chunks.
+func observeTraffic(t *testing.T, srv *ipinfotest.Server, metrics *ipinfo.Metrics) {
+	t.Helper()
+	srv.RequireToken("secret")
+	srv.RateLimit("/8.8.8.8", 1, 0)
+
+	client := srv.Client(
+		ipinfo.WithObserver(metrics),
+		ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := srv.LiteClient(ipinfo.WithObserver(metrics)).GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	ips := []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("9.9.9.9")}
+	if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{BatchSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetricsPrometheus(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	metrics := ipinfo.NewMetrics()
+	observeTraffic(t, srv, metrics)
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		`ipinfo_requests_total{product="legacy",code="200"} 3`,
+		`ipinfo_requests_total{product="lite",code="200"} 1`,
+		`ipinfo_request_duration_seconds_count{product="legacy"} 3`,
+		`ipinfo_request_duration_seconds_bucket{product="lite",le="+Inf"} 1`,
+		`ipinfo_retries_total{code="429"} 1`,
+		`ipinfo_cache_operations_total{op="hit"} 1`,
+		`ipinfo_cache_operations_total{op="miss"} 3`,
+		`ipinfo_cache_operations_total{op="set"} 3`,
+		`ipinfo_cache_operations_total{op="error"} 0`,
+		`ipinfo_batch_chunks_total 2`,
+		`ipinfo_batch_chunk_errors_total 0`,
+		`ipinfo_batch_urls_total 2`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("output lacks %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	metrics := ipinfo.NewMetrics()
+	metrics.RequestEnd(context.Background(), &ipinfo.RequestEvent{Product: "plus", Err: net.ErrClosed})
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `ipinfo_requests_total{product="plus",code="error"} 1`) {
+		t.Errorf("body lacks the failed request:\n%s", body)
+	}
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	metrics := ipinfo.NewMetrics()
+	observeTraffic(t, srv, metrics)
+
+	snap := metrics.Snapshot()
+	requests := snap["requests"].(map[string]map[string]uint64)
+	if requests["legacy"]["200"] != 3 || requests["lite"]["200"] != 1 {
+		t.Errorf("requests = %v", requests)
+	}
+	if retries := snap["retries"].(map[string]uint64); retries["429"] != 1 {
+		t.Errorf("retries = %v", retries)
+	}
+	if c := snap["cache"].(map[string]uint64); c["hit"] != 1 || c["miss"] != 3 {
+		t.Errorf("cache = %v", c)
+	}
+	if snap["batch_chunks"] != uint64(2) || snap["batch_urls"] != uint64(2) {
+		t.Errorf("batch_chunks = %v, batch_urls = %v", snap["batch_chunks"], snap["batch_urls"])
+	}
+}
+
+func TestMetricsPublish(t *testing.T) {
+	metrics := ipinfo.NewMetrics()
+	metrics.Cache(&ipinfo.CacheEvent{Op: ipinfo.CacheHit})
+	metrics.Publish("ipinfo_test_metrics")
+
+	v := expvar.Get("ipinfo_test_metrics")
+	if v == nil {
+		t.Fatal("metrics not published")
+	}
+	if s := v.String(); !strings.Contains(s, `"hit":1`) {
+		t.Errorf("published %s, want the cache hit", s)
+	}
+}
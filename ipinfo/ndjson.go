@@ -0,0 +1,218 @@
+package ipinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// NDJSONWriter writes results as newline-delimited JSON, one result per
+// line, as soon as each is written. This suits streaming output of large
+// lookups, since no result is buffered.
+//
+// Empty fields, i.e. null, false, 0, "" and empty objects and arrays, are left
+// out of every result, so that all result types omit empty fields alike
+// regardless of the `omitempty` options of their `json` tags.
+type NDJSONWriter struct {
+	w io.Writer
+}
+
+// NewNDJSONWriter returns an NDJSON writer writing to `w`.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+// Write writes `v` as a line of JSON.
+func (w *NDJSONWriter) Write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tree, err := decodeJSONTree(data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if isEmptyNode(tree) {
+		buf.WriteString("{}")
+	} else if err := writeJSONTree(&buf, tree); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.w.Write(buf.Bytes())
+	return err
+}
+
+// WriteBatch writes the results of `batch`, such as a `BatchCore`, `Batch`
+// or `BatchASNDetails`, as one line each, sorted by key. The keys themselves
+// are not written, as results carry their IP or ASN. Nil results are
+// skipped.
+func (w *NDJSONWriter) WriteBatch(batch interface{}) error {
+	keys, values, err := batchEntries(batch)
+	if err != nil {
+		return err
+	}
+	for i := range keys {
+		if err := w.Write(values[i].Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// `batchEntries` returns the keys of the map `batch` with non-nil values in
+// sorted order, and their values.
+func batchEntries(batch interface{}) ([]string, []reflect.Value, error) {
+	m := reflect.ValueOf(batch)
+	if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+		return nil, nil, fmt.Errorf("ipinfo: %T is not a batch result", batch)
+	}
+
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		v := m.MapIndex(k)
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if v.IsNil() {
+				continue
+			}
+		}
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	values := make([]reflect.Value, len(keys))
+	for i, k := range keys {
+		values[i] = m.MapIndex(reflect.ValueOf(k).Convert(m.Type().Key()))
+	}
+	return keys, values, nil
+}
+
+/* TREES */
+
+// orderedMap is an object of an encoding tree, which retains the order of its
+// keys. The nodes of an encoding tree are `*orderedMap`, `[]interface{}` and
+// scalar values.
+type orderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *orderedMap) add(key string, value interface{}) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// `decodeJSONTree` decodes the JSON value in `data` into an encoding tree,
+// leaving out empty object members. Numbers are decoded as `json.Number`.
+func decodeJSONTree(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeJSONNode(dec)
+}
+
+func decodeJSONNode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		m := &orderedMap{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if !isEmptyNode(val) {
+				m.add(key.(string), val)
+			}
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decodeJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// `isEmptyNode` reports whether the node `v` of an encoding tree is empty.
+func isEmptyNode(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case *orderedMap:
+		return len(v.keys) == 0
+	case []interface{}:
+		return len(v) == 0
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	}
+	return false
+}
+
+// `writeJSONTree` writes the encoding tree `v` as compact JSON.
+func writeJSONTree(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *orderedMap:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			data, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+			buf.WriteByte(':')
+			if err := writeJSONTree(buf, v.values[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONTree(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
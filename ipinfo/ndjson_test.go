@@ -0,0 +1,88 @@
+package ipinfo_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			"Core",
+			&ipinfo.Core{
+				IP:      net.ParseIP("8.8.8.8"),
+				ASN:     &ipinfo.CoreASN{ASN: "AS15169"},
+				Privacy: &ipinfo.CorePrivacy{},
+			},
+			`{"ip":"8.8.8.8","asn":{"asn":"AS15169"}}`,
+		},
+		{"empty Core", &ipinfo.Core{}, `{}`},
+		{"nil", nil, `{}`},
+		{"Lite", &ipinfo.Lite{ASN: "AS15169"}, `{"asn":"AS15169"}`},
+		{"CoreResponse", &ipinfo.CoreResponse{Geo: &ipinfo.CoreGeo{City: "Paris"}}, `{"geo":{"city":"Paris"}}`},
+		{"Plus", &ipinfo.Plus{Geo: &ipinfo.PlusGeo{Latitude: 48.85}}, `{"geo":{"latitude":48.85}}`},
+		{"ASNDetails", &ipinfo.ASNDetails{ASN: "AS1", Peers: []string{}}, `{"asn":"AS1"}`},
+		{"ResproxyDetails", &ipinfo.ResproxyDetails{IP: "1.2.3.4", PercentDaysSeen: 0}, `{"ip":"1.2.3.4"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ipinfo.NewNDJSONWriter(&buf).Write(tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("output = %q, want %q", got, tt.want+"\n")
+			}
+		})
+	}
+}
+
+func TestNDJSONWriterBatch(t *testing.T) {
+	var buf bytes.Buffer
+	w := ipinfo.NewNDJSONWriter(&buf)
+	err := w.WriteBatch(ipinfo.BatchCore{
+		"8.8.8.8": {City: "Mountain View"},
+		"1.1.1.1": {City: "Sydney"},
+		"9.9.9.9": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteBatch(ipinfo.Batch{"AS1": &ipinfo.ASNDetails{Name: "One"}, "x": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"city":"Sydney"}
+{"city":"Mountain View"}
+{"name":"One"}
+`
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	if err := w.WriteBatch([]string{"8.8.8.8"}); err == nil {
+		t.Error("err = nil for a non-batch value")
+	}
+}
+
+func TestNDJSONWriterStreams(t *testing.T) {
+	// each result is written through as soon as it is written.
+	var buf bytes.Buffer
+	w := ipinfo.NewNDJSONWriter(&buf)
+	for i, asn := range []string{"AS1", "AS2", "AS3"} {
+		if err := w.Write(&ipinfo.Lite{ASN: asn}); err != nil {
+			t.Fatal(err)
+		}
+		if n := bytes.Count(buf.Bytes(), []byte("\n")); n != i+1 {
+			t.Fatalf("%d lines written after %d results", n, i+1)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+)
+
+// Observer receives events about the operations of a client, e.g. to record
+// metrics or traces. Observers are set with `WithObserver`.
+//
+// Implementations must be concurrency-safe and should embed `NopObserver` so
+// that they only need to implement the events they are interested in and
+// keep compiling when events are added.
+type Observer interface {
+	// RequestStart is called before an API request is sent. The returned
+	// context is used for the request and passed to `RequestEnd`, so that
+	// e.g. a span can be started and propagated.
+	RequestStart(ctx context.Context, e *RequestEvent) context.Context
+
+	// RequestEnd is called once the response to an API request has been
+	// decoded or the request has failed.
+	RequestEnd(ctx context.Context, e *RequestEvent)
+
+	// Retry is called before a failed request is retried.
+	Retry(ctx context.Context, e *RetryEvent)
+
+	// Cache is called on every access of the cache of the client.
+	Cache(e *CacheEvent)
+
+	// BatchChunk is called when a chunk of a batch request completes.
+	BatchChunk(ctx context.Context, e *BatchChunkEvent)
+}
+
+// RequestEvent describes an API request.
+type RequestEvent struct {
+	// HTTP method of the request.
+	Method string
+
+	// URL of the request. It never contains the API token, which is sent in
+	// a header.
+	URL string
+
+	// API product of the client, e.g. "lite"; empty for the legacy API.
+	Product string
+
+	// HTTP status code of the response; 0 if there was none. Only set in
+	// `RequestEnd`.
+	StatusCode int
+
+	// Time taken by the request, including retries and decoding. Only set
+	// in `RequestEnd`.
+	Duration time.Duration
+
+	// Error the request failed with, if any. Only set in `RequestEnd`.
+	Err error
+}
+
+// RetryEvent describes the retry of a failed request.
+type RetryEvent struct {
+	// HTTP method and URL of the request.
+	Method string
+	URL    string
+
+	// Number of the attempt which failed, starting at 1.
+	Attempt int
+
+	// Wait before the next attempt.
+	Wait time.Duration
+
+	// HTTP status code of the failed attempt; 0 if there was no response.
+	StatusCode int
+
+	// Error of the failed attempt, if there was no response.
+	Err error
+}
+
+// CacheOp is the kind of a cache access.
+type CacheOp int
+
+const (
+	// CacheHit is a lookup which found a value.
+	CacheHit CacheOp = iota
+
+	// CacheMiss is a lookup which found no value.
+	CacheMiss
+
+	// CacheSet is a successful store of a value.
+	CacheSet
+
+	// CacheError is a lookup or store which failed.
+	CacheError
+)
+
+func (op CacheOp) String() string {
+	switch op {
+	case CacheHit:
+		return "hit"
+	case CacheMiss:
+		return "miss"
+	case CacheSet:
+		return "set"
+	case CacheError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// CacheEvent describes an access of the cache of a client.
+type CacheEvent struct {
+	// Kind of access.
+	Op CacheOp
+
+	// Cache key accessed.
+	Key string
+
+	// Error of the access, only set for `CacheError`.
+	Err error
+}
+
+// BatchChunkEvent describes a completed chunk of a batch request.
+type BatchChunkEvent struct {
+	// Number of URLs in the chunk.
+	Size int
+
+	// Time taken by the chunk.
+	Duration time.Duration
+
+	// Error the chunk failed with, if any.
+	Err error
+}
+
+// NopObserver is an `Observer` which ignores all events. Embed it in
+// observers which only handle some events.
+type NopObserver struct{}
+
+// RequestStart returns `ctx` unchanged.
+func (NopObserver) RequestStart(ctx context.Context, e *RequestEvent) context.Context {
+	return ctx
+}
+
+// RequestEnd does nothing.
+func (NopObserver) RequestEnd(ctx context.Context, e *RequestEvent) {}
+
+// Retry does nothing.
+func (NopObserver) Retry(ctx context.Context, e *RetryEvent) {}
+
+// Cache does nothing.
+func (NopObserver) Cache(e *CacheEvent) {}
+
+// BatchChunk does nothing.
+func (NopObserver) BatchChunk(ctx context.Context, e *BatchChunkEvent) {}
+
+// observers dispatches events to several observers.
+type observers []Observer
+
+func (o observers) requestStart(ctx context.Context, e *RequestEvent) context.Context {
+	for _, obs := range o {
+		ctx = obs.RequestStart(ctx, e)
+	}
+	return ctx
+}
+
+func (o observers) requestEnd(ctx context.Context, e *RequestEvent) {
+	// end in reverse order, so that nested spans end innermost first.
+	for i := len(o) - 1; i >= 0; i-- {
+		o[i].RequestEnd(ctx, e)
+	}
+}
+
+func (o observers) retry(ctx context.Context, e *RetryEvent) {
+	for _, obs := range o {
+		obs.Retry(ctx, e)
+	}
+}
+
+func (o observers) cache(e *CacheEvent) {
+	for _, obs := range o {
+		obs.Cache(e)
+	}
+}
+
+func (o observers) batchChunk(ctx context.Context, e *BatchChunkEvent) {
+	for _, obs := range o {
+		obs.BatchChunk(ctx, e)
+	}
+}
+
+// observedCache is a cache engine which reports every access to observers.
+type observedCache struct {
+	cache.Interface
+	observers observers
+}
+
+func (c *observedCache) Get(key string) (interface{}, error) {
+	v, err := c.Interface.Get(key)
+	switch {
+	case err == nil:
+		c.observers.cache(&CacheEvent{Op: CacheHit, Key: key})
+	case errors.Is(err, cache.ErrNotFound):
+		c.observers.cache(&CacheEvent{Op: CacheMiss, Key: key})
+	default:
+		c.observers.cache(&CacheEvent{Op: CacheError, Key: key, Err: err})
+	}
+	return v, err
+}
+
+func (c *observedCache) Set(key string, value interface{}) error {
+	err := c.Interface.Set(key, value)
+	if err != nil {
+		c.observers.cache(&CacheEvent{Op: CacheError, Key: key, Err: err})
+	} else {
+		c.observers.cache(&CacheEvent{Op: CacheSet, Key: key})
+	}
+	return err
+}
+
+// `statusCode` returns the status code of `resp`, or 0 if it is nil.
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
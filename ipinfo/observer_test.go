@@ -0,0 +1,210 @@
+package ipinfo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/cache"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// eventRecorder is an `Observer` recording the events it receives as
+// strings, prefixed with its name.
+type eventRecorder struct {
+	name string
+
+	mu     sync.Mutex
+	events *[]string
+}
+
+func newEventRecorder(name string, events *[]string) *eventRecorder {
+	return &eventRecorder{name: name, events: events}
+}
+
+func (r *eventRecorder) add(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.events = append(*r.events, r.name+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *eventRecorder) RequestStart(ctx context.Context, e *ipinfo.RequestEvent) context.Context {
+	r.add("start %s %s", e.Method, e.URL)
+	return ctx
+}
+
+func (r *eventRecorder) RequestEnd(ctx context.Context, e *ipinfo.RequestEvent) {
+	r.add("end %d %v", e.StatusCode, e.Err != nil)
+}
+
+func (r *eventRecorder) Retry(ctx context.Context, e *ipinfo.RetryEvent) {
+	r.add("retry %d %d", e.Attempt, e.StatusCode)
+}
+
+func (r *eventRecorder) Cache(e *ipinfo.CacheEvent) {
+	r.add("cache %s %s", e.Op, e.Key)
+}
+
+func (r *eventRecorder) BatchChunk(ctx context.Context, e *ipinfo.BatchChunkEvent) {
+	r.add("chunk %d %v", e.Size, e.Err != nil)
+}
+
+func TestObserver(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var events []string
+	client := srv.LiteClient(
+		ipinfo.WithObserver(newEventRecorder("obs", &events)),
+		ipinfo.WithCache(ipinfo.NewCache(cache.NewInMemory())),
+		ipinfo.WithToken("secret"),
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(events) == 0 || !strings.HasPrefix(events[0], "obs cache miss lite:8.8.8.8") {
+		t.Fatalf("events = %q, want a cache miss first", events)
+	}
+	key := strings.TrimPrefix(events[0], "obs cache miss ")
+	want := []string{
+		"obs cache miss " + key,
+		"obs start GET " + srv.URL + "/lite/8.8.8.8",
+		"obs end 200 false",
+		"obs cache set " + key,
+		"obs cache hit " + key,
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	for _, e := range events {
+		if strings.Contains(e, "secret") {
+			t.Errorf("event %q contains the token", e)
+		}
+	}
+}
+
+func TestObserverRetry(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 0)
+
+	var events []string
+	client := srv.Client(
+		ipinfo.WithObserver(newEventRecorder("obs", &events)),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"obs start GET " + srv.URL + "/8.8.8.8",
+		"obs retry 1 429",
+		"obs end 200 false",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestObserverOrder(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var events []string
+	client := srv.CoreClient(ipinfo.WithObserver(
+		newEventRecorder("outer", &events),
+		newEventRecorder("inner", &events),
+	))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	url := srv.URL + "/lookup/8.8.8.8"
+	want := []string{
+		"outer start GET " + url,
+		"inner start GET " + url,
+		"inner end 200 false",
+		"outer end 200 false",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestObserverBatch(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	var events []string
+	client := srv.Client(ipinfo.WithObserver(newEventRecorder("obs", &events)))
+	ips := []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1"), net.ParseIP("9.9.9.9")}
+	if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{BatchSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := map[string]int{}
+	for _, e := range events {
+		if strings.HasPrefix(e, "obs chunk") {
+			chunks[e]++
+		}
+	}
+	if want := map[string]int{"obs chunk 2 false": 1, "obs chunk 1 false": 1}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunk events = %v, want %v", chunks, want)
+	}
+}
+
+// failingCache is a cache engine whose every access fails.
+type failingCache struct{}
+
+var errCacheDown = errors.New("cache down")
+
+func (failingCache) Get(key string) (interface{}, error) { return nil, errCacheDown }
+
+func (failingCache) Set(key string, value interface{}) error { return errCacheDown }
+
+func TestObserverCacheError(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var events []string
+	client := srv.PlusClient(
+		ipinfo.WithObserver(newEventRecorder("obs", &events)),
+		ipinfo.WithCache(ipinfo.NewCache(failingCache{})),
+	)
+	client.GetIPInfo(net.ParseIP("8.8.8.8"))
+
+	n := 0
+	for _, e := range events {
+		if strings.HasPrefix(e, "obs cache error") {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("events = %q, want the failed get and set", events)
+	}
+}
+
+func TestCacheOpString(t *testing.T) {
+	for op, want := range map[ipinfo.CacheOp]string{
+		ipinfo.CacheHit:   "hit",
+		ipinfo.CacheMiss:  "miss",
+		ipinfo.CacheSet:   "set",
+		ipinfo.CacheError: "error",
+		ipinfo.CacheOp(9): "unknown",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("String of %d = %q, want %q", int(op), got, want)
+		}
+	}
+}
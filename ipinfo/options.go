@@ -0,0 +1,419 @@
+package ipinfo
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option configures a client created with one of the `New...WithOptions`
+// constructors.
+type Option func(c *clientConfig) error
+
+// NewClientWithOptions returns a new IPinfo API client configured by `opts`.
+//
+// Without options, the client is equivalent to `NewClient(nil, nil, "")`.
+func NewClientWithOptions(opts ...Option) (*Client, error) {
+	t, err := newTransportWithOptions(defaultBaseURL, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(t), nil
+}
+
+// NewLiteClientWithOptions returns a new IPinfo Lite API client configured by
+// `opts`.
+func NewLiteClientWithOptions(opts ...Option) (*LiteClient, error) {
+	t, err := newTransportWithOptions(defaultLiteBaseURL, "LITE", opts)
+	if err != nil {
+		return nil, err
+	}
+	return newLiteClient(t), nil
+}
+
+// NewCoreClientWithOptions returns a new IPinfo Core API client configured by
+// `opts`.
+func NewCoreClientWithOptions(opts ...Option) (*CoreClient, error) {
+	t, err := newTransportWithOptions(defaultCoreBaseURL, "CORE", opts)
+	if err != nil {
+		return nil, err
+	}
+	return newCoreClient(t), nil
+}
+
+// NewPlusClientWithOptions returns a new IPinfo Plus API client configured by
+// `opts`.
+func NewPlusClientWithOptions(opts ...Option) (*PlusClient, error) {
+	t, err := newTransportWithOptions(defaultPlusBaseURL, "PLUS", opts)
+	if err != nil {
+		return nil, err
+	}
+	return newPlusClient(t), nil
+}
+
+// WithOverrides returns a client which shares the HTTP client, cache and
+// hooks of `c` but has `opts` applied to a copy of its configuration, e.g. to
+// make a single call with a different token or without the cache:
+//
+//	uncached, err := c.WithOverrides(ipinfo.WithoutCache())
+//
+// Later changes to the configuration of `c` don't affect the returned client.
+func (c *Client) WithOverrides(opts ...Option) (*Client, error) {
+	t, err := c.transport().derive(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(t), nil
+}
+
+// WithOverrides returns a client with `opts` applied to a copy of the
+// configuration of `c`. See `Client.WithOverrides`.
+func (c *LiteClient) WithOverrides(opts ...Option) (*LiteClient, error) {
+	t, err := c.transport().derive(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newLiteClient(t), nil
+}
+
+// WithOverrides returns a client with `opts` applied to a copy of the
+// configuration of `c`. See `Client.WithOverrides`.
+func (c *CoreClient) WithOverrides(opts ...Option) (*CoreClient, error) {
+	t, err := c.transport().derive(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newCoreClient(t), nil
+}
+
+// WithOverrides returns a client with `opts` applied to a copy of the
+// configuration of `c`. See `Client.WithOverrides`.
+func (c *PlusClient) WithOverrides(opts ...Option) (*PlusClient, error) {
+	t, err := c.transport().derive(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newPlusClient(t), nil
+}
+
+func newTransportWithOptions(
+	baseURL string,
+	product string,
+	opts []Option,
+) (*transport, error) {
+	cfg := newConfig(nil, baseURL, product, nil, "")
+	if product == "" {
+		cfg.baseURLIPv6, _ = url.Parse(defaultBaseURLIPv6)
+	}
+	if err := cfg.apply(opts); err != nil {
+		return nil, err
+	}
+	return newTransportFromConfig(cfg), nil
+}
+
+// WithHTTPClient sets the HTTP client used to communicate with the API.
+//
+// If `httpClient` is nil, `http.DefaultClient` will be used.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *clientConfig) error {
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		c.client = httpClient
+		return nil
+	}
+}
+
+// WithBaseURL sets the base URL for API requests. A trailing slash is added
+// if missing.
+func WithBaseURL(baseURL string) Option {
+	return func(c *clientConfig) error {
+		u, err := parseBaseURL(baseURL)
+		if err != nil {
+			return err
+		}
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithIPv6BaseURL sets the base URL for requests which must be sent over
+// IPv6, e.g. by `Client.GetIPInfoV6`. A trailing slash is added if missing.
+func WithIPv6BaseURL(baseURL string) Option {
+	return func(c *clientConfig) error {
+		u, err := parseBaseURL(baseURL)
+		if err != nil {
+			return err
+		}
+		c.baseURLIPv6 = u
+		return nil
+	}
+}
+
+// WithUserAgent sets the user agent used when communicating with the API.
+func WithUserAgent(userAgent string) Option {
+	return func(c *clientConfig) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithToken sets the API token used for authorization, replacing any token
+// provider.
+func WithToken(token string) Option {
+	return func(c *clientConfig) error {
+		c.token = token
+		c.tokens = nil
+		return nil
+	}
+}
+
+// WithTokenProvider sets a provider of the API tokens used for authorization,
+// e.g. a `TokenPool`, replacing any single token.
+func WithTokenProvider(p TokenProvider) Option {
+	return func(c *clientConfig) error {
+		c.token = ""
+		c.tokens = p
+		return nil
+	}
+}
+
+// WithCache sets the cache used to prevent API quota overuse.
+func WithCache(cache *Cache) Option {
+	return func(c *clientConfig) error {
+		c.cache = cache
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout for each API call, including any retries.
+//
+// 0 means no timeout other than that of the HTTP client.
+func WithTimeout(d time.Duration) Option {
+	return func(c *clientConfig) error {
+		if d < 0 {
+			return fmt.Errorf("invalid timeout %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithRetry sets the policy for retrying failed requests.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *clientConfig) error {
+		c.retry = &policy
+		return nil
+	}
+}
+
+// WithLimiter sets a limiter which throttles all requests of the client.
+func WithLimiter(limiter Limiter) Option {
+	return func(c *clientConfig) error {
+		c.limiter = limiter
+		return nil
+	}
+}
+
+// WithCircuitBreaker sets a circuit breaker which guards all requests of the
+// client. See `CircuitBreaker`.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(c *clientConfig) error {
+		c.breaker = b
+		return nil
+	}
+}
+
+// WithEndpointPool sets a pool of equivalent base URLs which replaces the
+// base URL of the client, failing over between them. See `EndpointPool`.
+func WithEndpointPool(p *EndpointPool) Option {
+	return func(c *clientConfig) error {
+		c.endpoints = p
+		return nil
+	}
+}
+
+// WithLocalNetworks sets a table of networks whose lookups are answered from
+// the table, before bogons are detected and without calling the API. Such
+// results have `Local` set to the matching network. See `LocalNetworks`.
+//
+// For the legacy API, IPs in the table are answered locally by batch lookups
+// as well.
+func WithLocalNetworks(networks *LocalNetworks) Option {
+	return func(c *clientConfig) error {
+		c.local = networks
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware around the HTTP transport of the client. See
+// `Use`.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *clientConfig) error {
+		c.middleware = append(c.middleware, mw...)
+		return nil
+	}
+}
+
+// WithRequestHook adds hooks which are called with every request before it
+// is sent.
+func WithRequestHook(hooks ...RequestHook) Option {
+	return func(c *clientConfig) error {
+		c.requestHooks = append(c.requestHooks, hooks...)
+		return nil
+	}
+}
+
+// WithResponseHook adds hooks which are called with every response before it
+// is decoded.
+func WithResponseHook(hooks ...ResponseHook) Option {
+	return func(c *clientConfig) error {
+		c.responseHooks = append(c.responseHooks, hooks...)
+		return nil
+	}
+}
+
+// WithObserver adds observers which are notified of the requests, retries,
+// cache accesses and batch chunks of the client. See `Observer`.
+func WithObserver(obs ...Observer) Option {
+	return func(c *clientConfig) error {
+		c.observers = append(c.observers, obs...)
+		return nil
+	}
+}
+
+// WithoutCache disables the cache of the client. This is mostly useful with
+// `WithOverrides` to bypass the cache for a single call.
+func WithoutCache() Option {
+	return func(c *clientConfig) error {
+		c.cache = nil
+		return nil
+	}
+}
+
+// WithKeepRawBody makes the `...WithMeta` methods retain the raw response
+// body in the returned `ResponseMeta`.
+func WithKeepRawBody() Option {
+	return func(c *clientConfig) error {
+		c.keepRawBody = true
+		return nil
+	}
+}
+
+// WithStrictSchema makes responses which don't match their response type
+// fail with a `*SchemaError`. See `CheckSchema`.
+//
+// This applies to the IP and ASN entries of batch responses too. Unknown
+// fields are reported at any depth, whereas the `Extra` field of a response
+// retains only top-level ones; without strict checking, unknown fields of
+// nested objects such as `geo` are dropped.
+func WithStrictSchema() Option {
+	return func(c *clientConfig) error {
+		c.strictSchema = true
+		return nil
+	}
+}
+
+// FromEnv configures the client from environment variables. Unset variables
+// leave the configuration unchanged. The following variables are read:
+//
+//   - IPINFO_TOKEN: the API token.
+//   - IPINFO_BASE_URL: the base URL of the legacy API client.
+//   - IPINFO_LITE_BASE_URL, IPINFO_CORE_BASE_URL, IPINFO_PLUS_BASE_URL: the
+//     base URL of the Lite, Core and Plus API clients respectively.
+//   - IPINFO_IPV6_BASE_URL: the base URL for requests sent over IPv6.
+//   - IPINFO_USER_AGENT: the user agent.
+//   - IPINFO_TIMEOUT: the timeout for each call, e.g. "5s".
+//   - IPINFO_MAX_RETRIES: the number of retries of failed requests.
+//   - IPINFO_DEBUG: if "1" or "true", debug entries are logged to standard
+//     error. See `WithLogger`.
+//   - IPINFO_LOCAL_NETWORKS: the path of a CSV or JSON file of local
+//     networks. See `LoadLocalNetworks` and `WithLocalNetworks`.
+func FromEnv() Option {
+	return func(c *clientConfig) error {
+		if v, ok := os.LookupEnv("IPINFO_TOKEN"); ok {
+			c.token = v
+			c.tokens = nil
+		}
+
+		baseURLEnv := "IPINFO_BASE_URL"
+		if c.product != "" {
+			baseURLEnv = "IPINFO_" + c.product + "_BASE_URL"
+		}
+		if v := os.Getenv(baseURLEnv); v != "" {
+			u, err := parseBaseURL(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", baseURLEnv, err)
+			}
+			c.baseURL = u
+		}
+		if v := os.Getenv("IPINFO_IPV6_BASE_URL"); v != "" {
+			u, err := parseBaseURL(v)
+			if err != nil {
+				return fmt.Errorf("IPINFO_IPV6_BASE_URL: %w", err)
+			}
+			c.baseURLIPv6 = u
+		}
+
+		if v := os.Getenv("IPINFO_USER_AGENT"); v != "" {
+			c.userAgent = v
+		}
+
+		if v := os.Getenv("IPINFO_TIMEOUT"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return fmt.Errorf("IPINFO_TIMEOUT: invalid duration %q", v)
+			}
+			c.timeout = d
+		}
+
+		if v := os.Getenv("IPINFO_MAX_RETRIES"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("IPINFO_MAX_RETRIES: invalid count %q", v)
+			}
+			policy := RetryPolicy{}
+			if c.retry != nil {
+				policy = *c.retry
+			}
+			policy.MaxAttempts = n + 1
+			c.retry = &policy
+		}
+
+		if v := os.Getenv("IPINFO_LOCAL_NETWORKS"); v != "" {
+			networks, err := LoadLocalNetworks(v)
+			if err != nil {
+				return fmt.Errorf("IPINFO_LOCAL_NETWORKS: %w", err)
+			}
+			c.local = networks
+		}
+
+		if v, _ := strconv.ParseBool(os.Getenv("IPINFO_DEBUG")); v {
+			if err := WithLogger(NewTextLogger(os.Stderr))(c); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// `parseBaseURL` parses an absolute base URL, ensuring it ends with a slash
+// so that relative request paths resolve beneath it.
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be absolute", s)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
@@ -0,0 +1,286 @@
+package ipinfo_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestWithBaseURL(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	// the trailing slash is added.
+	client, err := ipinfo.NewLiteClientWithOptions(ipinfo.WithBaseURL(srv.URL + "/lite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || reqs[0].Path != "/lite/8.8.8.8" {
+		t.Errorf("requests = %+v, want one for /lite/8.8.8.8", reqs)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ipinfo.Option
+	}{
+		{"relative base URL", ipinfo.WithBaseURL("ipinfo.io")},
+		{"relative IPv6 base URL", ipinfo.WithIPv6BaseURL("/v6")},
+		{"negative timeout", ipinfo.WithTimeout(-time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ipinfo.NewClientWithOptions(tt.opt); err == nil {
+				t.Error("err = nil")
+			}
+			if _, err := ipinfo.NewPlusClientWithOptions(tt.opt); err == nil {
+				t.Error("Plus: err = nil")
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Slow("/", time.Second)
+
+	client := srv.Client(ipinfo.WithTimeout(20 * time.Millisecond))
+	start := time.Now()
+	_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("call took %v despite the timeout", d)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetIP("8.8.8.8", &ipinfo.Core{City: "Mountain View"})
+	srv.RateLimit("/", 2, 0)
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	}))
+	info, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Mountain View" {
+		t.Errorf("City = %q, want %q", info.City, "Mountain View")
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("server received %d requests, want 3", n)
+	}
+}
+
+func TestWithRetryExhausted(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusBadGateway})
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+	}))
+	_, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	var errResp *ipinfo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusBadGateway {
+		t.Errorf("err = %v, want a 502 error response", err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestWithRetryNotRetried(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusNotFound})
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	}))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Fatal("err = nil for a 404 response")
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want a 404 not to be retried", n)
+	}
+}
+
+func TestWithRetryShouldRetry(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/", ipinfotest.Fault{Status: http.StatusNotFound, Times: 1})
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		ShouldRetry: func(resp *http.Response, err error) bool {
+			return err == nil && resp.StatusCode == http.StatusNotFound
+		},
+	}))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestWithRetryMaxBackoff(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, time.Minute)
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 2,
+		MaxBackoff:  10 * time.Millisecond,
+	}))
+	start := time.Now()
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("retry waited %v, want Retry-After capped by MaxBackoff", d)
+	}
+}
+
+func TestWithRetryBatchBody(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.RateLimit("/batch", 1, 0)
+
+	client := srv.Client(ipinfo.WithRetry(ipinfo.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+	}))
+	batch, err := client.GetIPInfoBatch([]net.IP{net.ParseIP("8.8.8.8")}, ipinfo.BatchReqOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch["8.8.8.8"] == nil {
+		t.Errorf("batch = %v, want 8.8.8.8", batch)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || string(reqs[0].Body) != string(reqs[1].Body) {
+		t.Errorf("requests = %+v, want the body sent twice", reqs)
+	}
+}
+
+// countingLimiter counts the calls of `Wait` and fails them with `err`.
+type countingLimiter struct {
+	mu    sync.Mutex
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waits++
+	return l.err
+}
+
+func TestWithLimiter(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 0)
+
+	limiter := &countingLimiter{}
+	client := srv.Client(
+		ipinfo.WithLimiter(limiter),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.waits != 2 {
+		t.Errorf("limiter waited %d times, want once per attempt", limiter.waits)
+	}
+
+	limiter.err = errors.New("throttled")
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); !errors.Is(err, limiter.err) {
+		t.Errorf("err = %v, want %v", err, limiter.err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server received %d requests, want none while throttled", n-2)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("env-token")
+
+	t.Setenv("IPINFO_TOKEN", "env-token")
+	t.Setenv("IPINFO_BASE_URL", srv.URL)
+	t.Setenv("IPINFO_LITE_BASE_URL", srv.URL+"/lite")
+	t.Setenv("IPINFO_USER_AGENT", "env-agent")
+	t.Setenv("IPINFO_TIMEOUT", "5s")
+	t.Setenv("IPINFO_MAX_RETRIES", "1")
+
+	client, err := ipinfo.NewClientWithOptions(ipinfo.FromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.RateLimit("/", 1, 0)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	lite, err := ipinfo.NewLiteClientWithOptions(ipinfo.FromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lite.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("server received %d requests, want 3", len(reqs))
+	}
+	if reqs[2].Path != "/lite/8.8.8.8" {
+		t.Errorf("Lite path = %q, want /lite/8.8.8.8", reqs[2].Path)
+	}
+	for _, req := range reqs {
+		if req.Header.Get("User-Agent") != "env-agent" {
+			t.Errorf("User-Agent = %q, want %q", req.Header.Get("User-Agent"), "env-agent")
+		}
+	}
+}
+
+func TestFromEnvInvalid(t *testing.T) {
+	tests := []struct{ name, value string }{
+		{"IPINFO_BASE_URL", "ipinfo.io"},
+		{"IPINFO_IPV6_BASE_URL", "v6"},
+		{"IPINFO_TIMEOUT", "soon"},
+		{"IPINFO_MAX_RETRIES", "-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			if _, err := ipinfo.NewClientWithOptions(ipinfo.FromEnv()); err == nil {
+				t.Errorf("err = nil for %s=%q", tt.name, tt.value)
+			}
+		})
+	}
+}
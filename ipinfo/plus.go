@@ -1,14 +1,14 @@
 package ipinfo
 
 import (
-	"context"
 	"encoding/json"
-	"io"
 	"net"
 	"net/http"
 	"net/netip"
 	"net/url"
-	"strings"
+	"reflect"
+	"sync"
+	"time"
 )
 
 const (
@@ -16,10 +16,9 @@ const (
 )
 
 // PlusClient is a client for the IPinfo Plus API.
+//
+// The exported fields reflect the configuration of the client. See `Client`.
 type PlusClient struct {
-	// HTTP client used to communicate with the API.
-	client *http.Client
-
 	// Base URL for API requests.
 	BaseURL *url.URL
 
@@ -31,113 +30,149 @@ type PlusClient struct {
 
 	// The API token used for authorization.
 	Token string
+
+	// Transport of the client, created on first use if the client wasn't
+	// created by a constructor.
+	t    *transport
+	once sync.Once
 }
 
 // Plus represents the response from the IPinfo Plus API /lookup endpoint.
 type Plus struct {
-	IP          net.IP         `json:"ip"`
-	Hostname    string         `json:"hostname,omitempty"`
-	Bogon       bool           `json:"bogon,omitempty"`
-	Geo         *PlusGeo       `json:"geo,omitempty"`
-	AS          *PlusAS        `json:"as,omitempty"`
-	Mobile      *PlusMobile    `json:"mobile,omitempty"`
-	Anonymous   *PlusAnonymous `json:"anonymous,omitempty"`
-	IsAnonymous bool           `json:"is_anonymous"`
-	IsAnycast   bool           `json:"is_anycast"`
-	IsHosting   bool           `json:"is_hosting"`
-	IsMobile    bool           `json:"is_mobile"`
-	IsSatellite bool           `json:"is_satellite"`
-	Abuse       *PlusAbuse     `json:"abuse,omitempty"`
-	Company     *PlusCompany   `json:"company,omitempty"`
-	Privacy     *PlusPrivacy   `json:"privacy,omitempty"`
-	Domains     *PlusDomains   `json:"domains,omitempty"`
+	IP          net.IP         `json:"ip" csv:"ip" yaml:"ip,omitempty" schema:"required"`
+	Hostname    string         `json:"hostname,omitempty" csv:"hostname" yaml:"hostname,omitempty"`
+	Bogon       bool           `json:"bogon,omitempty" csv:"bogon" yaml:"bogon,omitempty"`
+	BogonInfo   *BogonInfo     `json:"bogon_info,omitempty" csv:"bogon_,inline" yaml:"bogonInfo,omitempty"`
+	Local       *LocalNetwork  `json:"local,omitempty" csv:"local_,inline" yaml:"local,omitempty"`
+	Geo         *PlusGeo       `json:"geo,omitempty" csv:"geo_,inline" yaml:"geo,omitempty"`
+	AS          *PlusAS        `json:"as,omitempty" csv:"as_,inline" yaml:"as,omitempty"`
+	Mobile      *PlusMobile    `json:"mobile,omitempty" csv:"mobile_,inline" yaml:"mobile,omitempty"`
+	Anonymous   *PlusAnonymous `json:"anonymous,omitempty" csv:"anonymous_,inline" yaml:"anonymous,omitempty"`
+	IsAnonymous bool           `json:"is_anonymous" csv:"is_anonymous" yaml:"isAnonymous,omitempty" schema:"required"`
+	IsAnycast   bool           `json:"is_anycast" csv:"is_anycast" yaml:"isAnycast,omitempty" schema:"required"`
+	IsHosting   bool           `json:"is_hosting" csv:"is_hosting" yaml:"isHosting,omitempty" schema:"required"`
+	IsMobile    bool           `json:"is_mobile" csv:"is_mobile" yaml:"isMobile,omitempty" schema:"required"`
+	IsSatellite bool           `json:"is_satellite" csv:"is_satellite" yaml:"isSatellite,omitempty" schema:"required"`
+	Abuse       *PlusAbuse     `json:"abuse,omitempty" csv:"abuse_,inline" yaml:"abuse,omitempty"`
+	Company     *PlusCompany   `json:"company,omitempty" csv:"company_,inline" yaml:"company,omitempty"`
+	Privacy     *PlusPrivacy   `json:"privacy,omitempty" csv:"privacy_,inline" yaml:"privacy,omitempty"`
+	Domains     *PlusDomains   `json:"domains,omitempty" csv:"domains_,inline" yaml:"domains,omitempty"`
+
+	// Extra holds response fields unknown to this version of the library.
+	Extra map[string]json.RawMessage `json:"-" csv:"-" yaml:"-"`
 }
 
 // PlusGeo represents the geo object in Plus API response.
 type PlusGeo struct {
-	City          string  `json:"city,omitempty"`
-	Region        string  `json:"region,omitempty"`
-	RegionCode    string  `json:"region_code,omitempty"`
-	Country       string  `json:"country,omitempty"`
-	CountryCode   string  `json:"country_code,omitempty"`
-	Continent     string  `json:"continent,omitempty"`
-	ContinentCode string  `json:"continent_code,omitempty"`
-	Latitude      float64 `json:"latitude"`
-	Longitude     float64 `json:"longitude"`
-	Timezone      string  `json:"timezone,omitempty"`
-	PostalCode    string  `json:"postal_code,omitempty"`
-	DMACode       string  `json:"dma_code,omitempty"`
-	GeonameID     string  `json:"geoname_id,omitempty"`
-	Radius        int     `json:"radius"`
-	LastChanged   string  `json:"last_changed,omitempty"`
+	City          string  `json:"city,omitempty" csv:"city" yaml:"city,omitempty"`
+	Region        string  `json:"region,omitempty" csv:"region" yaml:"region,omitempty"`
+	RegionCode    string  `json:"region_code,omitempty" csv:"region_code" yaml:"regionCode,omitempty"`
+	Country       string  `json:"country,omitempty" csv:"country" yaml:"country,omitempty"`
+	CountryCode   string  `json:"country_code,omitempty" csv:"country_code" yaml:"countryCode,omitempty"`
+	Continent     string  `json:"continent,omitempty" csv:"continent" yaml:"continent,omitempty"`
+	ContinentCode string  `json:"continent_code,omitempty" csv:"continent_code" yaml:"continentCode,omitempty"`
+	Latitude      float64 `json:"latitude" csv:"latitude" yaml:"latitude,omitempty"`
+	Longitude     float64 `json:"longitude" csv:"longitude" yaml:"longitude,omitempty"`
+	Timezone      string  `json:"timezone,omitempty" csv:"timezone" yaml:"timezone,omitempty"`
+	PostalCode    string  `json:"postal_code,omitempty" csv:"postal_code" yaml:"postalCode,omitempty"`
+	DMACode       string  `json:"dma_code,omitempty" csv:"dma_code" yaml:"dmaCode,omitempty"`
+	GeonameID     string  `json:"geoname_id,omitempty" csv:"geoname_id" yaml:"geonameID,omitempty"`
+	Radius        int     `json:"radius" csv:"radius" yaml:"radius,omitempty"`
+	LastChanged   string  `json:"last_changed,omitempty" csv:"last_changed" yaml:"lastChanged,omitempty"`
 
 	// Extended fields using the same country data as legacy Core API
-	CountryName     string          `json:"-"`
-	IsEU            bool            `json:"-"`
-	CountryFlag     CountryFlag     `json:"-"`
-	CountryFlagURL  string          `json:"-"`
-	CountryCurrency CountryCurrency `json:"-"`
-	ContinentInfo   Continent       `json:"-"`
+	CountryName     string          `json:"-" csv:"country_name" yaml:"countryName,omitempty"`
+	IsEU            bool            `json:"-" csv:"isEU" yaml:"isEU,omitempty"`
+	CountryFlag     CountryFlag     `json:"-" csv:"country_flag_,inline" yaml:"countryFlag,omitempty"`
+	CountryFlagURL  string          `json:"-" csv:"country_flag_url" yaml:"countryFlagURL,omitempty"`
+	CountryCurrency CountryCurrency `json:"-" csv:"country_currency_,inline" yaml:"countryCurrency,omitempty"`
+	ContinentInfo   Continent       `json:"-" csv:"continent_info_,inline" yaml:"continentInfo,omitempty"`
 }
 
 // PlusAS represents the AS object in Plus API response.
 type PlusAS struct {
-	ASN         string `json:"asn"`
-	Name        string `json:"name"`
-	Domain      string `json:"domain"`
-	Type        string `json:"type"`
-	LastChanged string `json:"last_changed,omitempty"`
+	ASN         string `json:"asn" csv:"asn" yaml:"asn,omitempty" schema:"required"`
+	Name        string `json:"name" csv:"name" yaml:"name,omitempty" schema:"required"`
+	Domain      string `json:"domain" csv:"domain" yaml:"domain,omitempty" schema:"required"`
+	Type        string `json:"type" csv:"type" yaml:"type,omitempty" schema:"required"`
+	LastChanged string `json:"last_changed,omitempty" csv:"last_changed" yaml:"lastChanged,omitempty"`
 }
 
 // PlusMobile represents the mobile object in Plus API response.
 type PlusMobile struct {
-	Name string `json:"name,omitempty"`
-	MCC  string `json:"mcc,omitempty"`
-	MNC  string `json:"mnc,omitempty"`
+	Name string `json:"name,omitempty" csv:"name" yaml:"name,omitempty"`
+	MCC  string `json:"mcc,omitempty" csv:"mcc" yaml:"mcc,omitempty"`
+	MNC  string `json:"mnc,omitempty" csv:"mnc" yaml:"mnc,omitempty"`
 }
 
 // PlusAnonymous represents the anonymous object in Plus API response.
 type PlusAnonymous struct {
-	IsProxy bool   `json:"is_proxy"`
-	IsRelay bool   `json:"is_relay"`
-	IsTor   bool   `json:"is_tor"`
-	IsVPN   bool   `json:"is_vpn"`
-	Name    string `json:"name,omitempty"`
+	IsProxy bool   `json:"is_proxy" csv:"is_proxy" yaml:"isProxy,omitempty"`
+	IsRelay bool   `json:"is_relay" csv:"is_relay" yaml:"isRelay,omitempty"`
+	IsTor   bool   `json:"is_tor" csv:"is_tor" yaml:"isTor,omitempty"`
+	IsVPN   bool   `json:"is_vpn" csv:"is_vpn" yaml:"isVPN,omitempty"`
+	Name    string `json:"name,omitempty" csv:"name" yaml:"name,omitempty"`
 }
 
 // PlusAbuse represents the abuse object in Plus API response.
 type PlusAbuse struct {
-	Address     string `json:"address,omitempty"`
-	Country     string `json:"country,omitempty"`
-	CountryName string `json:"country_name,omitempty"`
-	Email       string `json:"email,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Network     string `json:"network,omitempty"`
-	Phone       string `json:"phone,omitempty"`
+	Address     string `json:"address,omitempty" csv:"address" yaml:"address,omitempty"`
+	Country     string `json:"country,omitempty" csv:"country" yaml:"country,omitempty"`
+	CountryName string `json:"country_name,omitempty" csv:"country_name" yaml:"countryName,omitempty"`
+	Email       string `json:"email,omitempty" csv:"email" yaml:"email,omitempty"`
+	Name        string `json:"name,omitempty" csv:"name" yaml:"name,omitempty"`
+	Network     string `json:"network,omitempty" csv:"network" yaml:"network,omitempty"`
+	Phone       string `json:"phone,omitempty" csv:"phone" yaml:"phone,omitempty"`
 }
 
 // PlusCompany represents the company object in Plus API response.
 type PlusCompany struct {
-	Name   string `json:"name,omitempty"`
-	Domain string `json:"domain,omitempty"`
-	Type   string `json:"type,omitempty"`
+	Name   string `json:"name,omitempty" csv:"name" yaml:"name,omitempty"`
+	Domain string `json:"domain,omitempty" csv:"domain" yaml:"domain,omitempty"`
+	Type   string `json:"type,omitempty" csv:"type" yaml:"type,omitempty"`
 }
 
 // PlusPrivacy represents the privacy object in Plus API response.
 type PlusPrivacy struct {
-	VPN     bool   `json:"vpn"`
-	Proxy   bool   `json:"proxy"`
-	Tor     bool   `json:"tor"`
-	Relay   bool   `json:"relay"`
-	Hosting bool   `json:"hosting"`
-	Service string `json:"service,omitempty"`
+	VPN     bool   `json:"vpn" csv:"vpn" yaml:"vpn,omitempty"`
+	Proxy   bool   `json:"proxy" csv:"proxy" yaml:"proxy,omitempty"`
+	Tor     bool   `json:"tor" csv:"tor" yaml:"tor,omitempty"`
+	Relay   bool   `json:"relay" csv:"relay" yaml:"relay,omitempty"`
+	Hosting bool   `json:"hosting" csv:"hosting" yaml:"hosting,omitempty"`
+	Service string `json:"service,omitempty" csv:"service" yaml:"service,omitempty"`
 }
 
 // PlusDomains represents the domains object in Plus API response.
 type PlusDomains struct {
-	IP      string   `json:"ip,omitempty"`
-	Total   uint64   `json:"total"`
-	Domains []string `json:"domains,omitempty"`
+	IP      string   `json:"ip,omitempty" csv:"-" yaml:"ip,omitempty"`
+	Total   uint64   `json:"total" csv:"total" yaml:"total,omitempty"`
+	Domains []string `json:"domains,omitempty" csv:"-" yaml:"domains,omitempty"`
+}
+
+// UnmarshalJSON decodes `data` into `v`, retaining unknown fields in
+// `v.Extra`.
+func (v *Plus) UnmarshalJSON(data []byte) error {
+	type plus Plus
+	if err := json.Unmarshal(data, (*plus)(v)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, reflect.TypeOf(*v))
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes `v`, including any fields retained in `v.Extra`.
+func (v Plus) MarshalJSON() ([]byte, error) {
+	type plus Plus
+	data, err := json.Marshal(plus(v))
+	if err != nil {
+		return nil, err
+	}
+	return marshalWithExtra(data, v.Extra)
 }
 
 func (v *Plus) enrichGeo() {
@@ -159,132 +194,154 @@ func (v *Plus) enrichGeo() {
 
 // NewPlusClient creates a new IPinfo Plus API client.
 func NewPlusClient(httpClient *http.Client, cache *Cache, token string) *PlusClient {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
+	return newPlusClient(newTransport(httpClient, defaultPlusBaseURL, "PLUS", cache, token))
+}
 
-	baseURL, _ := url.Parse(defaultPlusBaseURL)
-	return &PlusClient{
-		client:    httpClient,
-		BaseURL:   baseURL,
-		UserAgent: defaultUserAgent,
-		Cache:     cache,
-		Token:     token,
-	}
+// `transport` returns the transport of `c`, creating one with the defaults
+// of `NewPlusClient` if `c` wasn't created by a constructor.
+func (c *PlusClient) transport() *transport {
+	c.once.Do(func() {
+		if c.t == nil {
+			c.t = newTransport(nil, defaultPlusBaseURL, "PLUS", nil, "")
+		}
+		c.t.bind(&clientFields{&c.BaseURL, &c.UserAgent, &c.Cache, &c.Token})
+	})
+	return c.t
+}
+
+// `newPlusClient` returns a PlusClient using `t`, with its exported fields set
+// from the configuration of `t`.
+func newPlusClient(t *transport) *PlusClient {
+	c := &PlusClient{t: t}
+	c.transport()
+	return c
 }
 
 // GetIPInfo returns the Plus details for the specified IP.
 func (c *PlusClient) GetIPInfo(ip net.IP) (*Plus, error) {
-	if ip != nil && isBogon(netip.MustParseAddr(ip.String())) {
+	v, _, err := c.GetIPInfoWithMeta(ip)
+	return v, err
+}
+
+// GetIPInfoWithMeta returns the Plus details for the specified IP
+// along with metadata about the API response.
+func (c *PlusClient) GetIPInfoWithMeta(ip net.IP) (*Plus, *ResponseMeta, error) {
+	start := time.Now()
+	cfg := c.transport().config()
+	cache := cfg.observedCache
+	addr, err := validIP(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := cfg.local.Lookup(addr); ok {
+		return n.plus(ip), &ResponseMeta{Duration: time.Since(start)}, nil
+	}
+	if info := ClassifyBogon(addr); info != nil {
 		bogonResponse := new(Plus)
 		bogonResponse.Bogon = true
+		bogonResponse.BogonInfo = info
 		bogonResponse.IP = ip
-		return bogonResponse, nil
+		return bogonResponse, &ResponseMeta{Duration: time.Since(start)}, nil
 	}
 	relUrl := ""
 	if ip != nil {
 		relUrl = ip.String()
 	}
 
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey(relUrl)); err == nil {
-			return res.(*Plus), nil
+	key := cacheKey("plus:" + relUrl)
+	if cache != nil {
+		if res, err := cache.Get(key); err == nil {
+			return res.(*Plus), cachedMeta(start), nil
 		}
 	}
 
-	req, err := c.newRequest(nil, "GET", relUrl, nil)
+	req, err := c.transport().newRequest(nil, "GET", relUrl, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	res := new(Plus)
-	if _, err := c.do(req, res); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, res)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	res.enrichGeo()
 
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey(relUrl), res); err != nil {
-			return res, err
+	if cache != nil {
+		if err := cache.Set(key, res); err != nil {
+			return res, meta, err
 		}
 	}
 
-	return res, nil
+	return res, meta, nil
 }
 
-func (c *PlusClient) newRequest(ctx context.Context,
-	method string,
-	urlStr string,
-	body io.Reader,
-) (*http.Request, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	u := new(url.URL)
-	baseURL := c.BaseURL
-	if rel, err := url.Parse(urlStr); err == nil {
-		u = baseURL.ResolveReference(rel)
-	} else if strings.ContainsRune(urlStr, ':') {
-		// IPv6 strings fail to parse as URLs, so let's add it as a URL Path.
-		*u = *baseURL
-		u.Path += urlStr
-	} else {
-		return nil, err
-	}
+// GetIPAddrInfo returns the Plus details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func (c *PlusClient) GetIPAddrInfo(addr netip.Addr) (*Plus, error) {
+	v, _, err := c.GetIPAddrInfoWithMeta(addr)
+	return v, err
+}
 
-	// get `http` package request object.
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+// GetIPAddrInfoWithMeta is like `GetIPAddrInfo` but also returns metadata
+// about the API response.
+func (c *PlusClient) GetIPAddrInfoWithMeta(addr netip.Addr) (*Plus, *ResponseMeta, error) {
+	ip, err := addrIP(addr)
 	if err != nil {
-		return nil, err
-	}
-
-	// set common headers.
-	req.Header.Set("Accept", "application/json")
-	if c.UserAgent != "" {
-		req.Header.Set("User-Agent", c.UserAgent)
+		return nil, nil, err
 	}
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
-	}
-
-	return req, nil
+	return c.GetIPInfoWithMeta(ip)
 }
 
-func (c *PlusClient) do(
-	req *http.Request,
-	v interface{},
-) (*http.Response, error) {
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// GetIPStrInfo returns the Plus details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func (c *PlusClient) GetIPStrInfo(ip string) (*Plus, error) {
+	v, _, err := c.GetIPStrInfoWithMeta(ip)
+	return v, err
+}
 
-	err = checkResponse(resp)
+// GetIPStrInfoWithMeta is like `GetIPStrInfo` but also returns metadata
+// about the API response.
+func (c *PlusClient) GetIPStrInfoWithMeta(ip string) (*Plus, *ResponseMeta, error) {
+	parsed, err := parseIP(ip)
 	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
+		return nil, nil, err
 	}
-
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-			if err == io.EOF {
-				// ignore EOF errors caused by empty response body
-				err = nil
-			}
-		}
-	}
-
-	return resp, err
+	return c.GetIPInfoWithMeta(parsed)
 }
 
 // GetIPInfoPlus returns the Plus details for the specified IP.
 func GetIPInfoPlus(ip net.IP) (*Plus, error) {
 	return DefaultPlusClient.GetIPInfo(ip)
 }
+
+// GetIPInfoPlusWithMeta returns the Plus details for the specified IP
+// along with metadata about the API response.
+func GetIPInfoPlusWithMeta(ip net.IP) (*Plus, *ResponseMeta, error) {
+	return DefaultPlusClient.GetIPInfoWithMeta(ip)
+}
+
+// GetIPAddrInfoPlus returns the Plus details for the specified IP, or an
+// `*InvalidIPError` if `addr` is not a valid IP without a zone.
+func GetIPAddrInfoPlus(addr netip.Addr) (*Plus, error) {
+	return DefaultPlusClient.GetIPAddrInfo(addr)
+}
+
+// GetIPAddrInfoPlusWithMeta is like `GetIPAddrInfoPlus` but also returns
+// metadata about the API response.
+func GetIPAddrInfoPlusWithMeta(addr netip.Addr) (*Plus, *ResponseMeta, error) {
+	return DefaultPlusClient.GetIPAddrInfoWithMeta(addr)
+}
+
+// GetIPStrInfoPlus returns the Plus details for the specified IP, or an
+// `*InvalidIPError` if `ip` is not a valid IP.
+func GetIPStrInfoPlus(ip string) (*Plus, error) {
+	return DefaultPlusClient.GetIPStrInfo(ip)
+}
+
+// GetIPStrInfoPlusWithMeta is like `GetIPStrInfoPlus` but also returns
+// metadata about the API response.
+func GetIPStrInfoPlusWithMeta(ip string) (*Plus, *ResponseMeta, error) {
+	return DefaultPlusClient.GetIPStrInfoWithMeta(ip)
+}
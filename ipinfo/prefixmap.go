@@ -0,0 +1,162 @@
+package ipinfo
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// PrefixMap maps IP prefixes to values, and finds the most specific prefix
+// containing an address in time proportional to the prefix length, regardless
+// of the number of prefixes. It is built with a `PrefixMapBuilder` and is
+// immutable, so it may be used concurrently.
+//
+// IPv4 and IPv6 prefixes are kept apart: an IPv4-mapped IPv6 address such as
+// "::ffff:10.0.0.1" only matches IPv6 prefixes. Use `netip.Addr.Unmap` to
+// match it against IPv4 prefixes.
+//
+// A `PrefixMap[struct{}]` serves as a set of networks, e.g. for allow and
+// deny lists. The zero value and a nil map are empty.
+type PrefixMap[V interface{}] struct {
+	nodes    []prefixNode
+	prefixes []netip.Prefix
+	values   []V
+}
+
+// prefixNode is a node of the binary trie of a `PrefixMap`. Nodes 0 and 1
+// are the roots of the IPv4 and IPv6 tries, so that 0 marks a missing child.
+type prefixNode struct {
+	child [2]int32
+
+	// index of the entry of the prefix ending at this node, or -1.
+	entry int32
+}
+
+// PrefixMapBuilder collects the prefixes of a `PrefixMap`. The zero value is
+// ready to use.
+type PrefixMapBuilder[V interface{}] struct {
+	prefixes []netip.Prefix
+	values   []V
+	index    map[netip.Prefix]int
+}
+
+// Add maps `prefix` to `value`, replacing the value of an earlier `Add` of
+// the same prefix. Host bits of `prefix` are ignored, e.g. "10.1.2.3/8" is
+// "10.0.0.0/8".
+func (b *PrefixMapBuilder[V]) Add(prefix netip.Prefix, value V) error {
+	if !prefix.IsValid() {
+		return errors.New("ipinfo: invalid prefix")
+	}
+	prefix = prefix.Masked()
+
+	if b.index == nil {
+		b.index = make(map[netip.Prefix]int)
+	}
+	if i, ok := b.index[prefix]; ok {
+		b.values[i] = value
+		return nil
+	}
+	b.index[prefix] = len(b.prefixes)
+	b.prefixes = append(b.prefixes, prefix)
+	b.values = append(b.values, value)
+	return nil
+}
+
+// Len returns the number of prefixes added so far.
+func (b *PrefixMapBuilder[V]) Len() int {
+	return len(b.prefixes)
+}
+
+// Build returns a `PrefixMap` of the prefixes added so far. The builder may
+// be used further without affecting the map.
+func (b *PrefixMapBuilder[V]) Build() *PrefixMap[V] {
+	m := &PrefixMap[V]{
+		nodes:    []prefixNode{{entry: -1}, {entry: -1}},
+		prefixes: append([]netip.Prefix(nil), b.prefixes...),
+		values:   append([]V(nil), b.values...),
+	}
+	for i, p := range m.prefixes {
+		a := addrBits(p.Addr())
+		n := m.root(p.Addr())
+		for bit := 0; bit < p.Bits(); bit++ {
+			c := a[bit/8] >> (7 - bit%8) & 1
+			if m.nodes[n].child[c] == 0 {
+				m.nodes = append(m.nodes, prefixNode{entry: -1})
+				m.nodes[n].child[c] = int32(len(m.nodes) - 1)
+			}
+			n = m.nodes[n].child[c]
+		}
+		m.nodes[n].entry = int32(i)
+	}
+	return m
+}
+
+// Lookup returns the value of the most specific prefix containing `addr`.
+func (m *PrefixMap[V]) Lookup(addr netip.Addr) (V, bool) {
+	_, v, ok := m.LookupPrefix(addr)
+	return v, ok
+}
+
+// LookupPrefix returns the most specific prefix containing `addr` along with
+// its value.
+func (m *PrefixMap[V]) LookupPrefix(addr netip.Addr) (netip.Prefix, V, bool) {
+	if i := m.find(addr); i >= 0 {
+		return m.prefixes[i], m.values[i], true
+	}
+	var zero V
+	return netip.Prefix{}, zero, false
+}
+
+// Contains reports whether any prefix contains `addr`.
+func (m *PrefixMap[V]) Contains(addr netip.Addr) bool {
+	return m.find(addr) >= 0
+}
+
+// Len returns the number of prefixes in `m`.
+func (m *PrefixMap[V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.prefixes)
+}
+
+// `find` returns the index of the entry of the most specific prefix
+// containing `addr`, or -1.
+func (m *PrefixMap[V]) find(addr netip.Addr) int32 {
+	if m == nil || len(m.nodes) == 0 || !addr.IsValid() {
+		return -1
+	}
+
+	a := addrBits(addr)
+	n := m.root(addr)
+	best := m.nodes[n].entry
+	for bit := 0; bit < addr.BitLen(); bit++ {
+		n = m.nodes[n].child[a[bit/8]>>(7-bit%8)&1]
+		if n == 0 {
+			break
+		}
+		if e := m.nodes[n].entry; e >= 0 {
+			best = e
+		}
+	}
+	return best
+}
+
+// `root` returns the root node of the trie for the family of `addr`.
+func (m *PrefixMap[V]) root(addr netip.Addr) int32 {
+	if addr.Is4() {
+		return 0
+	}
+	return 1
+}
+
+// `addrBits` returns the bytes of `addr`, with those of an IPv4 address
+// first.
+func addrBits(addr netip.Addr) [16]byte {
+	if addr.Is4() {
+		var a [16]byte
+		v4 := addr.As4()
+		copy(a[:], v4[:])
+		return a
+	}
+	return addr.As16()
+}
@@ -0,0 +1,173 @@
+package ipinfo_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+// `buildPrefixMap` returns a map of each prefix in `prefixes` to itself.
+func buildPrefixMap(t testing.TB, prefixes ...string) *ipinfo.PrefixMap[string] {
+	t.Helper()
+	var b ipinfo.PrefixMapBuilder[string]
+	for _, p := range prefixes {
+		if err := b.Add(netip.MustParsePrefix(p), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Build()
+}
+
+func TestPrefixMapLookup(t *testing.T) {
+	m := buildPrefixMap(t,
+		"10.0.0.0/8",
+		"10.20.0.0/16",
+		"10.20.30.0/24",
+		"10.20.30.40/32",
+		"192.168.0.0/16",
+		"2001:db8::/32",
+		"2001:db8:1::/48",
+		"::ffff:0:0/96",
+	)
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		// the longest prefix wins among overlapping ones.
+		{"10.1.1.1", "10.0.0.0/8"},
+		{"10.20.1.1", "10.20.0.0/16"},
+		{"10.20.30.1", "10.20.30.0/24"},
+		{"10.20.30.40", "10.20.30.40/32"},
+		{"10.20.30.41", "10.20.30.0/24"},
+		{"10.255.255.255", "10.0.0.0/8"},
+		{"192.168.255.1", "192.168.0.0/16"},
+		{"2001:db8::1", "2001:db8::/32"},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"2001:db8:2::1", "2001:db8::/32"},
+		// IPv4-mapped addresses only match IPv6 prefixes.
+		{"::ffff:10.20.30.40", "::ffff:0:0/96"},
+		// misses.
+		{"11.0.0.1", ""},
+		{"9.255.255.255", ""},
+		{"2001:db9::1", ""},
+		{"::1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			addr := netip.MustParseAddr(tt.addr)
+			prefix, v, ok := m.LookupPrefix(addr)
+			if ok != (tt.want != "") || v != tt.want {
+				t.Fatalf("LookupPrefix = %v, %q, %v, want %q", prefix, v, ok, tt.want)
+			}
+			if ok && prefix != netip.MustParsePrefix(tt.want) {
+				t.Errorf("prefix = %v, want %s", prefix, tt.want)
+			}
+			if m.Contains(addr) != ok {
+				t.Errorf("Contains = %v, want %v", !ok, ok)
+			}
+		})
+	}
+}
+
+func TestPrefixMapDefaultRoute(t *testing.T) {
+	m := buildPrefixMap(t, "0.0.0.0/0", "10.0.0.0/8", "::/0")
+	tests := []struct{ addr, want string }{
+		{"8.8.8.8", "0.0.0.0/0"},
+		{"10.1.1.1", "10.0.0.0/8"},
+		{"0.0.0.0", "0.0.0.0/0"},
+		{"255.255.255.255", "0.0.0.0/0"},
+		{"2001:db8::1", "::/0"},
+		{"::ffff:10.1.1.1", "::/0"},
+	}
+	for _, tt := range tests {
+		if v, ok := m.Lookup(netip.MustParseAddr(tt.addr)); !ok || v != tt.want {
+			t.Errorf("Lookup(%s) = %q, %v, want %q", tt.addr, v, ok, tt.want)
+		}
+	}
+	if _, ok := m.Lookup(netip.Addr{}); ok {
+		t.Error("the zero Addr matched /0")
+	}
+}
+
+func TestPrefixMapBuilder(t *testing.T) {
+	var b ipinfo.PrefixMapBuilder[int]
+	if err := b.Add(netip.Prefix{}, 0); err == nil {
+		t.Error("err = nil for an invalid prefix")
+	}
+	// host bits are ignored, and a later value replaces an earlier one.
+	b.Add(netip.MustParsePrefix("10.1.2.3/8"), 1)
+	b.Add(netip.MustParsePrefix("10.0.0.0/8"), 2)
+	if b.Len() != 1 {
+		t.Errorf("Len = %d, want 1", b.Len())
+	}
+	m := b.Build()
+
+	// the map isn't affected by later additions.
+	b.Add(netip.MustParsePrefix("10.1.0.0/16"), 3)
+	if v, ok := m.Lookup(netip.MustParseAddr("10.1.1.1")); !ok || v != 2 {
+		t.Errorf("Lookup = %d, %v, want 2", v, ok)
+	}
+	if m.Len() != 1 || b.Build().Len() != 2 {
+		t.Errorf("Len = %d and %d, want 1 and 2", m.Len(), b.Build().Len())
+	}
+
+	// nil and zero maps are empty.
+	var nilMap *ipinfo.PrefixMap[int]
+	var zero ipinfo.PrefixMap[int]
+	for _, m := range []*ipinfo.PrefixMap[int]{nilMap, &zero} {
+		if m.Contains(netip.MustParseAddr("10.0.0.1")) || m.Len() != 0 {
+			t.Errorf("map %v isn't empty", m)
+		}
+	}
+}
+
+var benchmarkAddrs = map[string][]netip.Addr{
+	"IPv4": {
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("192.168.1.1"),
+		netip.MustParseAddr("203.0.113.7"),
+		netip.MustParseAddr("255.255.255.255"),
+	},
+	"IPv6": {
+		netip.MustParseAddr("2001:4860:4860::8888"),
+		netip.MustParseAddr("fe80::1"),
+		netip.MustParseAddr("2001:0:a01:203::1"),
+		netip.MustParseAddr("2002:ffff:ffff::1"),
+	},
+}
+
+// BenchmarkBogonLookup compares a linear scan of the bogon networks with a
+// `PrefixMap` of them. Run it with `go test -bench BogonLookup ./ipinfo`.
+func BenchmarkBogonLookup(b *testing.B) {
+	prefixes := ipinfo.BogonPrefixes()
+	var builder ipinfo.PrefixMapBuilder[struct{}]
+	for _, p := range prefixes {
+		if err := builder.Add(p, struct{}{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	m := builder.Build()
+
+	for _, family := range []string{"IPv4", "IPv6"} {
+		addrs := benchmarkAddrs[family]
+		b.Run(family+"/Linear", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// find the most specific prefix, as `Lookup` does.
+				addr := addrs[i%len(addrs)]
+				best := -1
+				for _, p := range prefixes {
+					if p.Contains(addr) && p.Bits() > best {
+						best = p.Bits()
+					}
+				}
+			}
+		})
+		b.Run(family+"/PrefixMap", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.Lookup(addrs[i%len(addrs)])
+			}
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package ipinfo
 
+import (
+	"time"
+)
+
 // ResproxyDetails represents residential proxy detection details for an IP.
 type ResproxyDetails struct {
-	IP              string  `json:"ip"`
-	LastSeen        string  `json:"last_seen"`
-	PercentDaysSeen float64 `json:"percent_days_seen"`
-	Service         string  `json:"service"`
+	IP              string  `json:"ip" csv:"ip" yaml:"ip,omitempty"`
+	LastSeen        string  `json:"last_seen" csv:"last_seen" yaml:"lastSeen,omitempty"`
+	PercentDaysSeen float64 `json:"percent_days_seen" csv:"percent_days_seen" yaml:"percentDaysSeen,omitempty"`
+	Service         string  `json:"service" csv:"service" yaml:"service,omitempty"`
 }
 
 // GetResproxy returns the residential proxy details for the specified IP.
@@ -13,34 +17,53 @@ func GetResproxy(ip string) (*ResproxyDetails, error) {
 	return DefaultClient.GetResproxy(ip)
 }
 
+// GetResproxyWithMeta returns the residential proxy details for the
+// specified IP along with metadata about the API response.
+func GetResproxyWithMeta(ip string) (*ResproxyDetails, *ResponseMeta, error) {
+	return DefaultClient.GetResproxyWithMeta(ip)
+}
+
 // GetResproxy returns the residential proxy details for the specified IP.
 func (c *Client) GetResproxy(ip string) (*ResproxyDetails, error) {
+	v, _, err := c.GetResproxyWithMeta(ip)
+	return v, err
+}
+
+// GetResproxyWithMeta returns the residential proxy details for the
+// specified IP along with metadata about the API response.
+func (c *Client) GetResproxyWithMeta(
+	ip string,
+) (*ResproxyDetails, *ResponseMeta, error) {
+	start := time.Now()
+	cache := c.transport().config().observedCache
+
 	// perform cache lookup.
 	cacheKey := cacheKey("resproxy:" + ip)
-	if c.Cache != nil {
-		if res, err := c.Cache.Get(cacheKey); err == nil {
-			return res.(*ResproxyDetails), nil
+	if cache != nil {
+		if res, err := cache.Get(cacheKey); err == nil {
+			return res.(*ResproxyDetails), cachedMeta(start), nil
 		}
 	}
 
 	// prepare req
-	req, err := c.newRequest(nil, "GET", "resproxy/"+ip, nil)
+	req, err := c.transport().newRequest(nil, "GET", "resproxy/"+ip, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// do req
 	v := new(ResproxyDetails)
-	if _, err := c.do(req, v); err != nil {
-		return nil, err
+	_, meta, err := c.transport().doMeta(req, v)
+	if err != nil {
+		return nil, meta, err
 	}
 
 	// cache req result
-	if c.Cache != nil {
-		if err := c.Cache.Set(cacheKey, v); err != nil {
-			return v, err
+	if cache != nil {
+		if err := cache.Set(cacheKey, v); err != nil {
+			return v, meta, err
 		}
 	}
 
-	return v, nil
+	return v, meta, nil
 }
@@ -0,0 +1,101 @@
+package ipinfo
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	retryMinBackoffDefault = 500 * time.Millisecond
+	retryMaxBackoffDefault = 10 * time.Second
+)
+
+// RetryPolicy configures how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per request, including
+	// the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the wait before the first retry; it doubles with every
+	// further retry.
+	//
+	// 0 means to use a default of 500ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries, including waits requested by
+	// the API through `Retry-After`.
+	//
+	// 0 means to use a default of 10s.
+	MaxBackoff time.Duration
+
+	// ShouldRetry reports whether a request which ended with `resp` or `err`
+	// should be retried.
+	//
+	// nil means to retry network errors, 429 and 5xx responses.
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+// Limiter throttles outgoing requests. `Wait` blocks until a request may be
+// sent or `ctx` is done.
+//
+// `*rate.Limiter` from `golang.org/x/time/rate` implements this interface.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 2 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500
+}
+
+// `backoff` returns the wait before retry number `n` (starting at 1),
+// honoring a `Retry-After` header on `resp` if present.
+func (p *RetryPolicy) backoff(n int, resp *http.Response) time.Duration {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = retryMinBackoffDefault
+	}
+	if max <= 0 {
+		max = retryMaxBackoffDefault
+	}
+
+	d := min
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if resp != nil {
+		if rl := parseRateLimit(resp.Header, time.Now()); rl != nil && rl.RetryAfter > 0 {
+			d = rl.RetryAfter
+		}
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// `sleep` waits for `d` or until `ctx` is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,267 @@
+package ipinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SchemaError reports differences between an API response and the type it
+// was decoded into. Field names are dotted JSON paths, e.g. "geo.city".
+type SchemaError struct {
+	// Name of the type the response was decoded into.
+	Type string
+
+	// Fields present in the response which the type does not know about.
+	Unknown []string
+
+	// Fields the API always sends for the type which were absent from the
+	// response.
+	Missing []string
+}
+
+func (e *SchemaError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown fields "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing fields "+strings.Join(e.Missing, ", "))
+	}
+	return fmt.Sprintf("schema mismatch for %s: %s", e.Type, strings.Join(parts, "; "))
+}
+
+// CheckSchema compares the JSON object in `data` against the type of `v`,
+// which must be a pointer to a struct, and returns a `*SchemaError` if the
+// data contains fields unknown to the type or lacks fields the type expects.
+//
+// A field is expected when it is tagged `schema:"required"`, which marks the
+// fields the API sends in every response of the type; all other fields are
+// optional. Nested objects are checked only when present in `data`.
+//
+// This is useful to detect API drift against recorded fixtures, e.g.
+//
+//	err := ipinfo.CheckSchema(fixture, new(ipinfo.Core))
+func CheckSchema(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("ipinfo: CheckSchema of non-struct %T", v)
+	}
+
+	e := &SchemaError{Type: t.Name()}
+	checkSchema("", data, t, e)
+	if len(e.Unknown) == 0 && len(e.Missing) == 0 {
+		return nil
+	}
+	sort.Strings(e.Unknown)
+	sort.Strings(e.Missing)
+	return e
+}
+
+func checkSchema(
+	path string,
+	data json.RawMessage,
+	t reflect.Type,
+	e *SchemaError,
+) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+			return
+		}
+
+		fields := jsonFields(t)
+		for key, val := range obj {
+			f, ok := lookupJSONField(fields, key)
+			if !ok {
+				e.Unknown = append(e.Unknown, path+key)
+				continue
+			}
+			checkSchema(path+f.name+".", val, f.typ, e)
+		}
+		for _, f := range fields {
+			if !f.required {
+				continue
+			}
+			if _, ok := lookupJSONKey(obj, f.name); !ok {
+				e.Missing = append(e.Missing, path+f.name)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !isStructType(t.Elem()) {
+			return
+		}
+		var arr []json.RawMessage
+		if err := json.Unmarshal(data, &arr); err != nil {
+			return
+		}
+		for _, val := range arr {
+			checkSchema(path, val, t.Elem(), e)
+		}
+	}
+}
+
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// jsonField describes a struct field as seen by `encoding/json`.
+type jsonField struct {
+	name     string
+	index    int
+	typ      reflect.Type
+	required bool
+}
+
+var jsonFieldsCache sync.Map // map[reflect.Type][]jsonField
+
+// `jsonFields` returns the JSON-visible fields of the struct type `t`.
+func jsonFields(t reflect.Type) []jsonField {
+	if v, ok := jsonFieldsCache.Load(t); ok {
+		return v.([]jsonField)
+	}
+
+	fields := make([]jsonField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{
+			name:     name,
+			index:    i,
+			typ:      sf.Type,
+			required: sf.Tag.Get("schema") == "required",
+		})
+	}
+
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+// `lookupJSONField` finds the field that `encoding/json` would decode `key`
+// into, preferring an exact match over a case-insensitive one.
+func lookupJSONField(fields []jsonField, key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func lookupJSONKey(
+	obj map[string]json.RawMessage,
+	name string,
+) (json.RawMessage, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// `extraFields` returns the top-level members of the JSON object in `data`
+// which have no corresponding field in the struct type `t`, or nil if there
+// are none.
+func extraFields(
+	data []byte,
+	t reflect.Type,
+) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	var extra map[string]json.RawMessage
+	fields := jsonFields(t)
+	for k, v := range obj {
+		if _, ok := lookupJSONField(fields, k); ok {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[k] = v
+	}
+	return extra, nil
+}
+
+// `marshalWithExtra` appends the members of `extra` to the JSON object `data`,
+// skipping members already present.
+func marshalWithExtra(
+	data []byte,
+	extra map[string]json.RawMessage,
+) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if _, exists := obj[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return data, nil
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimRight(data, " \t\r\n"))
+	buf.Truncate(buf.Len() - 1) // drop the closing brace.
+	empty := len(obj) == 0
+	for _, k := range keys {
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
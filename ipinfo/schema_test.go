@@ -0,0 +1,229 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+func TestExtraFields(t *testing.T) {
+	data := []byte(`{"ip":"8.8.8.8","city":"Mountain View","new_field":{"a":1},"score":0.5}`)
+
+	var v ipinfo.Core
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.City != "Mountain View" {
+		t.Errorf("City = %q, want %q", v.City, "Mountain View")
+	}
+	want := map[string]json.RawMessage{
+		"new_field": json.RawMessage(`{"a":1}`),
+		"score":     json.RawMessage(`0.5`),
+	}
+	if !reflect.DeepEqual(v.Extra, want) {
+		t.Errorf("Extra = %s, want %s", v.Extra, want)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var round map[string]interface{}
+	if err := json.Unmarshal(out, &round); err != nil {
+		t.Fatalf("invalid JSON %s: %v", out, err)
+	}
+	if round["score"] != 0.5 || round["city"] != "Mountain View" {
+		t.Errorf("Marshal = %s, want the known and the extra fields", out)
+	}
+}
+
+func TestExtraFieldsNone(t *testing.T) {
+	var v ipinfo.CoreResponse
+	if err := json.Unmarshal([]byte(`{"ip":"8.8.8.8","geo":{"city":"Sydney"}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Extra != nil {
+		t.Errorf("Extra = %s, want nil", v.Extra)
+	}
+}
+
+func TestExtraFieldsResponse(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/lookup/", ipinfotest.Fault{Body: `{"ip":"8.8.8.8","hostname":"dns.google","is_new":true}`})
+
+	v, err := srv.PlusClient().GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Extra["is_new"]) != "true" {
+		t.Errorf("Extra = %s, want is_new", v.Extra)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		v       interface{}
+		unknown []string
+		missing []string
+	}{
+		{
+			name: "match",
+			data: `{"ip":"8.8.8.8","city":"Mountain View"}`,
+			v:    new(ipinfo.Core),
+		},
+		{
+			name:    "unknown",
+			data:    `{"ip":"8.8.8.8","zeta":1,"alpha":2}`,
+			v:       new(ipinfo.Core),
+			unknown: []string{"alpha", "zeta"},
+		},
+		{
+			name:    "missing",
+			data:    `{"city":"Mountain View"}`,
+			v:       new(ipinfo.Core),
+			missing: []string{"ip"},
+		},
+		{
+			name:    "nested",
+			data:    `{"ip":"8.8.8.8","asn":{"asn":"AS15169","name":"Google LLC","domain":"google.com","type":"hosting","size":1}}`,
+			v:       new(ipinfo.Core),
+			unknown: []string{"asn.size"},
+			missing: []string{"asn.route"},
+		},
+		{
+			name: "optional",
+			data: `{"ip":"8.8.8.8","country_code":"US","country":"United States","continent_code":"NA","continent":"North America"}`,
+			v:    new(ipinfo.Lite),
+		},
+		{
+			name:    "required",
+			data:    `{"ip":"8.8.8.8","geo":{"city":"Sydney"}}`,
+			v:       new(ipinfo.CoreResponse),
+			missing: []string{"is_anonymous", "is_anycast", "is_hosting", "is_mobile", "is_satellite"},
+		},
+		{
+			name: "case insensitive",
+			data: `{"IP":"8.8.8.8"}`,
+			v:    new(ipinfo.Core),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ipinfo.CheckSchema([]byte(tt.data), tt.v)
+			if tt.unknown == nil && tt.missing == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+
+			var se *ipinfo.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("err = %v, want a *SchemaError", err)
+			}
+			if !reflect.DeepEqual(se.Unknown, tt.unknown) {
+				t.Errorf("Unknown = %q, want %q", se.Unknown, tt.unknown)
+			}
+			if !reflect.DeepEqual(se.Missing, tt.missing) {
+				t.Errorf("Missing = %q, want %q", se.Missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaNonStruct(t *testing.T) {
+	if err := ipinfo.CheckSchema([]byte(`{}`), new(string)); err == nil {
+		t.Error("err = nil for a non-struct type")
+	}
+}
+
+func TestStrictSchema(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	body := `{"ip":"8.8.8.8","country_code":"US","renamed":"x"}`
+	srv.Fault("/lite/", ipinfotest.Fault{Body: body})
+
+	// lenient clients accept the drift.
+	if _, err := srv.LiteClient().GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatalf("lenient client: %v", err)
+	}
+
+	_, err := srv.LiteClient(ipinfo.WithStrictSchema()).GetIPInfo(net.ParseIP("8.8.8.8"))
+	var se *ipinfo.SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want a *SchemaError", err)
+	}
+	if se.Type != "Lite" {
+		t.Errorf("Type = %q, want %q", se.Type, "Lite")
+	}
+	if !reflect.DeepEqual(se.Unknown, []string{"renamed"}) {
+		t.Errorf("Unknown = %q, want [renamed]", se.Unknown)
+	}
+	if len(se.Missing) == 0 {
+		t.Error("Missing is empty, want the Lite fields absent from the response")
+	}
+}
+
+func TestStrictSchemaMatching(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetLite("8.8.8.8", &ipinfo.Lite{ASN: "AS15169", CountryCode: "US"})
+
+	client := srv.LiteClient(ipinfo.WithStrictSchema())
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("err = %v for a response matching the schema", err)
+	}
+}
+
+func TestStrictSchemaLite(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	body := `{"ip":"8.8.8.8","asn":"AS15169","as_name":"Google LLC",` +
+		`"as_domain":"google.com","country_code":"US","country":"United States",` +
+		`"continent_code":"NA","continent":"North America"}`
+	srv.Fault("/lite/", ipinfotest.Fault{Body: body})
+
+	client := srv.LiteClient(ipinfo.WithStrictSchema())
+	v, err := client.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatalf("err = %v for a non-bogon Lite response", err)
+	}
+	if v.ASName != "Google LLC" {
+		t.Errorf("ASName = %q, want %q", v.ASName, "Google LLC")
+	}
+}
+
+func TestStrictSchemaBatch(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.Fault("/batch", ipinfotest.Fault{Body: `{"8.8.8.8":{"ip":"8.8.8.8","renamed":"x"}}`})
+
+	_, err := srv.Client().GetIPInfoBatch(
+		[]net.IP{net.ParseIP("8.8.8.8")},
+		ipinfo.BatchReqOpts{},
+	)
+	if err != nil {
+		t.Fatalf("lenient client: %v", err)
+	}
+
+	_, err = srv.Client(ipinfo.WithStrictSchema()).GetIPInfoBatch(
+		[]net.IP{net.ParseIP("8.8.8.8")},
+		ipinfo.BatchReqOpts{},
+	)
+	var se *ipinfo.SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want a *SchemaError", err)
+	}
+	if !reflect.DeepEqual(se.Unknown, []string{"renamed"}) {
+		t.Errorf("Unknown = %q, want [renamed]", se.Unknown)
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"net"
+	"net/netip"
 )
 
 // IPSummary is the full JSON response from the IP summary API.
 type IPSummary struct {
-	Total     uint64            `json:"total"`
-	Unique    uint64            `json:"unique"`
+	Total     uint64            `json:"total" csv:"total"`
+	Unique    uint64            `json:"unique" csv:"unique"`
 	Countries map[string]uint64 `json:"countries"`
 	Cities    map[string]uint64 `json:"cities"`
 	Regions   map[string]uint64 `json:"regions"`
@@ -18,18 +19,18 @@ type IPSummary struct {
 	IPTypes   map[string]uint64 `json:"ipTypes"`
 	Routes    map[string]uint64 `json:"routes"`
 	Carriers  map[string]uint64 `json:"carriers"`
-	Mobile    uint64            `json:"mobile"`
+	Mobile    uint64            `json:"mobile" csv:"mobile"`
 	Domains   map[string]uint64 `json:"domains"`
 	Privacy   struct {
-		VPN     uint64 `json:"vpn"`
-		Proxy   uint64 `json:"proxy"`
-		Hosting uint64 `json:"hosting"`
-		Relay   uint64 `json:"relay"`
-		Tor     uint64 `json:"tor"`
-	} `json:"privacy"`
+		VPN     uint64 `json:"vpn" csv:"vpn"`
+		Proxy   uint64 `json:"proxy" csv:"proxy"`
+		Hosting uint64 `json:"hosting" csv:"hosting"`
+		Relay   uint64 `json:"relay" csv:"relay"`
+		Tor     uint64 `json:"tor" csv:"tor"`
+	} `json:"privacy" csv:"privacy_,inline"`
 	PrivacyServices map[string]uint64 `json:"privacyServices"`
-	Anycast         uint64            `json:"anycast"`
-	Bogon           uint64            `json:"bogon"`
+	Anycast         uint64            `json:"anycast" csv:"anycast"`
+	Bogon           uint64            `json:"bogon" csv:"bogon"`
 }
 
 // GetIPSummary returns summarized results for a group of IPs.
@@ -45,16 +46,50 @@ func (c *Client) GetIPSummary(ips []net.IP) (*IPSummary, error) {
 	}
 	jsonBuf := bytes.NewBuffer(jsonArrStr)
 
-	req, err := c.newRequest(nil, "POST", "summarize?cli=1", jsonBuf)
+	req, err := c.transport().newRequest(nil, "POST", "summarize?cli=1", jsonBuf)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	result := new(IPSummary)
-	if _, err := c.do(req, result); err != nil {
+	if _, err := c.transport().do(req, result); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
+
+// GetIPAddrSummary is like `GetIPSummary` but takes `netip.Addr`s. An
+// `*InvalidIPError` is returned if any of `addrs` is not a valid IP without a
+// zone.
+func GetIPAddrSummary(addrs []netip.Addr) (*IPSummary, error) {
+	return DefaultClient.GetIPAddrSummary(addrs)
+}
+
+// GetIPAddrSummary is like `GetIPSummary` but takes `netip.Addr`s. An
+// `*InvalidIPError` is returned if any of `addrs` is not a valid IP without a
+// zone.
+func (c *Client) GetIPAddrSummary(addrs []netip.Addr) (*IPSummary, error) {
+	ips, err := addrIPs(addrs)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPSummary(ips)
+}
+
+// GetIPStrSummary is like `GetIPSummary` but takes strings. An
+// `*InvalidIPError` is returned if any of `ips` is not a valid IP.
+func GetIPStrSummary(ips []string) (*IPSummary, error) {
+	return DefaultClient.GetIPStrSummary(ips)
+}
+
+// GetIPStrSummary is like `GetIPSummary` but takes strings. An
+// `*InvalidIPError` is returned if any of `ips` is not a valid IP.
+func (c *Client) GetIPStrSummary(ips []string) (*IPSummary, error) {
+	parsed, err := parseIPs(ips)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPSummary(parsed)
+}
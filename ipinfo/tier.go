@@ -0,0 +1,348 @@
+package ipinfo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	autoDetectRetryDefault = time.Minute
+	autoDowngradeDefault   = time.Minute
+)
+
+// Tier identifies an IPinfo API product tier.
+type Tier int
+
+const (
+	// TierUnknown means the tier has not been determined.
+	TierUnknown Tier = iota
+
+	// TierLite is the IPinfo Lite API.
+	TierLite
+
+	// TierCore is the IPinfo Core API.
+	TierCore
+
+	// TierPlus is the IPinfo Plus API.
+	TierPlus
+
+	// TierLegacy is the legacy IPinfo API used by `Client`. Its fields depend
+	// on the plan of the token, so it is not ordered relative to the others.
+	TierLegacy
+)
+
+func (t Tier) String() string {
+	switch t {
+	case TierLite:
+		return "lite"
+	case TierCore:
+		return "core"
+	case TierPlus:
+		return "plus"
+	case TierLegacy:
+		return "legacy"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes `t` as its name.
+func (t Tier) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a tier name into `t`.
+func (t *Tier) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "lite":
+		*t = TierLite
+	case "core":
+		*t = TierCore
+	case "plus":
+		*t = TierPlus
+	case "legacy":
+		*t = TierLegacy
+	case "unknown", "":
+		*t = TierUnknown
+	default:
+		return fmt.Errorf("invalid tier %q", text)
+	}
+	return nil
+}
+
+// TierResult is the result of a lookup by a client spanning several tiers.
+// Only the field corresponding to `Tier` is set.
+type TierResult struct {
+	// Tier which answered the lookup.
+	Tier Tier
+
+	Lite *Lite
+	Core *CoreResponse
+	Plus *Plus
+}
+
+// AutoClient is a client which looks up IPs in the highest tier that its
+// token is entitled to, falling back to lower tiers when the token lacks
+// access.
+type AutoClient struct {
+	// Clients for the individual tiers, sharing the same configuration.
+	Lite *LiteClient
+	Core *CoreClient
+	Plus *PlusClient
+
+	// Client for the legacy API, used to query the token details.
+	Account *Client
+
+	// Serializes detections, so that concurrent first lookups query the
+	// token details only once.
+	detectMu sync.Mutex
+
+	mu       sync.Mutex
+	tier     Tier
+	detected bool
+
+	// Time before which a failed detection is not retried.
+	retryDetect time.Time
+
+	// Tier to start lookups at instead of `tier` after a 403, until
+	// `restoreTier`.
+	downgraded  Tier
+	restoreTier time.Time
+}
+
+// NewAutoClient creates a new plan-aware client for all tiers.
+//
+// If `httpClient` is nil, `http.DefaultClient` will be used.
+//
+// If `cache` is non-nil, it is shared by the clients of all tiers.
+func NewAutoClient(
+	httpClient *http.Client,
+	cache *Cache,
+	token string,
+) *AutoClient {
+	return &AutoClient{
+		Lite:    NewLiteClient(httpClient, cache, token),
+		Core:    NewCoreClient(httpClient, cache, token),
+		Plus:    NewPlusClient(httpClient, cache, token),
+		Account: NewClient(httpClient, nil, token),
+	}
+}
+
+// NewAutoClientWithOptions returns a new plan-aware client for all tiers
+// whose clients, including `Account`, are all configured by `opts`. The token
+// details are never cached.
+//
+// Note that `WithBaseURL` sets the same base URL for all tiers; use `FromEnv`
+// with the per-product variables such as `IPINFO_LITE_BASE_URL`, or
+// `Reconfigure` the clients of the individual tiers instead.
+func NewAutoClientWithOptions(opts ...Option) (*AutoClient, error) {
+	lite, err := NewLiteClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	core, err := NewCoreClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	plus, err := NewPlusClientWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	account, err := NewClientWithOptions(append(opts[:len(opts):len(opts)], WithoutCache())...)
+	if err != nil {
+		return nil, err
+	}
+	return &AutoClient{Lite: lite, Core: core, Plus: plus, Account: account}, nil
+}
+
+// Detect queries the details of the client's token and selects the tier to
+// use accordingly. It is called automatically by the first lookup.
+//
+// If the details can't be queried, or don't report the entitlements of the
+// token, lookups start at the Plus tier and fall back from there. A failed
+// detection is retried by a lookup a minute later.
+func (c *AutoClient) Detect() (*TokenDetails, error) {
+	c.detectMu.Lock()
+	defer c.detectMu.Unlock()
+	return c.detect()
+}
+
+// `detect` is like `Detect` but must be called with `c.detectMu` held.
+func (c *AutoClient) detect() (*TokenDetails, error) {
+	details, err := c.Account.GetTokenDetails()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		// without details, start at the top and let lookups fall back.
+		if c.tier == TierUnknown {
+			c.tier = TierPlus
+		}
+		c.retryDetect = time.Now().Add(autoDetectRetryDefault)
+		return nil, err
+	}
+	c.detected = true
+	c.tier = details.Tier()
+	if c.tier == TierUnknown {
+		c.tier = TierPlus
+	}
+	c.downgraded = TierUnknown
+	return details, nil
+}
+
+// SetTier pins the tier to start lookups at, e.g. when the plan of the token
+// is known, so that it is not detected from the token details. Lookups still
+// fall back to lower tiers when the token lacks access; see `GetIPInfo`.
+func (c *AutoClient) SetTier(tier Tier) error {
+	if tier < TierLite || tier > TierPlus {
+		return errors.New("invalid tier " + tier.String())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tier = tier
+	c.detected = true
+	c.downgraded = TierUnknown
+	return nil
+}
+
+// Tier returns the tier currently used for lookups, or `TierUnknown` if it
+// has not been detected yet.
+func (c *AutoClient) Tier() Tier {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tierLocked()
+}
+
+// `tierLocked` is like `Tier` but must be called with `c.mu` held.
+func (c *AutoClient) tierLocked() Tier {
+	if c.downgraded != TierUnknown && time.Now().Before(c.restoreTier) {
+		return c.downgraded
+	}
+	return c.tier
+}
+
+// `currentTier` returns the tier to start lookups at, detecting it first if
+// necessary.
+func (c *AutoClient) currentTier() Tier {
+	if tier, ok := c.knownTier(); ok {
+		return tier
+	}
+
+	c.detectMu.Lock()
+	defer c.detectMu.Unlock()
+	// another lookup may have detected the tier while we waited.
+	if tier, ok := c.knownTier(); ok {
+		return tier
+	}
+	// NOTE: a detection error is not fatal; see `Detect`.
+	c.detect()
+	return c.Tier()
+}
+
+// `knownTier` returns the tier to start lookups at, unless it is still to be
+// detected.
+func (c *AutoClient) knownTier() (Tier, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.detected || time.Now().Before(c.retryDetect) {
+		return c.tierLocked(), true
+	}
+	return TierUnknown, false
+}
+
+// `downgrade` records that the token lacks access to `tier`, so that lookups
+// start below it for a minute. The detected or pinned tier is kept, as the
+// access may be restored, e.g. by an upgrade of the plan.
+func (c *AutoClient) downgrade(tier Tier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tierLocked() >= tier {
+		c.downgraded = tier - 1
+		c.restoreTier = time.Now().Add(autoDowngradeDefault)
+	}
+}
+
+// GetIPInfo returns the details for the specified IP from the highest tier
+// the token has access to.
+//
+// When the token lacks access to a tier, the lookup falls back to the next
+// lower one, and later lookups start there for a minute before trying the
+// detected or pinned tier again.
+func (c *AutoClient) GetIPInfo(ip net.IP) (*TierResult, error) {
+	var err error
+	for tier := c.currentTier(); tier >= TierLite; tier-- {
+		var res *TierResult
+		res, err = lookupTier(tier, c.Lite, c.Core, c.Plus, ip)
+		if err == nil {
+			return res, nil
+		}
+		if !isAccessError(err) || tier == TierLite {
+			return nil, err
+		}
+		c.downgrade(tier)
+	}
+	return nil, err
+}
+
+// GetIPAddrInfo is like `GetIPInfo` but takes a `netip.Addr`. An
+// `*InvalidIPError` is returned if `addr` is not a valid IP without a zone.
+func (c *AutoClient) GetIPAddrInfo(addr netip.Addr) (*TierResult, error) {
+	ip, err := addrIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPInfo(ip)
+}
+
+// GetIPStrInfo is like `GetIPInfo` but takes a string. An `*InvalidIPError`
+// is returned if `ip` is not a valid IP.
+func (c *AutoClient) GetIPStrInfo(ip string) (*TierResult, error) {
+	parsed, err := parseIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetIPInfo(parsed)
+}
+
+// `lookupTier` looks up `ip` with the client for `tier`.
+func lookupTier(
+	tier Tier,
+	lite *LiteClient,
+	core *CoreClient,
+	plus *PlusClient,
+	ip net.IP,
+) (*TierResult, error) {
+	res := &TierResult{Tier: tier}
+	var err error
+	switch tier {
+	case TierLite:
+		res.Lite, err = lite.GetIPInfo(ip)
+	case TierCore:
+		res.Core, err = core.GetIPInfo(ip)
+	case TierPlus:
+		res.Plus, err = plus.GetIPInfo(ip)
+	default:
+		return nil, errors.New("invalid tier " + tier.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// `isAccessError` reports whether `err` means that the token lacks access to
+// the requested API. An invalid token, reported as 401, has no access to any
+// tier, so only 403 qualifies.
+func isAccessError(err error) bool {
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	return errResp.Response.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,358 @@
+package ipinfo_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `autoClient` returns an `AutoClient` whose clients are pointed at `srv`.
+func autoClient(srv *ipinfotest.Server) *ipinfo.AutoClient {
+	c := ipinfo.NewAutoClient(nil, nil, "")
+	c.Lite = srv.LiteClient()
+	c.Core = srv.CoreClient()
+	c.Plus = srv.PlusClient()
+	c.Account = srv.Client()
+	return c
+}
+
+// `tokenDetails` returns token details entitled to `features`, e.g. "core".
+func tokenDetails(plan string, features ...string) *ipinfo.TokenDetails {
+	d := &ipinfo.TokenDetails{Token: "t", Plan: plan, Features: make(map[string]json.RawMessage)}
+	for _, f := range features {
+		d.Features[f] = json.RawMessage("true")
+	}
+	return d
+}
+
+// `countRequests` returns the number of requests `srv` received for `path`.
+func countRequests(srv *ipinfotest.Server, path string) int {
+	n := 0
+	for _, req := range srv.Requests() {
+		if req.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAutoClientDetect(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Core", "core"))
+
+	c := autoClient(srv)
+	if tier := c.Tier(); tier != ipinfo.TierUnknown {
+		t.Errorf("Tier = %v before detection", tier)
+	}
+	details, err := c.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Plan != "Core" {
+		t.Errorf("Plan = %q, want %q", details.Plan, "Core")
+	}
+	if tier := c.Tier(); tier != ipinfo.TierCore {
+		t.Errorf("Tier = %v, want %v", tier, ipinfo.TierCore)
+	}
+
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierCore || res.Core == nil {
+		t.Errorf("result = %+v, want a Core result", res)
+	}
+	if n := countRequests(srv, "/me"); n != 1 {
+		t.Errorf("token details queried %d times, want once", n)
+	}
+}
+
+func TestAutoClientDetectOnce(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Lite", "lite"))
+	srv.Slow("/me", 50*time.Millisecond)
+
+	c := autoClient(srv)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res.Tier != ipinfo.TierLite {
+				t.Errorf("Tier = %v, want %v", res.Tier, ipinfo.TierLite)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := countRequests(srv, "/me"); n != 1 {
+		t.Errorf("token details queried %d times by concurrent lookups, want once", n)
+	}
+}
+
+func TestAutoClientDetectFailure(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/me", ipinfotest.Fault{Status: http.StatusBadGateway, Times: 1})
+	srv.SetTokenDetails(tokenDetails("Lite", "lite"))
+
+	c := autoClient(srv)
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierPlus {
+		t.Errorf("Tier = %v, want lookups to start at Plus without details", res.Tier)
+	}
+
+	// the failure isn't retried right away by lookups...
+	if _, err := c.GetIPInfo(net.ParseIP("1.1.1.1")); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRequests(srv, "/me"); n != 1 {
+		t.Errorf("token details queried %d times, want once", n)
+	}
+
+	// ...but later, rather than pinning the fallback tier.
+	c.ExpireDetectRetry()
+	res, err = c.GetIPInfo(net.ParseIP("1.1.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v after a successful detection, want %v", res.Tier, ipinfo.TierLite)
+	}
+	if n := countRequests(srv, "/me"); n != 2 {
+		t.Errorf("token details queried %d times, want twice", n)
+	}
+}
+
+func TestAutoClientDowngrade(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Plus", "plus"))
+	// Plus and Core deny the first lookup only.
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusForbidden, Times: 2})
+
+	c := autoClient(srv)
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierLite || res.Lite == nil {
+		t.Errorf("result = %+v, want a Lite result", res)
+	}
+	if tier := c.Tier(); tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v after 403s, want %v", tier, ipinfo.TierLite)
+	}
+
+	// later lookups start at the lower tier...
+	if _, err := c.GetIPInfo(net.ParseIP("1.1.1.1")); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRequests(srv, "/lookup/8.8.8.8") + countRequests(srv, "/lookup/1.1.1.1"); n != 2 {
+		t.Errorf("Core and Plus queried %d times, want only by the first lookup", n)
+	}
+
+	// ...for a while, after which the detected tier is tried again.
+	c.ExpireDowngrade()
+	if tier := c.Tier(); tier != ipinfo.TierPlus {
+		t.Errorf("Tier = %v after the downgrade expired, want %v", tier, ipinfo.TierPlus)
+	}
+	res, err = c.GetIPInfo(net.ParseIP("1.1.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierPlus {
+		t.Errorf("Tier = %v once access is restored, want %v", res.Tier, ipinfo.TierPlus)
+	}
+	if n := countRequests(srv, "/me"); n != 1 {
+		t.Errorf("token details queried %d times, want once", n)
+	}
+}
+
+func TestAutoClientDetectWithoutEntitlements(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Basic"))
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusForbidden})
+
+	// lookups start at the top and fall back.
+	c := autoClient(srv)
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v, want %v", res.Tier, ipinfo.TierLite)
+	}
+	if n := countRequests(srv, "/lookup/8.8.8.8"); n != 2 {
+		t.Errorf("Core and Plus queried %d times, want twice", n)
+	}
+}
+
+func TestAutoClientUnauthorized(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Plus", "plus"))
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusUnauthorized})
+
+	c := autoClient(srv)
+	if _, err := c.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Fatal("err = nil for a 401 response")
+	}
+	if tier := c.Tier(); tier != ipinfo.TierPlus {
+		t.Errorf("Tier = %v, want a 401 not to downgrade", tier)
+	}
+	if n := countRequests(srv, "/lite/8.8.8.8"); n != 0 {
+		t.Errorf("Lite queried %d times after a 401", n)
+	}
+}
+
+func TestTierText(t *testing.T) {
+	for _, tier := range []ipinfo.Tier{ipinfo.TierUnknown, ipinfo.TierLite, ipinfo.TierCore, ipinfo.TierPlus, ipinfo.TierLegacy} {
+		text, err := tier.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ipinfo.Tier
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if got != tier {
+			t.Errorf("UnmarshalText(%q) = %v, want %v", text, got, tier)
+		}
+	}
+
+	var tier ipinfo.Tier
+	if err := tier.UnmarshalText([]byte("gold")); err == nil {
+		t.Error("err = nil for an invalid tier")
+	}
+}
+
+func TestTokenDetailsTier(t *testing.T) {
+	tests := []struct {
+		plan     string
+		features string
+		want     ipinfo.Tier
+	}{
+		// the tier isn't guessed from the plan name.
+		{"Plus", "", ipinfo.TierUnknown},
+		{"Basic", "", ipinfo.TierUnknown},
+		{"", "", ipinfo.TierUnknown},
+		{"Free", `{"plus": false}`, ipinfo.TierUnknown},
+		// entitlements decide regardless of the plan name.
+		{"Free", `{"core": {"daily": 1000}}`, ipinfo.TierCore},
+		{"Business", `{"lite": true}`, ipinfo.TierLite},
+		{"Basic", `{"plus": true, "core": true}`, ipinfo.TierPlus},
+	}
+	for _, tt := range tests {
+		var details ipinfo.TokenDetails
+		body := `{"plan": "` + tt.plan + `"`
+		if tt.features != "" {
+			body += `, "features": ` + tt.features
+		}
+		if err := json.Unmarshal([]byte(body+"}"), &details); err != nil {
+			t.Fatal(err)
+		}
+		if got := details.Tier(); got != tt.want {
+			t.Errorf("Tier of %s = %v, want %v", body+"}", got, tt.want)
+		}
+	}
+}
+
+func TestAutoClientSetTier(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.SetTokenDetails(tokenDetails("Plus", "plus"))
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusForbidden})
+
+	c := autoClient(srv)
+	if err := c.SetTier(ipinfo.TierCore); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countRequests(srv, "/me"); n != 0 {
+		t.Errorf("token details queried %d times despite the pinned tier", n)
+	}
+	// the pinned tier still falls back.
+	if res.Tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v, want %v", res.Tier, ipinfo.TierLite)
+	}
+	if n := countRequests(srv, "/lookup/8.8.8.8"); n != 1 {
+		t.Errorf("Core and Plus queried %d times, want Core only", n)
+	}
+
+	// the pinned tier is tried again once the downgrade expires.
+	c.ExpireDowngrade()
+	if tier := c.Tier(); tier != ipinfo.TierCore {
+		t.Errorf("Tier = %v after the downgrade expired, want the pinned %v", tier, ipinfo.TierCore)
+	}
+
+	for _, tier := range []ipinfo.Tier{ipinfo.TierUnknown, ipinfo.TierLegacy} {
+		if err := c.SetTier(tier); err == nil {
+			t.Errorf("err = nil for tier %v", tier)
+		}
+	}
+}
+
+func TestNewAutoClientWithOptions(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	srv.SetTokenDetails(&ipinfo.TokenDetails{Token: "secret", Plan: "Plus"})
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusForbidden})
+
+	t.Setenv("IPINFO_BASE_URL", srv.URL)
+	t.Setenv("IPINFO_LITE_BASE_URL", srv.URL+"/lite")
+	t.Setenv("IPINFO_CORE_BASE_URL", srv.URL+"/lookup")
+	t.Setenv("IPINFO_PLUS_BASE_URL", srv.URL+"/lookup")
+
+	c, err := ipinfo.NewAutoClientWithOptions(
+		ipinfo.FromEnv(),
+		ipinfo.WithToken("secret"),
+		ipinfo.WithUserAgent("auto-agent"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.GetIPInfo(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != ipinfo.TierLite {
+		t.Errorf("Tier = %v, want %v", res.Tier, ipinfo.TierLite)
+	}
+
+	// the token details and every tier were queried with the options.
+	want := []string{"/me", "/lookup/8.8.8.8", "/lookup/8.8.8.8", "/lite/8.8.8.8"}
+	reqs := srv.Requests()
+	if len(reqs) != len(want) {
+		t.Fatalf("requests = %+v, want %q", reqs, want)
+	}
+	for i, req := range reqs {
+		if req.Path != want[i] || req.Header.Get("User-Agent") != "auto-agent" {
+			t.Errorf("request %d = %s with User-Agent %q, want %s", i, req.Path, req.Header.Get("User-Agent"), want[i])
+		}
+	}
+
+	if _, err := ipinfo.NewAutoClientWithOptions(ipinfo.WithBaseURL("ipinfo.io")); err == nil {
+		t.Error("err = nil for an invalid option")
+	}
+}
@@ -0,0 +1,231 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	tokenPoolRateLimitedBenchDefault  = time.Minute
+	tokenPoolUnauthorizedBenchDefault = time.Hour
+)
+
+// ErrNoTokenAvailable is returned by a `TokenPool` when all of its tokens are
+// benched.
+var ErrNoTokenAvailable = errors.New("no API token available")
+
+// TokenProvider supplies the API tokens used to authorize requests.
+//
+// Implementations must be concurrency-safe.
+type TokenProvider interface {
+	// Token returns the token to authorize the next request with, or an
+	// empty string to send the request without a token.
+	Token(ctx context.Context) (string, error)
+
+	// Report is called with the outcome of every request authorized with a
+	// token returned by `Token`. Exactly one of `resp` and `err` is non-nil.
+	Report(token string, resp *http.Response, err error)
+}
+
+// TokenStrategy selects which token of a `TokenPool` is used next.
+type TokenStrategy int
+
+const (
+	// TokenRoundRobin uses the available tokens in turn.
+	TokenRoundRobin TokenStrategy = iota
+
+	// TokenMostRemaining uses the available token with the most remaining
+	// quota, as last reported by the API. Tokens whose quota is not yet
+	// known are preferred, so that it becomes known.
+	TokenMostRemaining
+)
+
+// TokenPoolOpts are options for a `TokenPool`.
+type TokenPoolOpts struct {
+	// Strategy used to select the next token.
+	Strategy TokenStrategy
+
+	// RateLimitedBench is how long a token is left unused after a 429
+	// response, unless the response says otherwise through `Retry-After`.
+	//
+	// 0 means to use a default of 1 minute.
+	RateLimitedBench time.Duration
+
+	// UnauthorizedBench is how long a token is left unused after a 401
+	// response.
+	//
+	// 0 means to use a default of 1 hour.
+	UnauthorizedBench time.Duration
+}
+
+// TokenUsage reports the usage of a single token of a `TokenPool`.
+type TokenUsage struct {
+	// The token.
+	Token string
+
+	// Number of requests authorized with the token.
+	Requests uint64
+
+	// Number of those requests which failed, including API errors.
+	Errors uint64
+
+	// Number of 429 responses received for the token.
+	RateLimited uint64
+
+	// Number of 401 responses received for the token.
+	Unauthorized uint64
+
+	// Number of 403 responses received for the token. These don't bench the
+	// token, as they deny access to the data requested, e.g. of a higher
+	// tier, rather than to the API.
+	Forbidden uint64
+
+	// Remaining quota last reported by the API, or -1 if unknown.
+	Remaining int64
+
+	// Time until which the token is benched; zero if it is available.
+	BenchedUntil time.Time
+}
+
+// TokenPool is a `TokenProvider` which spreads requests across several
+// tokens, temporarily benching tokens which are rate limited or rejected.
+//
+// Requests which fail on a benched token are not retried unless the client
+// also has a retry policy; retries pick a token afresh.
+type TokenPool struct {
+	opts TokenPoolOpts
+
+	mu     sync.Mutex
+	tokens []*TokenUsage
+	next   int
+}
+
+// NewTokenPool returns a pool of `tokens`. Empty tokens are ignored.
+func NewTokenPool(tokens []string, opts TokenPoolOpts) *TokenPool {
+	if opts.RateLimitedBench <= 0 {
+		opts.RateLimitedBench = tokenPoolRateLimitedBenchDefault
+	}
+	if opts.UnauthorizedBench <= 0 {
+		opts.UnauthorizedBench = tokenPoolUnauthorizedBenchDefault
+	}
+
+	p := &TokenPool{opts: opts}
+	for _, tok := range tokens {
+		if tok != "" {
+			p.tokens = append(p.tokens, &TokenUsage{Token: tok, Remaining: -1})
+		}
+	}
+	return p
+}
+
+// Token returns the next available token according to the pool strategy, or
+// `ErrNoTokenAvailable` if all tokens are benched.
+func (p *TokenPool) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *TokenUsage
+	for i := range p.tokens {
+		idx := (p.next + i) % len(p.tokens)
+		u := p.tokens[idx]
+		if now.Before(u.BenchedUntil) {
+			continue
+		}
+		if p.opts.Strategy == TokenRoundRobin {
+			p.next = idx + 1
+			return u.Token, nil
+		}
+		if best == nil || remainingRank(u) > remainingRank(best) {
+			best = u
+		}
+	}
+	if best == nil {
+		return "", ErrNoTokenAvailable
+	}
+	return best.Token, nil
+}
+
+// remainingRank orders tokens for `TokenMostRemaining`, ranking tokens with
+// unknown quota highest.
+func remainingRank(u *TokenUsage) int64 {
+	if u.Remaining < 0 {
+		return 1<<63 - 1
+	}
+	return u.Remaining
+}
+
+// Report records the outcome of a request made with `token`, benching the
+// token after 429 and 401 responses.
+func (p *TokenPool) Report(token string, resp *http.Response, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var u *TokenUsage
+	for _, t := range p.tokens {
+		if t.Token == token {
+			u = t
+			break
+		}
+	}
+	if u == nil {
+		return
+	}
+
+	u.Requests++
+	if err != nil {
+		u.Errors++
+		return
+	}
+
+	now := time.Now()
+	rl := parseRateLimit(resp.Header, now)
+	if rl != nil && resp.Header.Get("X-RateLimit-Remaining") != "" {
+		u.Remaining = rl.Remaining
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		u.Errors++
+		u.RateLimited++
+		u.Remaining = 0
+		bench := p.opts.RateLimitedBench
+		if rl != nil && rl.RetryAfter > 0 {
+			bench = rl.RetryAfter
+		}
+		u.BenchedUntil = now.Add(bench)
+	case http.StatusUnauthorized:
+		u.Errors++
+		u.Unauthorized++
+		u.BenchedUntil = now.Add(p.opts.UnauthorizedBench)
+	case http.StatusForbidden:
+		u.Errors++
+		u.Forbidden++
+	default:
+		if resp.StatusCode >= 400 {
+			u.Errors++
+		}
+	}
+}
+
+// Usage returns a snapshot of the usage of every token in the pool.
+func (p *TokenPool) Usage() []TokenUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	usage := make([]TokenUsage, len(p.tokens))
+	for i, u := range p.tokens {
+		usage[i] = *u
+		if !now.Before(u.BenchedUntil) {
+			usage[i].BenchedUntil = time.Time{}
+		}
+	}
+	return usage
+}
+
+// Check if TokenPool implements TokenProvider
+var _ TokenProvider = (*TokenPool)(nil)
@@ -0,0 +1,243 @@
+package ipinfo_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `response` returns a response with status `code` and headers `kv`.
+func response(code int, kv ...string) *http.Response {
+	h := http.Header{}
+	for i := 0; i < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return &http.Response{StatusCode: code, Header: h}
+}
+
+// `usage` returns the usage of `token` in `p`.
+func usage(t *testing.T, p *ipinfo.TokenPool, token string) ipinfo.TokenUsage {
+	t.Helper()
+	for _, u := range p.Usage() {
+		if u.Token == token {
+			return u
+		}
+	}
+	t.Fatalf("no usage of token %q", token)
+	return ipinfo.TokenUsage{}
+}
+
+// `nextTokens` returns the next `n` tokens of `p`.
+func nextTokens(t *testing.T, p *ipinfo.TokenPool, n int) []string {
+	t.Helper()
+	var tokens []string
+	for i := 0; i < n; i++ {
+		tok, err := p.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+func TestTokenPoolRoundRobin(t *testing.T) {
+	p := ipinfo.NewTokenPool([]string{"a", "", "b", "c"}, ipinfo.TokenPoolOpts{})
+	got := nextTokens(t, p, 4)
+	if want := []string{"a", "b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+}
+
+func TestTokenPoolMostRemaining(t *testing.T) {
+	p := ipinfo.NewTokenPool([]string{"a", "b", "c"}, ipinfo.TokenPoolOpts{
+		Strategy: ipinfo.TokenMostRemaining,
+	})
+	p.Report("a", response(200, "X-RateLimit-Remaining", "10"), nil)
+	p.Report("b", response(200, "X-RateLimit-Remaining", "500"), nil)
+
+	// the quota of c is unknown, so it is used first.
+	if got := nextTokens(t, p, 1); got[0] != "c" {
+		t.Errorf("token = %q, want the one with unknown quota", got[0])
+	}
+	p.Report("c", response(200, "X-RateLimit-Remaining", "20"), nil)
+	if got := nextTokens(t, p, 2); !reflect.DeepEqual(got, []string{"b", "b"}) {
+		t.Errorf("tokens = %q, want the one with the most quota", got)
+	}
+	if u := usage(t, p, "b"); u.Remaining != 500 {
+		t.Errorf("Remaining = %d, want 500", u.Remaining)
+	}
+}
+
+func TestTokenPoolReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *http.Response
+		err     error
+		benched time.Duration
+		check   func(u ipinfo.TokenUsage) bool
+	}{
+		{
+			name:  "success",
+			resp:  response(200),
+			check: func(u ipinfo.TokenUsage) bool { return u.Errors == 0 },
+		},
+		{
+			name:    "rate limited",
+			resp:    response(429),
+			benched: time.Minute,
+			check: func(u ipinfo.TokenUsage) bool {
+				return u.Errors == 1 && u.RateLimited == 1 && u.Remaining == 0
+			},
+		},
+		{
+			name:    "rate limited with Retry-After",
+			resp:    response(429, "Retry-After", "5"),
+			benched: 5 * time.Second,
+			check:   func(u ipinfo.TokenUsage) bool { return u.RateLimited == 1 },
+		},
+		{
+			name:    "unauthorized",
+			resp:    response(401),
+			benched: time.Hour,
+			check:   func(u ipinfo.TokenUsage) bool { return u.Errors == 1 && u.Unauthorized == 1 },
+		},
+		{
+			name: "forbidden",
+			resp: response(403),
+			check: func(u ipinfo.TokenUsage) bool {
+				return u.Errors == 1 && u.Forbidden == 1 && u.Unauthorized == 0
+			},
+		},
+		{
+			name:  "server error",
+			resp:  response(502),
+			check: func(u ipinfo.TokenUsage) bool { return u.Errors == 1 },
+		},
+		{
+			name:  "network error",
+			err:   errors.New("connection reset"),
+			check: func(u ipinfo.TokenUsage) bool { return u.Errors == 1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ipinfo.NewTokenPool([]string{"a"}, ipinfo.TokenPoolOpts{})
+			start := time.Now()
+			p.Report("a", tt.resp, tt.err)
+
+			u := usage(t, p, "a")
+			if u.Requests != 1 {
+				t.Errorf("Requests = %d, want 1", u.Requests)
+			}
+			if !tt.check(u) {
+				t.Errorf("usage = %+v", u)
+			}
+
+			_, err := p.Token(context.Background())
+			if tt.benched == 0 {
+				if !u.BenchedUntil.IsZero() || err != nil {
+					t.Errorf("token benched until %v: %v", u.BenchedUntil, err)
+				}
+				return
+			}
+			if !errors.Is(err, ipinfo.ErrNoTokenAvailable) {
+				t.Errorf("err = %v, want %v", err, ipinfo.ErrNoTokenAvailable)
+			}
+			if d := u.BenchedUntil.Sub(start); d < tt.benched || d > tt.benched+time.Second {
+				t.Errorf("benched for %v, want %v", d, tt.benched)
+			}
+		})
+	}
+}
+
+func TestTokenPoolUnknownToken(t *testing.T) {
+	p := ipinfo.NewTokenPool([]string{"a"}, ipinfo.TokenPoolOpts{})
+	p.Report("other", response(429), nil)
+	if u := usage(t, p, "a"); u.Requests != 0 {
+		t.Errorf("usage = %+v, want reports of other tokens ignored", u)
+	}
+}
+
+func TestTokenPoolBenchExpires(t *testing.T) {
+	p := ipinfo.NewTokenPool([]string{"a"}, ipinfo.TokenPoolOpts{
+		UnauthorizedBench: 10 * time.Millisecond,
+	})
+	p.Report("a", response(401), nil)
+	if _, err := p.Token(context.Background()); err == nil {
+		t.Fatal("err = nil for a benched token")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := p.Token(context.Background()); err != nil {
+		t.Errorf("err = %v after the bench expired", err)
+	}
+	if u := usage(t, p, "a"); !u.BenchedUntil.IsZero() {
+		t.Errorf("BenchedUntil = %v after the bench expired", u.BenchedUntil)
+	}
+}
+
+func TestTokenPoolClient(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 0)
+
+	pool := ipinfo.NewTokenPool([]string{"a", "b"}, ipinfo.TokenPoolOpts{})
+	client := srv.Client(
+		ipinfo.WithTokenProvider(pool),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a is benched after the 429 and b is used from then on, including
+	// by the retry.
+	var got []string
+	for _, req := range srv.Requests() {
+		got = append(got, req.Header.Get("Authorization"))
+	}
+	want := []string{"Bearer a", "Bearer b", "Bearer b", "Bearer b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if u := usage(t, pool, "b"); u.Requests != 3 || u.Errors != 0 {
+		t.Errorf("usage of b = %+v", u)
+	}
+}
+
+func TestTokenPoolForbiddenNotBenched(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.Fault("/lookup/", ipinfotest.Fault{Status: http.StatusForbidden})
+
+	pool := ipinfo.NewTokenPool([]string{"a"}, ipinfo.TokenPoolOpts{})
+	plus := srv.PlusClient(ipinfo.WithTokenProvider(pool))
+	lite := srv.LiteClient(ipinfo.WithTokenProvider(pool))
+
+	// a 403 of the Plus API leaves the token usable for the Lite API.
+	if _, err := plus.GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Fatal("Plus: err = nil for a 403 response")
+	}
+	if _, err := lite.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("Lite: %v", err)
+	}
+	if u := usage(t, pool, "a"); u.Forbidden != 1 || !u.BenchedUntil.IsZero() {
+		t.Errorf("usage = %+v, want one 403 and no bench", u)
+	}
+}
+
+func TestTokenPoolEmpty(t *testing.T) {
+	p := ipinfo.NewTokenPool(nil, ipinfo.TokenPoolOpts{})
+	if _, err := p.Token(context.Background()); !errors.Is(err, ipinfo.ErrNoTokenAvailable) {
+		t.Errorf("err = %v, want %v", err, ipinfo.ErrNoTokenAvailable)
+	}
+}
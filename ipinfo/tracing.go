@@ -0,0 +1,83 @@
+package ipinfo
+
+import (
+	"context"
+)
+
+// Tracer starts spans for API requests. It is the adapter point for tracing
+// libraries such as OpenTelemetry, which this package doesn't depend on. For
+// OpenTelemetry, an adapter is as small as:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, ipinfo.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+// where `otelSpan` implements `Span` by calling `SetAttributes`,
+// `RecordError` and `End` on the wrapped span.
+type Tracer interface {
+	// Start starts a span named `name` as a child of any span in `ctx`,
+	// returning a context containing the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a `Tracer`.
+type Span interface {
+	// SetAttribute sets an attribute of the span. `value` is a string, int
+	// or bool.
+	SetAttribute(key string, value interface{})
+
+	// RecordError marks the span as failed with `err`.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// TracingObserver returns an `Observer` which wraps every API request,
+// including each chunk of a batch request, in a span started by `tracer`.
+// Spans are propagated through the request context, so that e.g. middleware
+// can inject trace headers.
+func TracingObserver(tracer Tracer) Observer {
+	return &tracingObserver{tracer: tracer}
+}
+
+type tracingObserver struct {
+	NopObserver
+	tracer Tracer
+}
+
+type tracingSpanKey struct{}
+
+func (o *tracingObserver) RequestStart(
+	ctx context.Context,
+	e *RequestEvent,
+) context.Context {
+	ctx, span := o.tracer.Start(ctx, "ipinfo "+e.Method)
+	span.SetAttribute("http.method", e.Method)
+	span.SetAttribute("http.url", e.URL)
+	span.SetAttribute("ipinfo.product", metricsProduct(e.Product))
+	return context.WithValue(ctx, tracingSpanKey{}, span)
+}
+
+func (o *tracingObserver) RequestEnd(ctx context.Context, e *RequestEvent) {
+	span, ok := ctx.Value(tracingSpanKey{}).(Span)
+	if !ok {
+		return
+	}
+	if e.StatusCode != 0 {
+		span.SetAttribute("http.status_code", e.StatusCode)
+	}
+	if e.Err != nil {
+		span.RecordError(e.Err)
+	}
+	span.End()
+}
+
+func (o *tracingObserver) Retry(ctx context.Context, e *RetryEvent) {
+	if span, ok := ctx.Value(tracingSpanKey{}).(Span); ok {
+		span.SetAttribute("ipinfo.retries", e.Attempt)
+	}
+}
@@ -0,0 +1,128 @@
+package ipinfo_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+type fakeSpanKey struct{}
+
+// fakeSpan records the attributes, error and end of a span.
+type fakeSpan struct {
+	name string
+
+	mu    sync.Mutex
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *fakeSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *fakeSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *fakeSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+}
+
+// fakeTracer records the spans it starts.
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, ipinfo.Span) {
+	span := &fakeSpan{name: name, attrs: map[string]interface{}{}}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, fakeSpanKey{}, span), span
+}
+
+func TestTracingObserver(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RateLimit("/", 1, 0)
+
+	// the span is propagated to middleware, e.g. to inject trace headers.
+	var propagated bool
+	tracer := &fakeTracer{}
+	client := srv.PlusClient(
+		ipinfo.WithObserver(ipinfo.TracingObserver(tracer)),
+		ipinfo.WithRetry(ipinfo.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		ipinfo.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				propagated = req.Context().Value(fakeSpanKey{}) != nil
+				return next.RoundTrip(req)
+			})
+		}),
+	)
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("%d spans started, want 1", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "ipinfo GET" || !span.ended || span.err != nil {
+		t.Errorf("span = %+v", span)
+	}
+	want := map[string]interface{}{
+		"http.method":      "GET",
+		"http.url":         srv.URL + "/lookup/8.8.8.8",
+		"ipinfo.product":   "plus",
+		"ipinfo.retries":   1,
+		"http.status_code": 200,
+	}
+	for k, v := range want {
+		if span.attrs[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, span.attrs[k], v)
+		}
+	}
+	if !propagated {
+		t.Error("span not in the request context")
+	}
+}
+
+func TestTracingObserverError(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	tracer := &fakeTracer{}
+	client := srv.Client(ipinfo.WithObserver(ipinfo.TracingObserver(tracer)))
+	ips := []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1")}
+	if _, err := client.GetIPInfoBatch(ips, ipinfo.BatchReqOpts{BatchSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(tracer.spans); n != 2 {
+		t.Errorf("%d spans started, want one per chunk", n)
+	}
+
+	tracer.spans = nil
+	if _, err := srv.Client(ipinfo.WithToken("wrong"), ipinfo.WithObserver(ipinfo.TracingObserver(tracer))).GetIPInfo(net.ParseIP("8.8.8.8")); err == nil {
+		t.Fatal("err = nil for the wrong token")
+	}
+	span := tracer.spans[0]
+	if !span.ended || span.err == nil || span.attrs["http.status_code"] != http.StatusForbidden {
+		t.Errorf("span = %+v, want a failed span", span)
+	}
+}
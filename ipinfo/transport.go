@@ -0,0 +1,478 @@
+package ipinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as
+// an `http.RoundTripper`.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the `http.RoundTripper` used by a client to add behavior
+// to every request it sends, e.g. logging, metrics or authentication.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RequestHook is called with every request a client is about to send. It may
+// modify the request; returning an error aborts it.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called with every response a client receives, before it is
+// checked for errors and decoded. Returning an error fails the call.
+type ResponseHook func(resp *http.Response) error
+
+// transport holds the configuration and request logic shared by all clients.
+//
+// The configuration in use is an immutable snapshot which is replaced when
+// the client is reconfigured, so that reconfiguration is safe while requests
+// are in flight.
+type transport struct {
+	// Current configuration snapshot.
+	cfg *clientConfig
+
+	// Exported fields of the client the transport belongs to, or nil if it
+	// isn't bound to a client yet.
+	fields *clientFields
+
+	// Guards `cfg` and the exported fields of the client.
+	mu sync.Mutex
+}
+
+// clientFields points at the exported fields of a client. Assigning one of
+// the fields overrides the corresponding configuration from the next
+// request on, and reconfiguring the client writes them back.
+type clientFields struct {
+	baseURL   **url.URL
+	userAgent *string
+	cache     **Cache
+	token     *string
+}
+
+func newTransport(
+	httpClient *http.Client,
+	baseURL string,
+	product string,
+	cache *Cache,
+	token string,
+) *transport {
+	cfg := newConfig(httpClient, baseURL, product, cache, token)
+	return newTransportFromConfig(cfg)
+}
+
+func newTransportFromConfig(cfg *clientConfig) *transport {
+	return &transport{cfg: cfg}
+}
+
+// `bind` binds `t` to the exported fields of a client. Fields which are
+// unset take the value of the configuration, and fields which are set
+// override it.
+func (t *transport) bind(f *clientFields) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if *f.baseURL == nil {
+		*f.baseURL = t.cfg.baseURL
+	}
+	if *f.userAgent == "" {
+		*f.userAgent = t.cfg.userAgent
+	}
+	if *f.cache == nil {
+		*f.cache = t.cfg.cache
+	}
+	if *f.token == "" {
+		*f.token = t.cfg.token
+	}
+	t.fields = f
+}
+
+// `setFields` sets the exported fields of the client from `cfg`. Must be
+// called with `t.mu` held.
+func (t *transport) setFields(cfg *clientConfig) {
+	if f := t.fields; f != nil {
+		*f.baseURL = cfg.baseURL
+		*f.userAgent = cfg.userAgent
+		*f.cache = cfg.cache
+		*f.token = cfg.token
+	}
+}
+
+// Use adds middleware around the HTTP transport of the client. Middleware
+// added first is outermost, i.e. sees requests first and responses last.
+//
+// Use is safe to call concurrently with requests.
+func (c *Client) Use(mw ...Middleware) {
+	c.transport().update(WithMiddleware(mw...))
+}
+
+// OnRequest adds hooks which are called with every request before it is
+// sent.
+//
+// OnRequest is safe to call concurrently with requests.
+func (c *Client) OnRequest(hooks ...RequestHook) {
+	c.transport().update(WithRequestHook(hooks...))
+}
+
+// OnResponse adds hooks which are called with every response before it is
+// decoded.
+//
+// OnResponse is safe to call concurrently with requests.
+func (c *Client) OnResponse(hooks ...ResponseHook) {
+	c.transport().update(WithResponseHook(hooks...))
+}
+
+// Use adds middleware around the HTTP transport of the client. See
+// `Client.Use`.
+func (c *LiteClient) Use(mw ...Middleware) {
+	c.transport().update(WithMiddleware(mw...))
+}
+
+// OnRequest adds hooks which are called with every request before it is
+// sent. See `Client.OnRequest`.
+func (c *LiteClient) OnRequest(hooks ...RequestHook) {
+	c.transport().update(WithRequestHook(hooks...))
+}
+
+// OnResponse adds hooks which are called with every response before it is
+// decoded. See `Client.OnResponse`.
+func (c *LiteClient) OnResponse(hooks ...ResponseHook) {
+	c.transport().update(WithResponseHook(hooks...))
+}
+
+// Use adds middleware around the HTTP transport of the client. See
+// `Client.Use`.
+func (c *CoreClient) Use(mw ...Middleware) {
+	c.transport().update(WithMiddleware(mw...))
+}
+
+// OnRequest adds hooks which are called with every request before it is
+// sent. See `Client.OnRequest`.
+func (c *CoreClient) OnRequest(hooks ...RequestHook) {
+	c.transport().update(WithRequestHook(hooks...))
+}
+
+// OnResponse adds hooks which are called with every response before it is
+// decoded. See `Client.OnResponse`.
+func (c *CoreClient) OnResponse(hooks ...ResponseHook) {
+	c.transport().update(WithResponseHook(hooks...))
+}
+
+// Use adds middleware around the HTTP transport of the client. See
+// `Client.Use`.
+func (c *PlusClient) Use(mw ...Middleware) {
+	c.transport().update(WithMiddleware(mw...))
+}
+
+// OnRequest adds hooks which are called with every request before it is
+// sent. See `Client.OnRequest`.
+func (c *PlusClient) OnRequest(hooks ...RequestHook) {
+	c.transport().update(WithRequestHook(hooks...))
+}
+
+// OnResponse adds hooks which are called with every response before it is
+// decoded. See `Client.OnResponse`.
+func (c *PlusClient) OnResponse(hooks ...ResponseHook) {
+	c.transport().update(WithResponseHook(hooks...))
+}
+
+// newRequest for IPV4
+func (t *transport) newRequest(
+	ctx context.Context,
+	method string,
+	urlStr string,
+	body io.Reader,
+) (*http.Request, error) {
+	return t.newRequestBase(ctx, method, urlStr, body, false)
+}
+
+// newRequest for IPV6
+func (t *transport) newRequestV6(
+	ctx context.Context,
+	method string,
+	urlStr string,
+	body io.Reader,
+) (*http.Request, error) {
+	return t.newRequestBase(ctx, method, urlStr, body, true)
+}
+
+// `newRequest` creates an API request. A relative URL can be provided in
+// urlStr, in which case it is resolved relative to the BaseURL of the client.
+// Relative URLs should always be specified without a preceding slash.
+func (t *transport) newRequestBase(
+	ctx context.Context,
+	method string,
+	urlStr string,
+	body io.Reader,
+	useIPv6 bool,
+) (*http.Request, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cfg := t.config()
+	u := new(url.URL)
+
+	baseURL := cfg.baseURL
+	if useIPv6 && cfg.baseURLIPv6 != nil {
+		baseURL = cfg.baseURLIPv6
+	}
+
+	// get final URL path.
+	if rel, err := url.Parse(urlStr); err == nil {
+		u = baseURL.ResolveReference(rel)
+	} else if strings.ContainsRune(urlStr, ':') {
+		// IPv6 strings fail to parse as URLs, so let's add it as a URL Path.
+		*u = *baseURL
+		u.Path += urlStr
+	} else {
+		return nil, err
+	}
+
+	// get `http` package request object.
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// set common headers.
+	req.Header.Set("Accept", "application/json")
+	if cfg.userAgent != "" {
+		req.Header.Set("User-Agent", cfg.userAgent)
+	}
+	if _, err := cfg.authorize(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// `do` sends an API request and returns the API response. The API response is
+// JSON decoded and stored in the value pointed to by v, or returned as an
+// error if an API error has occurred. If v implements the io.Writer interface,
+// the raw response body will be written to v, without attempting to first
+// decode it.
+func (t *transport) do(
+	req *http.Request,
+	v interface{},
+) (*http.Response, error) {
+	resp, _, err := t.doMeta(req, v)
+	return resp, err
+}
+
+// `doMeta` is like `do` but also returns metadata about the exchange.
+func (t *transport) doMeta(
+	req *http.Request,
+	v interface{},
+) (*http.Response, *ResponseMeta, error) {
+	cfg := t.config()
+	if len(cfg.observers) == 0 {
+		return cfg.exchange(req, v)
+	}
+
+	e := &RequestEvent{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Product: strings.ToLower(cfg.product),
+	}
+	start := time.Now()
+	ctx := cfg.observers.requestStart(req.Context(), e)
+	resp, meta, err := cfg.exchange(req.WithContext(ctx), v)
+	e.StatusCode = statusCode(resp)
+	e.Duration = time.Since(start)
+	e.Err = err
+	cfg.observers.requestEnd(ctx, e)
+	return resp, meta, err
+}
+
+// `exchange` sends `req` and decodes the response into `v` as described for
+// `do`, returning metadata about the exchange.
+func (cfg *clientConfig) exchange(
+	req *http.Request,
+	v interface{},
+) (*http.Response, *ResponseMeta, error) {
+	for _, hook := range cfg.requestHooks {
+		if err := hook(req); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), cfg.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
+	resp, err := cfg.send(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	meta := &ResponseMeta{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		RateLimit:  parseRateLimit(resp.Header, start),
+	}
+
+	for _, hook := range cfg.responseHooks {
+		if err := hook(resp); err != nil {
+			meta.Duration = time.Since(start)
+			return resp, meta, err
+		}
+	}
+
+	err = checkResponse(resp)
+	if err != nil {
+		// even though there was an error, we still return the response
+		// in case the caller wants to inspect it further
+		meta.Duration = time.Since(start)
+		return resp, meta, err
+	}
+
+	if v != nil {
+		if w, ok := v.(io.Writer); ok && !cfg.keepRawBody {
+			io.Copy(w, resp.Body)
+		} else {
+			var data []byte
+			data, err = io.ReadAll(resp.Body)
+			if err == nil {
+				if cfg.keepRawBody {
+					meta.Body = data
+				}
+				if w, ok := v.(io.Writer); ok {
+					_, err = w.Write(data)
+				} else if len(bytes.TrimSpace(data)) != 0 {
+					// an empty response body is not an error.
+					err = json.Unmarshal(data, v)
+					if err == nil && cfg.strictSchema && isStructType(reflect.TypeOf(v)) {
+						err = CheckSchema(data, v)
+					}
+				}
+			}
+		}
+	}
+
+	meta.Duration = time.Since(start)
+	return resp, meta, err
+}
+
+// `send` sends `req`, waiting for the limiter before each attempt and
+// retrying according to the retry policy of `c`. Each attempt must be let
+// through by the circuit breaker of `c`, if any.
+func (c *clientConfig) send(req *http.Request) (*http.Response, error) {
+	attempts := c.retry.attempts()
+	for attempt := 1; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		if attempt > 1 && c.tokens != nil {
+			// pick a token afresh, as the last one may have been benched.
+			if _, err := c.authorize(req); err != nil {
+				return nil, err
+			}
+		}
+
+		var gen uint64
+		if c.breaker != nil {
+			var err error
+			if gen, err = c.breaker.allow(); err != nil {
+				return c.breaker.reject(req, err)
+			}
+		}
+
+		var resp *http.Response
+		var err error
+		if c.endpoints != nil {
+			resp, err = c.endpoints.do(c.wrapped, req, c.baseURL)
+		} else {
+			resp, err = c.wrapped.Do(req)
+		}
+		if c.breaker != nil {
+			c.breaker.done(gen, resp, err)
+		}
+		if c.tokens != nil {
+			c.tokens.Report(requestToken(req), resp, err)
+		}
+		if attempt >= attempts || !c.retry.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		// a body which cannot be rewound cannot be sent again.
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		wait := c.retry.backoff(attempt, resp)
+		if len(c.observers) != 0 {
+			c.observers.retry(req.Context(), &RetryEvent{
+				Method:     req.Method,
+				URL:        req.URL.String(),
+				Attempt:    attempt,
+				Wait:       wait,
+				StatusCode: statusCode(resp),
+				Err:        err,
+			})
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// `authorize` sets the Authorization header of `req` from the token provider
+// or the token of `c`, and returns the token used.
+func (c *clientConfig) authorize(req *http.Request) (string, error) {
+	token := c.token
+	if c.tokens != nil {
+		var err error
+		if token, err = c.tokens.Token(req.Context()); err != nil {
+			return "", err
+		}
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Del("Authorization")
+	}
+	return token, nil
+}
+
+// `hasToken` reports whether requests of `c` are authorized.
+func (c *clientConfig) hasToken() bool {
+	return c.token != "" || c.tokens != nil
+}
+
+// `requestToken` returns the token that `req` is authorized with.
+func requestToken(req *http.Request) string {
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+}
@@ -0,0 +1,190 @@
+package ipinfo_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/ipinfo/go/v2/ipinfo/ipinfotest"
+)
+
+// `tagMiddleware` returns middleware appending `name` to `calls` on each
+// request and response.
+func tagMiddleware(name string, calls *[]string) ipinfo.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return ipinfo.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+" request")
+			resp, err := next.RoundTrip(req)
+			*calls = append(*calls, name+" response")
+			return resp, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var calls []string
+	client := srv.Client(ipinfo.WithMiddleware(
+		tagMiddleware("outer", &calls),
+		tagMiddleware("inner", &calls),
+	))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"outer request", "inner request", "inner response", "outer response"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestMiddlewareAllClients(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	srv.RequireToken("secret")
+
+	var calls []string
+	mw := ipinfo.WithMiddleware(tagMiddleware("mw", &calls))
+	ip := net.ParseIP("8.8.8.8")
+	if _, err := srv.Client(mw).GetIPInfo(ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.LiteClient(mw).GetIPInfo(ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.CoreClient(mw).GetIPInfo(ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.PlusClient(mw).GetIPInfo(ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Client(mw).GetIPInfoBatch([]net.IP{ip}, ipinfo.BatchReqOpts{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(calls); n != 10 {
+		t.Errorf("middleware saw %d requests and responses, want 10: %q", n, calls)
+	}
+}
+
+func TestUse(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var calls []string
+	client := srv.LiteClient()
+	client.Use(tagMiddleware("mw", &calls))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("calls = %q, want a request and a response", calls)
+	}
+}
+
+func TestRequestHook(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	client := srv.CoreClient()
+	client.OnRequest(func(req *http.Request) error {
+		req.Header.Set("X-Request-Id", "42")
+		return nil
+	})
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 1 || reqs[0].Header.Get("X-Request-Id") != "42" {
+		t.Errorf("requests = %+v, want one with X-Request-Id", reqs)
+	}
+
+	errAbort := errors.New("abort")
+	client.OnRequest(func(req *http.Request) error { return errAbort })
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); !errors.Is(err, errAbort) {
+		t.Errorf("err = %v, want %v", err, errAbort)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want the aborted one not sent", n)
+	}
+}
+
+func TestResponseHook(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+
+	var statuses []int
+	errReject := errors.New("reject")
+	client := srv.PlusClient(ipinfo.WithResponseHook(func(resp *http.Response) error {
+		statuses = append(statuses, resp.StatusCode)
+		if resp.Header.Get("X-Reject") != "" {
+			return errReject
+		}
+		return nil
+	}))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Reject", "1")
+	srv.Fault("/", ipinfotest.Fault{Header: header})
+	if _, err := client.GetIPInfo(net.ParseIP("1.1.1.1")); !errors.Is(err, errReject) {
+		t.Errorf("err = %v, want %v", err, errReject)
+	}
+	if !reflect.DeepEqual(statuses, []int{200, 200}) {
+		t.Errorf("statuses = %v, want [200 200]", statuses)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+
+	client := srv.Client(ipinfo.WithUserAgent("test-agent/1.0"))
+	if _, err := client.GetIPInfo(net.ParseIP("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+	h := srv.Requests()[0].Header
+	if h.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Authorization = %q", h.Get("Authorization"))
+	}
+	if h.Get("User-Agent") != "test-agent/1.0" {
+		t.Errorf("User-Agent = %q", h.Get("User-Agent"))
+	}
+	if h.Get("Accept") != "application/json" {
+		t.Errorf("Accept = %q", h.Get("Accept"))
+	}
+}
+
+func TestIPv6BaseURL(t *testing.T) {
+	srv := ipinfotest.NewServer()
+	defer srv.Close()
+	v6 := ipinfotest.NewServer()
+	defer v6.Close()
+
+	client, err := ipinfo.NewClientWithOptions(
+		ipinfo.WithBaseURL(srv.URL),
+		ipinfo.WithIPv6BaseURL(v6.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIPInfoV6(net.ParseIP("2001:4860:4860::8888")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("base URL received %d requests, want 0", n)
+	}
+	reqs := v6.Requests()
+	if len(reqs) != 1 || reqs[0].Path != "/2001:4860:4860::8888" {
+		t.Errorf("IPv6 base URL received %+v, want one request for the IP", reqs)
+	}
+}
@@ -0,0 +1,271 @@
+package ipinfo
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalYAML returns the YAML encoding of `v`, such as a `*Core`, `*Lite`
+// or `BatchCore`.
+//
+// Struct fields are named by their `yaml` tags, falling back to their `json`
+// tags; fields tagged `yaml:"-"`, or `json:"-"` without a `yaml` tag, are
+// left out. Empty fields, i.e. nil, false, 0, "" and empty structs, maps and
+// slices, are always left out, so that all result types omit empty fields
+// alike. Map keys are sorted.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	tree, ok := yamlTree(reflect.ValueOf(v))
+	if !ok {
+		return []byte("{}\n"), nil
+	}
+
+	var buf bytes.Buffer
+	writeYAMLTree(&buf, tree, 0)
+	return buf.Bytes(), nil
+}
+
+// YAMLWriter writes results as a stream of YAML documents, one per result,
+// separated by "---" lines. See `MarshalYAML` for the encoding of results.
+type YAMLWriter struct {
+	w       io.Writer
+	written bool
+}
+
+// NewYAMLWriter returns a YAML writer writing to `w`.
+func NewYAMLWriter(w io.Writer) *YAMLWriter {
+	return &YAMLWriter{w: w}
+}
+
+// Write writes `v` as a YAML document.
+func (w *YAMLWriter) Write(v interface{}) error {
+	data, err := MarshalYAML(v)
+	if err != nil {
+		return err
+	}
+	if w.written {
+		data = append([]byte("---\n"), data...)
+	}
+	w.written = true
+	_, err = w.w.Write(data)
+	return err
+}
+
+// WriteBatch writes the results of `batch`, such as a `BatchCore`, `Batch`
+// or `BatchASNDetails`, as a single YAML document mapping each key to its
+// result. Nil results are skipped.
+func (w *YAMLWriter) WriteBatch(batch interface{}) error {
+	if _, _, err := batchEntries(batch); err != nil {
+		return err
+	}
+	return w.Write(batch)
+}
+
+// `yamlTree` converts `v` into an encoding tree with the field names and
+// omission rules of `MarshalYAML`. It reports false if `v` is empty.
+func yamlTree(v reflect.Value) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, false
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil || len(text) == 0 {
+			return nil, false
+		}
+		return string(text), true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return yamlTree(v.Elem())
+	case reflect.Struct:
+		m := &orderedMap{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := yamlFieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			if val, ok := yamlTree(v.Field(i)); ok {
+				m.add(name, val)
+			}
+		}
+		return m, len(m.keys) != 0
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if val, ok := yamlTree(iter.Value()); ok {
+				k := fmt.Sprint(iter.Key().Interface())
+				keys = append(keys, k)
+				values[k] = val
+			}
+		}
+		sort.Strings(keys)
+		m := &orderedMap{}
+		for _, k := range keys {
+			m.add(k, values[k])
+		}
+		return m, len(m.keys) != 0
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return nil, false
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i], _ = yamlTree(v.Index(i))
+		}
+		return arr, true
+	case reflect.String:
+		return v.String(), v.Len() != 0
+	case reflect.Bool:
+		return true, v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), v.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), v.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), v.Float() != 0
+	}
+	return nil, false
+}
+
+// `yamlFieldName` returns the YAML name of the struct field `sf`, or false
+// if it is left out.
+func yamlFieldName(sf reflect.StructField) (string, bool) {
+	if sf.PkgPath != "" {
+		return "", false
+	}
+
+	if tag, ok := sf.Tag.Lookup("yaml"); ok {
+		if tag == "-" {
+			return "", false
+		}
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name, true
+		}
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return sf.Name, true
+}
+
+// `writeYAMLTree` writes the encoding tree `v` as a YAML block indented by
+// `indent` spaces.
+func writeYAMLTree(buf *bytes.Buffer, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case *orderedMap:
+		for i, key := range v.keys {
+			buf.WriteString(pad + yamlString(key) + ":")
+			writeYAMLValue(buf, v.values[i], indent+2)
+		}
+	case []interface{}:
+		for _, e := range v {
+			if m, ok := e.(*orderedMap); ok && len(m.keys) != 0 {
+				// start the mapping on the line of the marker.
+				var item bytes.Buffer
+				writeYAMLTree(&item, m, indent+2)
+				buf.WriteString(pad + "- ")
+				buf.Write(item.Bytes()[indent+2:])
+				continue
+			}
+			buf.WriteString(pad + "-")
+			writeYAMLValue(buf, e, indent+2)
+		}
+	default:
+		buf.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+// `writeYAMLValue` writes `v` following a key or sequence marker.
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch vv := v.(type) {
+	case *orderedMap:
+		if len(vv.keys) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+	case []interface{}:
+		if len(vv) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+		return
+	}
+	buf.WriteString("\n")
+	writeYAMLTree(buf, v, indent)
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// yamlImplicit matches the plain scalars which a YAML 1.1 or 1.2 resolver
+// reads as something other than a string: integers in any base, floats
+// including infinities and NaN, timestamps, and the value and merge keys.
+// Null and boolean words are checked separately by `yamlString`.
+var yamlImplicit = regexp.MustCompile(`^(?:` +
+	`[-+]?0b[01_]+|` +
+	`[-+]?0o?[0-7_]+|` +
+	`[-+]?0x[0-9a-fA-F_]+|` +
+	`[-+]?[0-9][0-9_]*|` +
+	`[-+]?(?:[0-9][0-9_]*)?\.[0-9_]*(?:[eE][-+]?[0-9]+)?|` +
+	`[-+]?[0-9][0-9_]*(?:\.[0-9_]*)?[eE][-+]?[0-9]+|` +
+	`[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)|` +
+	`[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt ].*)?|` +
+	`=|<<` +
+	`)$`)
+
+// `yamlString` returns `s` as a plain scalar, or quoted if it would
+// otherwise be read back as something else.
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\r\t\\") ||
+		strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return strconv.Quote(s)
+	}
+	if yamlImplicit.MatchString(s) {
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
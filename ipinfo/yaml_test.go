@@ -0,0 +1,136 @@
+package ipinfo_test
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/ipinfo/go/v2/ipinfo"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	data, err := ipinfo.MarshalYAML(&ipinfo.Core{
+		IP:      net.ParseIP("8.8.8.8"),
+		City:    "Mountain View",
+		ASN:     &ipinfo.CoreASN{ASN: "AS15169", Name: "Google LLC"},
+		Privacy: &ipinfo.CorePrivacy{},
+		Domains: &ipinfo.CoreDomains{Total: 2, Domains: []string{"a.com", "b.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `ip: 8.8.8.8
+city: Mountain View
+asn:
+  asn: AS15169
+  name: Google LLC
+domains:
+  total: 2
+  domains:
+    - a.com
+    - b.com
+`
+	if string(data) != want {
+		t.Errorf("MarshalYAML =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestMarshalYAMLOmitsEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", (*ipinfo.Core)(nil), "{}\n"},
+		{"Core", &ipinfo.Core{}, "{}\n"},
+		{"Lite", &ipinfo.Lite{ASN: "AS15169"}, "asn: AS15169\n"},
+		{"CoreResponse", &ipinfo.CoreResponse{Geo: &ipinfo.CoreGeo{}, IsAnycast: true}, "isAnycast: true\n"},
+		{"Plus", &ipinfo.Plus{Geo: &ipinfo.PlusGeo{City: "Paris"}}, "geo:\n  city: Paris\n"},
+		{"ASNDetails", &ipinfo.ASNDetails{NumIPs: 256}, "numIPs: 256\n"},
+		{"ResproxyDetails", &ipinfo.ResproxyDetails{}, "{}\n"},
+		{"batch", ipinfo.BatchCore{"8.8.8.8": nil, "1.1.1.1": {City: "Sydney"}}, "1.1.1.1:\n  city: Sydney\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ipinfo.MarshalYAML(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("MarshalYAML = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalYAMLQuoting(t *testing.T) {
+	quoted := []string{
+		// null and booleans.
+		"~", "null", "Null", "true", "False", "yes", "No", "y", "N", "on", "OFF",
+		// integers.
+		"0", "42", "-7", "+3", "1_000", "0x10", "0o17", "017", "0b101",
+		// floats.
+		"1.5", ".5", "1.", "1e3", "-2.5E-3", ".inf", "-.Inf", "+.INF", ".nan", ".NaN",
+		// timestamps.
+		"2024-01-02", "2024-1-2", "2001-12-14t21:59:43.10-05:00", "2001-12-14 21:59:43.10 -5",
+		// indicators and whitespace.
+		"", " padded", "a: b", "#comment", "- item", "? key", "[x]", "{x}", "&anchor",
+		"*alias", "!tag", "|", ">", "'", `"`, "%YAML", "@at", "`tick", "a\nb", "=", "<<",
+	}
+	for _, s := range quoted {
+		data, err := ipinfo.MarshalYAML(map[string]string{"k": s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s == "" {
+			// empty values are left out.
+			continue
+		}
+		if want := "k: " + strconv.Quote(s) + "\n"; string(data) != want {
+			t.Errorf("MarshalYAML(%q) = %q, want %q", s, data, want)
+		}
+	}
+
+	plain := []string{"AS15169", "Mountain View", "8.8.8.8", "US", "Europe/Paris", "0x", "1.2.3", "yesterday", "nope", "2024-01"}
+	for _, s := range plain {
+		data, err := ipinfo.MarshalYAML(map[string]string{"k": s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "k: " + s + "\n"; string(data) != want {
+			t.Errorf("MarshalYAML(%q) = %q, want %q", s, data, want)
+		}
+	}
+
+	// keys are quoted alike.
+	data, err := ipinfo.MarshalYAML(map[string]int{"no": 1, "15169": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"15169\": 2\n\"no\": 1\n"; string(data) != want {
+		t.Errorf("MarshalYAML = %q, want %q", data, want)
+	}
+}
+
+func TestYAMLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := ipinfo.NewYAMLWriter(&buf)
+	if err := w.Write(&ipinfo.Lite{ASN: "AS1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(&ipinfo.Lite{ASN: "AS2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteBatch(ipinfo.BatchASNDetails{"AS3": {Name: "Three"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "asn: AS1\n---\nasn: AS2\n---\nAS3:\n  name: Three\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	if err := w.WriteBatch(&ipinfo.Core{}); err == nil {
+		t.Error("err = nil for a non-batch value")
+	}
+}